@@ -78,6 +78,17 @@ func init() {
 	backfillCmd.PersistentFlags().Int("backfill-workers", 4, "number of worker goroutines to concurrently make and process http requests")
 	backfillCmd.PersistentFlags().Int("backfill-timeout", 15, "timeout used for backfill http requests (in seconds)")
 	backfillCmd.PersistentFlags().Int("backfill-validation-level", 1, "data validated less than this amount will be backfilled")
+	backfillCmd.PersistentFlags().Int("backfill-progress-frequency", 30, "how often to report backfill progress (in seconds; default 30)")
+	backfillCmd.PersistentFlags().Int("backfill-tail-distance", 0, "once there are no gaps, follow the chain this many blocks behind head (0 disables tail-following)")
+	backfillCmd.PersistentFlags().Int("backfill-mode-switch-passes", 3, "number of consecutive gap searches that must agree before switching between gap-filling and tail-following")
+	backfillCmd.PersistentFlags().Float64("backfill-jitter", 0, "percentage of the frequency by which each gap search is randomly offset (0 disables jitter)")
+	backfillCmd.PersistentFlags().Bool("backfill-headers-only", false, "only index headers and uncles, recording the blocks in eth.gaps to be completed by a later full backfill")
+	backfillCmd.PersistentFlags().Int("backfill-partitions", 0, "split each pass into this many contiguous ranges, each backfilled with its own connection (0 or 1 disables partitioning)")
+	backfillCmd.PersistentFlags().Bool("backfill-persist-payloads", false, "also store the raw payload of each block in eth.payloads so that it can be reprocessed without a node")
+	backfillCmd.PersistentFlags().String("backfill-event-socket", "", "path of a unix socket to write a json event to for each committed block")
+	backfillCmd.PersistentFlags().StringSlice("backfill-blocks", nil, "only backfill these block numbers, in a single pass, instead of the gaps")
+	backfillCmd.PersistentFlags().String("backfill-blocks-file", "", "path of a file listing the only block numbers to backfill, in a single pass, instead of the gaps")
+	backfillCmd.PersistentFlags().Bool("backfill-defer-constraints", false, "check the foreign key constraints of each block when it commits rather than on each insert")
 	backfillCmd.PersistentFlags().String("eth-http-path", "", "http url for ethereum node")
 
 	// and their .toml config bindings
@@ -86,5 +97,16 @@ func init() {
 	viper.BindPFlag("backfill.workers", backfillCmd.PersistentFlags().Lookup("backfill-workers"))
 	viper.BindPFlag("backfill.timeout", backfillCmd.PersistentFlags().Lookup("backfill-timeout"))
 	viper.BindPFlag("backfill.validationLevel", backfillCmd.PersistentFlags().Lookup("backfill-validation-level"))
+	viper.BindPFlag("backfill.progressFrequency", backfillCmd.PersistentFlags().Lookup("backfill-progress-frequency"))
+	viper.BindPFlag("backfill.tailDistance", backfillCmd.PersistentFlags().Lookup("backfill-tail-distance"))
+	viper.BindPFlag("backfill.modeSwitchPasses", backfillCmd.PersistentFlags().Lookup("backfill-mode-switch-passes"))
+	viper.BindPFlag("backfill.jitter", backfillCmd.PersistentFlags().Lookup("backfill-jitter"))
+	viper.BindPFlag("backfill.headersOnly", backfillCmd.PersistentFlags().Lookup("backfill-headers-only"))
+	viper.BindPFlag("backfill.partitions", backfillCmd.PersistentFlags().Lookup("backfill-partitions"))
+	viper.BindPFlag("backfill.persistPayloads", backfillCmd.PersistentFlags().Lookup("backfill-persist-payloads"))
+	viper.BindPFlag("backfill.eventSocket", backfillCmd.PersistentFlags().Lookup("backfill-event-socket"))
+	viper.BindPFlag("backfill.blocks", backfillCmd.PersistentFlags().Lookup("backfill-blocks"))
+	viper.BindPFlag("backfill.blocksFile", backfillCmd.PersistentFlags().Lookup("backfill-blocks-file"))
+	viper.BindPFlag("backfill.deferConstraints", backfillCmd.PersistentFlags().Lookup("backfill-defer-constraints"))
 	viper.BindPFlag("ethereum.httpPath", backfillCmd.PersistentFlags().Lookup("eth-http-path"))
 }
@@ -0,0 +1,134 @@
+// Copyright © 2020 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/node"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/utils"
+	v "github.com/vulcanize/ipld-eth-indexer/version"
+)
+
+// Env variables for the database the configured one is compared against
+const (
+	DATABASE_COMPARE_NAME     = "DATABASE_COMPARE_NAME"
+	DATABASE_COMPARE_HOSTNAME = "DATABASE_COMPARE_HOSTNAME"
+	DATABASE_COMPARE_PORT     = "DATABASE_COMPARE_PORT"
+	DATABASE_COMPARE_USER     = "DATABASE_COMPARE_USER"
+	DATABASE_COMPARE_PASSWORD = "DATABASE_COMPARE_PASSWORD"
+	DATABASE_COMPARE_SCHEMA   = "DATABASE_COMPARE_SCHEMA"
+)
+
+// diffDBCmd represents the diff-db command
+var diffDBCmd = &cobra.Command{
+	Use:   "diff-db",
+	Short: "Compare the data two indexers have indexed for a block range",
+	Long: `Use this command to confirm that two indexers, e.g. the current version and a refactored one, produce identical data
+For each height in an explicit block range the header hashes, and the row counts and cids of the header, uncle, transaction,
+receipt, state and storage tables are compared, and the first divergence is reported
+The configured database is compared against the one configured under [database.compare], whose unset connection settings
+default to those of the configured database, so two schemas of the same database can be compared by only setting its schema`,
+	Run: func(cmd *cobra.Command, args []string) {
+		subCommand = cmd.CalledAs()
+		logWithCommand = *log.WithField("SubCommand", subCommand)
+		diffDB()
+	},
+}
+
+func diffDB() {
+	logWithCommand.Infof("running ipld-eth-indexer version: %s", v.VersionWithMeta)
+	start := viper.GetUint64("diffDB.start")
+	stop := viper.GetUint64("diffDB.stop")
+	var dbConfig postgres.Config
+	dbConfig.Init()
+	compareConfig := compareDBConfig(dbConfig)
+	if compareConfig == dbConfig {
+		logWithCommand.Fatal("the database to compare against has to differ from the configured one, set it under [database.compare]")
+	}
+	db := utils.LoadPostgres(dbConfig, node.Info{})
+	compareDB := utils.LoadPostgres(compareConfig, node.Info{})
+	logWithCommand.Infof("comparing ethereum data from %d to %d between %s/%s.%s and %s/%s.%s", start, stop,
+		dbConfig.Hostname, dbConfig.Name, db.Schema, compareConfig.Hostname, compareConfig.Name, compareDB.Schema)
+	divergence, err := eth.NewDBDiffer(&db, &compareDB).Diff(start, stop)
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	if divergence != nil {
+		logWithCommand.Fatalf("first divergence at block %d in the %s: %s in the configured database, %s in the compared one",
+			divergence.BlockNumber, divergence.Field, divergence.Left, divergence.Right)
+	}
+	logWithCommand.Infof("ethereum db diff finished, the databases have indexed identical data from %d to %d", start, stop)
+}
+
+// compareDBConfig returns the config of the database to compare against, which is the provided config overridden with
+// the settings under database.compare
+func compareDBConfig(config postgres.Config) postgres.Config {
+	viper.BindEnv("database.compare.name", DATABASE_COMPARE_NAME)
+	viper.BindEnv("database.compare.hostname", DATABASE_COMPARE_HOSTNAME)
+	viper.BindEnv("database.compare.port", DATABASE_COMPARE_PORT)
+	viper.BindEnv("database.compare.user", DATABASE_COMPARE_USER)
+	viper.BindEnv("database.compare.password", DATABASE_COMPARE_PASSWORD)
+	viper.BindEnv("database.compare.schema", DATABASE_COMPARE_SCHEMA)
+
+	if name := viper.GetString("database.compare.name"); name != "" {
+		config.Name = name
+	}
+	if hostname := viper.GetString("database.compare.hostname"); hostname != "" {
+		config.Hostname = hostname
+	}
+	if port := viper.GetInt("database.compare.port"); port > 0 {
+		config.Port = port
+	}
+	if user := viper.GetString("database.compare.user"); user != "" {
+		config.User = user
+	}
+	if password := viper.GetString("database.compare.password"); password != "" {
+		config.Password = password
+	}
+	if schema := viper.GetString("database.compare.schema"); schema != "" {
+		config.Schema = schema
+	}
+	return config
+}
+
+func init() {
+	rootCmd.AddCommand(diffDBCmd)
+
+	// flags
+	diffDBCmd.PersistentFlags().Uint64("start", 0, "block height to start comparing at")
+	diffDBCmd.PersistentFlags().Uint64("stop", 0, "block height to stop comparing at")
+	diffDBCmd.PersistentFlags().String("compare-database-name", "", "name of the database to compare against")
+	diffDBCmd.PersistentFlags().String("compare-database-hostname", "", "hostname of the database to compare against")
+	diffDBCmd.PersistentFlags().Int("compare-database-port", 0, "port of the database to compare against")
+	diffDBCmd.PersistentFlags().String("compare-database-user", "", "user of the database to compare against")
+	diffDBCmd.PersistentFlags().String("compare-database-password", "", "password of the database to compare against")
+	diffDBCmd.PersistentFlags().String("compare-database-schema", "", "schema of the database to compare against")
+
+	// and their .toml config bindings
+	viper.BindPFlag("diffDB.start", diffDBCmd.PersistentFlags().Lookup("start"))
+	viper.BindPFlag("diffDB.stop", diffDBCmd.PersistentFlags().Lookup("stop"))
+	viper.BindPFlag("database.compare.name", diffDBCmd.PersistentFlags().Lookup("compare-database-name"))
+	viper.BindPFlag("database.compare.hostname", diffDBCmd.PersistentFlags().Lookup("compare-database-hostname"))
+	viper.BindPFlag("database.compare.port", diffDBCmd.PersistentFlags().Lookup("compare-database-port"))
+	viper.BindPFlag("database.compare.user", diffDBCmd.PersistentFlags().Lookup("compare-database-user"))
+	viper.BindPFlag("database.compare.password", diffDBCmd.PersistentFlags().Lookup("compare-database-password"))
+	viper.BindPFlag("database.compare.schema", diffDBCmd.PersistentFlags().Lookup("compare-database-schema"))
+}
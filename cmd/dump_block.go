@@ -0,0 +1,83 @@
+// Copyright © 2020 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/node"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/utils"
+)
+
+// dumpBlockCmd represents the dump-block command
+var dumpBlockCmd = &cobra.Command{
+	Use:   "dump-block",
+	Short: "Print the indexed data for a block as JSON",
+	Long: `Use this command to print everything that has been indexed for a block as a single JSON document
+The block is selected by its hash with --hash, or by its number with --number
+A block selected by its hash is printed as an object: its header, uncles, transactions, receipts, state nodes and storage nodes
+A block selected by its number is printed as an array, with an object for each header indexed at that height`,
+	Run: func(cmd *cobra.Command, args []string) {
+		subCommand = cmd.CalledAs()
+		logWithCommand = *log.WithField("SubCommand", subCommand)
+		dumpBlock()
+	},
+}
+
+func dumpBlock() {
+	hash := viper.GetString("dumpBlock.hash")
+	if hash == "" && !viper.IsSet("dumpBlock.number") {
+		logWithCommand.Fatal("dump-block requires either --number or --hash")
+	}
+	var dbConfig postgres.Config
+	dbConfig.Init()
+	db := utils.LoadPostgres(dbConfig, node.Info{})
+	dumper := eth.NewBlockDumper(&db)
+	var dump interface{}
+	var err error
+	if hash != "" {
+		dump, err = dumper.DumpByHash(hash)
+	} else {
+		dump, err = dumper.DumpByNumber(viper.GetInt64("dumpBlock.number"))
+	}
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	out, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	fmt.Println(string(out))
+}
+
+func init() {
+	rootCmd.AddCommand(dumpBlockCmd)
+
+	// flags
+	dumpBlockCmd.PersistentFlags().Int64("number", 0, "number of the block to dump")
+	dumpBlockCmd.PersistentFlags().String("hash", "", "hash of the block to dump")
+
+	// and their .toml config bindings
+	viper.BindPFlag("dumpBlock.number", dumpBlockCmd.PersistentFlags().Lookup("number"))
+	viper.BindPFlag("dumpBlock.hash", dumpBlockCmd.PersistentFlags().Lookup("hash"))
+}
@@ -0,0 +1,83 @@
+// Copyright © 2020 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/gateway"
+	v "github.com/vulcanize/ipld-eth-indexer/version"
+)
+
+// gatewayCmd represents the gateway command
+var gatewayCmd = &cobra.Command{
+	Use:   "gateway",
+	Short: "Serve indexed IPLD blocks over http",
+	Long: `Use this command to serve the raw IPLD blocks indexed in Postgres over http
+Blocks are fetched by their CID at GET /ipld/{cid}
+If an ethereum node is configured, the distance between the head of the chain and the highest indexed block is served at GET /lag
+If a read replica is configured, queries are served from it rather than the primary
+and the highest indexed block of each is served at GET /freshness`,
+	Run: func(cmd *cobra.Command, args []string) {
+		subCommand = cmd.CalledAs()
+		logWithCommand = *log.WithField("SubCommand", subCommand)
+		gatewayCmdCommand()
+	},
+}
+
+func gatewayCmdCommand() {
+	logWithCommand.Infof("running ipld-eth-indexer version: %s", v.VersionWithMeta)
+	logWithCommand.Debug("loading gateway configuration variables")
+	gConfig, err := gateway.NewConfig()
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	logWithCommand.Infof("gateway config: %+v", gConfig)
+	mux := gateway.NewServeMux(gConfig.ReadDB())
+	if gConfig.ReplicaDB != nil {
+		mux.Handle(gateway.FreshnessPath, gateway.NewFreshnessHandler(gConfig.DB, gConfig.ReplicaDB))
+		logWithCommand.Infof("serving queries from the read replica at %s:%d", gConfig.ReplicaDBConfig.Hostname, gConfig.ReplicaDBConfig.Port)
+		logWithCommand.Infof("serving the read freshness at http://%s%s", gConfig.HTTPAddr, gateway.FreshnessPath)
+	}
+	if gConfig.LagTracker != nil {
+		mux.Handle(gateway.LagPath, gateway.NewLagHandler(gConfig.LagTracker))
+		logWithCommand.Infof("serving the indexing lag at http://%s%s", gConfig.HTTPAddr, gateway.LagPath)
+	}
+	logWithCommand.Infof("serving IPLD blocks at http://%s%s", gConfig.HTTPAddr, gateway.IPLDPath)
+	if err := http.ListenAndServe(gConfig.HTTPAddr, mux); err != nil {
+		logWithCommand.Fatal(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(gatewayCmd)
+
+	// flags
+	gatewayCmd.PersistentFlags().String("gateway-http-addr", "127.0.0.1:8091", "address to serve the IPLD gateway on")
+	gatewayCmd.PersistentFlags().String("eth-http-path", "", "http url for ethereum node, the indexing lag is only served if this is set")
+	gatewayCmd.PersistentFlags().String("database-replica-hostname", "", "hostname of a read replica to serve queries from instead of the primary")
+	gatewayCmd.PersistentFlags().Int("database-replica-port", 0, "port of the read replica, defaults to the primary's")
+
+	// and their .toml config bindings
+	viper.BindPFlag("gateway.httpAddr", gatewayCmd.PersistentFlags().Lookup("gateway-http-addr"))
+	viper.BindPFlag("ethereum.httpPath", gatewayCmd.PersistentFlags().Lookup("eth-http-path"))
+	viper.BindPFlag("database.replica.hostname", gatewayCmd.PersistentFlags().Lookup("database-replica-hostname"))
+	viper.BindPFlag("database.replica.port", gatewayCmd.PersistentFlags().Lookup("database-replica-port"))
+}
@@ -0,0 +1,97 @@
+// Copyright © 2020 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/ethereum/go-ethereum/core"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+	"github.com/vulcanize/ipld-eth-indexer/utils"
+	v "github.com/vulcanize/ipld-eth-indexer/version"
+)
+
+// indexGenesisCmd represents the index-genesis command
+var indexGenesisCmd = &cobra.Command{
+	Use:   "index-genesis",
+	Short: "Index the genesis block and allocation state from a genesis file",
+	Long: `Use this command to index block 0 from the genesis.json the chain was initialized with, with every allocated
+account, and its storage, indexed as state and storage leaves, so that the genesis state is indexed without a node
+A statediffing node does not produce a payload for the genesis block, so this is run once before the first sync or backfill
+The chain config is that of the genesis file, or if it has none the one selected by --eth-chain-id`,
+	Run: func(cmd *cobra.Command, args []string) {
+		subCommand = cmd.CalledAs()
+		logWithCommand = *log.WithField("SubCommand", subCommand)
+		indexGenesis()
+	},
+}
+
+func indexGenesis() {
+	logWithCommand.Infof("running ipld-eth-indexer version: %s", v.VersionWithMeta)
+	path := viper.GetString("indexGenesis.file")
+	if path == "" {
+		logWithCommand.Fatal("a genesis file is required")
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	defer file.Close()
+	genesis := new(core.Genesis)
+	if err := json.NewDecoder(file).Decode(genesis); err != nil {
+		logWithCommand.Fatalf("error decoding genesis file %s: %v", path, err)
+	}
+	multihashes, err := eth.ParseMultihashes(shared.MultihashEntries())
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	nodeInfo := shared.GetEthNodeInfo()
+	chainConfig := genesis.Config
+	if chainConfig == nil {
+		if chainConfig, err = eth.ChainConfig(nodeInfo.ChainID); err != nil {
+			logWithCommand.Fatal(err)
+		}
+	}
+	var dbConfig postgres.Config
+	dbConfig.Init()
+	db := utils.LoadPostgres(dbConfig, nodeInfo)
+	transformer := eth.NewStateDiffTransformer(chainConfig, &db)
+	if err := transformer.SetMultihashes(multihashes); err != nil {
+		logWithCommand.Fatal(err)
+	}
+	logWithCommand.Infof("indexing the ethereum genesis block from %s", path)
+	if _, err := transformer.IndexGenesis(genesis); err != nil {
+		logWithCommand.Fatalf("error indexing the genesis block: %v", err)
+	}
+	logWithCommand.Infof("ethereum genesis indexing finished, indexed %d allocated accounts", len(genesis.Alloc))
+}
+
+func init() {
+	rootCmd.AddCommand(indexGenesisCmd)
+
+	// flags
+	indexGenesisCmd.PersistentFlags().String("genesis-file", "", "path of the genesis.json the chain was initialized with")
+
+	// and their .toml config bindings
+	viper.BindPFlag("indexGenesis.file", indexGenesisCmd.PersistentFlags().Lookup("genesis-file"))
+}
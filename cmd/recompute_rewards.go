@@ -0,0 +1,76 @@
+// Copyright © 2020 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/node"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/utils"
+	v "github.com/vulcanize/ipld-eth-indexer/version"
+)
+
+// recomputeRewardsCmd represents the recompute-rewards command
+var recomputeRewardsCmd = &cobra.Command{
+	Use:   "recompute-rewards",
+	Short: "Recompute the rewards of an indexed block range",
+	Long: `Use this command to recompute the rewards stored for the headers and uncles indexed in an explicit block range
+The rewards are recalculated from the header, uncle, transaction and receipt IPLDs already in Postgres,
+and the header_cids and uncle_cids rows are updated in place, so the range does not need to be reindexed
+With --fees-only the block reward is only the transaction fees and uncles are not rewarded, as on a proof-of-authority chain`,
+	Run: func(cmd *cobra.Command, args []string) {
+		subCommand = cmd.CalledAs()
+		logWithCommand = *log.WithField("SubCommand", subCommand)
+		recomputeRewards()
+	},
+}
+
+func recomputeRewards() {
+	logWithCommand.Infof("running ipld-eth-indexer version: %s", v.VersionWithMeta)
+	start := viper.GetUint64("recomputeRewards.start")
+	stop := viper.GetUint64("recomputeRewards.stop")
+	var calculator eth.RewardCalculator = eth.EthRewardCalculator{}
+	if viper.GetBool("recomputeRewards.feesOnly") {
+		calculator = eth.FeeRewardCalculator{}
+	}
+	var dbConfig postgres.Config
+	dbConfig.Init()
+	db := utils.LoadPostgres(dbConfig, node.Info{})
+	logWithCommand.Infof("recomputing ethereum rewards from %d to %d", start, stop)
+	updated, err := eth.NewRewardRecomputer(&db, calculator).Recompute(start, stop)
+	if err != nil {
+		logWithCommand.Fatalf("%v (%d headers were updated before the error)", err, updated)
+	}
+	logWithCommand.Infof("ethereum reward recomputation finished, updated %d headers", updated)
+}
+
+func init() {
+	rootCmd.AddCommand(recomputeRewardsCmd)
+
+	// flags
+	recomputeRewardsCmd.PersistentFlags().Uint64("start", 0, "block height to start recomputing rewards at")
+	recomputeRewardsCmd.PersistentFlags().Uint64("stop", 0, "block height to stop recomputing rewards at")
+	recomputeRewardsCmd.PersistentFlags().Bool("fees-only", false, "only reward transaction fees, for chains without block rewards")
+
+	// and their .toml config bindings
+	viper.BindPFlag("recomputeRewards.start", recomputeRewardsCmd.PersistentFlags().Lookup("start"))
+	viper.BindPFlag("recomputeRewards.stop", recomputeRewardsCmd.PersistentFlags().Lookup("stop"))
+	viper.BindPFlag("recomputeRewards.feesOnly", recomputeRewardsCmd.PersistentFlags().Lookup("fees-only"))
+}
@@ -0,0 +1,93 @@
+// Copyright © 2020 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+	"github.com/vulcanize/ipld-eth-indexer/utils"
+	v "github.com/vulcanize/ipld-eth-indexer/version"
+)
+
+// reprocessCmd represents the reprocess command
+var reprocessCmd = &cobra.Command{
+	Use:   "reprocess",
+	Short: "Reindex a block range from the payloads stored in Postgres",
+	Long: `Use this command to reindex an explicit block range from the raw statediff payloads stored in eth.payloads
+by a sync or backfill process run with payload persistence enabled, so that the range can be reindexed without a node
+The chain config used to process the payloads is selected by --eth-chain-id
+
+Every block in the range must have a stored payload`,
+	Run: func(cmd *cobra.Command, args []string) {
+		subCommand = cmd.CalledAs()
+		logWithCommand = *log.WithField("SubCommand", subCommand)
+		reprocess()
+	},
+}
+
+func reprocess() {
+	logWithCommand.Infof("running ipld-eth-indexer version: %s", v.VersionWithMeta)
+	start := viper.GetUint64("reprocess.start")
+	stop := viper.GetUint64("reprocess.stop")
+	if stop < start {
+		logWithCommand.Fatal("reprocess range ending block number needs to be greater than the starting block number")
+	}
+	multihashes, err := eth.ParseMultihashes(shared.MultihashEntries())
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	nodeInfo := shared.GetEthNodeInfo()
+	chainConfig, err := eth.ChainConfig(nodeInfo.ChainID)
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	var dbConfig postgres.Config
+	dbConfig.Init()
+	db := utils.LoadPostgres(dbConfig, nodeInfo)
+	fetcher := eth.NewDBPayloadFetcher(&db)
+	transformer := eth.NewStateDiffTransformer(chainConfig, &db)
+	if err := transformer.SetMultihashes(multihashes); err != nil {
+		logWithCommand.Fatal(err)
+	}
+	logWithCommand.Infof("reprocessing ethereum payloads from %d to %d", start, stop)
+	for height := start; height <= stop; height++ {
+		payloads, err := fetcher.FetchAt([]uint64{height})
+		if err != nil {
+			logWithCommand.Fatal(err)
+		}
+		if _, err := transformer.Transform(0, payloads[0]); err != nil {
+			logWithCommand.Fatalf("error reprocessing the payload at height %d: %v", height, err)
+		}
+	}
+	logWithCommand.Infof("ethereum reprocessing finished, reindexed %d blocks", stop-start+1)
+}
+
+func init() {
+	rootCmd.AddCommand(reprocessCmd)
+
+	// flags
+	reprocessCmd.PersistentFlags().Uint64("start", 0, "block height to start reprocessing at")
+	reprocessCmd.PersistentFlags().Uint64("stop", 0, "block height to stop reprocessing at")
+
+	// and their .toml config bindings
+	viper.BindPFlag("reprocess.start", reprocessCmd.PersistentFlags().Lookup("start"))
+	viper.BindPFlag("reprocess.stop", reprocessCmd.PersistentFlags().Lookup("stop"))
+}
@@ -0,0 +1,80 @@
+// Copyright © 2020 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"github.com/ethereum/go-ethereum/ethclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/revalidate"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+	v "github.com/vulcanize/ipld-eth-indexer/version"
+)
+
+// revalidateCmd represents the revalidate command
+var revalidateCmd = &cobra.Command{
+	Use:   "revalidate",
+	Short: "Revalidate a historical block range",
+	Long: `Use this command to force revalidation of an explicit block range
+It resets times_validated to 0 for the headers in the range, so that they will be resynced by the backfill process,
+and re-checks each indexed header hash against the canonical chain, reporting any mismatches found
+
+NOTE: Requires a syncmode=full gcmode=archive statediffing go-ethereum node`,
+	Run: func(cmd *cobra.Command, args []string) {
+		subCommand = cmd.CalledAs()
+		logWithCommand = *log.WithField("SubCommand", subCommand)
+		revalidateCmdCommand()
+	},
+}
+
+func revalidateCmdCommand() {
+	logWithCommand.Infof("running ipld-eth-indexer version: %s", v.VersionWithMeta)
+	logWithCommand.Debug("loading revalidate configuration variables")
+	rConfig, err := revalidate.NewConfig()
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	logWithCommand.Infof("revalidate config: %+v", rConfig)
+	validator := eth.NewHeaderValidator(rConfig.DB, eth.NewRateLimitedHeaderClient(ethclient.NewClient(rConfig.HTTPClient), shared.RPCRateLimiter()), rConfig.Timeout)
+	logWithCommand.Infof("revalidating ethereum headers from %d to %d", rConfig.Start, rConfig.Stop)
+	mismatches, err := validator.Revalidate(rConfig.Start, rConfig.Stop)
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	for _, mismatch := range mismatches {
+		logWithCommand.Warnf("block %d: canonical hash %s, indexed hashes %v", mismatch.BlockNumber, mismatch.CanonicalHash, mismatch.IndexedHashes)
+	}
+	logWithCommand.Infof("ethereum revalidation finished, found %d mismatched heights", len(mismatches))
+}
+
+func init() {
+	rootCmd.AddCommand(revalidateCmd)
+
+	// flags
+	revalidateCmd.PersistentFlags().Int("revalidate-start", 0, "block height to start revalidation")
+	revalidateCmd.PersistentFlags().Int("revalidate-stop", 0, "block height to stop revalidation")
+	revalidateCmd.PersistentFlags().Int("revalidate-timeout", 15, "timeout used for revalidate http requests (in seconds)")
+	revalidateCmd.PersistentFlags().String("eth-http-path", "", "http url for ethereum node")
+
+	// and their .toml config bindings
+	viper.BindPFlag("revalidate.start", revalidateCmd.PersistentFlags().Lookup("revalidate-start"))
+	viper.BindPFlag("revalidate.stop", revalidateCmd.PersistentFlags().Lookup("revalidate-stop"))
+	viper.BindPFlag("revalidate.timeout", revalidateCmd.PersistentFlags().Lookup("revalidate-timeout"))
+	viper.BindPFlag("ethereum.httpPath", revalidateCmd.PersistentFlags().Lookup("eth-http-path"))
+}
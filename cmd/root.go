@@ -24,6 +24,9 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/prom"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
 )
 
 var (
@@ -62,6 +65,10 @@ func initFuncs(cmd *cobra.Command, args []string) {
 	if err := logLevel(); err != nil {
 		log.Fatal("Could not set log level: ", err)
 	}
+	if viper.GetBool("metrics.enabled") {
+		prom.Init()
+		prom.Serve(viper.GetString("metrics.httpAddr"))
+	}
 }
 
 func logLevel() error {
@@ -91,15 +98,28 @@ func init() {
 	rootCmd.PersistentFlags().String("database-hostname", "localhost", "database hostname")
 	rootCmd.PersistentFlags().String("database-user", "", "database user")
 	rootCmd.PersistentFlags().String("database-password", "", "database password")
+	rootCmd.PersistentFlags().Int("database-max-idle", 0, "maximum number of idle connections in the database pool (default 10)")
+	rootCmd.PersistentFlags().Int("database-max-open", 0, "maximum number of open connections in the database pool (default 50)")
+	rootCmd.PersistentFlags().Int("database-max-lifetime", 0, "maximum lifetime of a database connection (in seconds; default 1800)")
+	rootCmd.PersistentFlags().Bool("database-verify-indexes", false, "warn on startup if any of the expected indexes are missing")
+	rootCmd.PersistentFlags().String("database-schema", "eth", "schema to index cids into, created if it does not exist")
+	rootCmd.PersistentFlags().Int("database-publish-cache-size", shared.DefaultPublishCacheSize, "number of recently published IPLD keys whose redundant inserts are skipped, shared by all workers (0 disables the cache)")
 
 	rootCmd.PersistentFlags().String("log-level", log.InfoLevel.String(), "Log level (trace, debug, info, warn, error, fatal, panic")
 	rootCmd.PersistentFlags().String("logfile", "", "file path for logging")
 
+	rootCmd.PersistentFlags().Bool("metrics", false, "enable metrics")
+	rootCmd.PersistentFlags().String("metrics-http-addr", "127.0.0.1:8090", "address to serve prometheus metrics on")
+
 	rootCmd.PersistentFlags().String("eth-node-id", "", "eth node id")
 	rootCmd.PersistentFlags().String("eth-client-name", "Geth", "eth client name")
 	rootCmd.PersistentFlags().String("eth-genesis-block", "0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3", "eth genesis block hash")
 	rootCmd.PersistentFlags().String("eth-network-id", "1", "eth network id")
 	rootCmd.PersistentFlags().String("eth-chain-id", "1", "eth chain id")
+	rootCmd.PersistentFlags().Bool("eth-strict-chain-id", false, "refuse to start if the eth chain id does not match the chain id reported by the node")
+	rootCmd.PersistentFlags().Bool("eth-chain-config-from-node", false, "read the chain config from the admin_nodeInfo of the eth node, falling back to the known config for the chain id")
+	rootCmd.PersistentFlags().StringSlice("eth-multihashes", nil, "hash function to key an IPLD node type under, as nodeType=hashName e.g. state=sha2-256 (default keccak-256 for every node type)")
+	rootCmd.PersistentFlags().Float64("eth-rpc-rate-limit", 0, "maximum number of rpc requests per second made to the eth node, shared by all workers (0 disables the limit)")
 
 	// and their .toml config bindings
 	viper.BindPFlag("database.name", rootCmd.PersistentFlags().Lookup("database-name"))
@@ -107,15 +127,28 @@ func init() {
 	viper.BindPFlag("database.hostname", rootCmd.PersistentFlags().Lookup("database-hostname"))
 	viper.BindPFlag("database.user", rootCmd.PersistentFlags().Lookup("database-user"))
 	viper.BindPFlag("database.password", rootCmd.PersistentFlags().Lookup("database-password"))
+	viper.BindPFlag("database.maxIdle", rootCmd.PersistentFlags().Lookup("database-max-idle"))
+	viper.BindPFlag("database.maxOpen", rootCmd.PersistentFlags().Lookup("database-max-open"))
+	viper.BindPFlag("database.maxLifetime", rootCmd.PersistentFlags().Lookup("database-max-lifetime"))
+	viper.BindPFlag("database.verifyIndexes", rootCmd.PersistentFlags().Lookup("database-verify-indexes"))
+	viper.BindPFlag("database.schema", rootCmd.PersistentFlags().Lookup("database-schema"))
+	viper.BindPFlag("database.publishCacheSize", rootCmd.PersistentFlags().Lookup("database-publish-cache-size"))
 
 	viper.BindPFlag("logfile", rootCmd.PersistentFlags().Lookup("logfile"))
 	viper.BindPFlag("log.level", rootCmd.PersistentFlags().Lookup("log-level"))
 
+	viper.BindPFlag("metrics.enabled", rootCmd.PersistentFlags().Lookup("metrics"))
+	viper.BindPFlag("metrics.httpAddr", rootCmd.PersistentFlags().Lookup("metrics-http-addr"))
+
 	viper.BindPFlag("ethereum.nodeID", rootCmd.PersistentFlags().Lookup("eth-node-id"))
 	viper.BindPFlag("ethereum.clientName", rootCmd.PersistentFlags().Lookup("eth-client-name"))
 	viper.BindPFlag("ethereum.genesisBlock", rootCmd.PersistentFlags().Lookup("eth-genesis-block"))
 	viper.BindPFlag("ethereum.networkID", rootCmd.PersistentFlags().Lookup("eth-network-id"))
 	viper.BindPFlag("ethereum.chainID", rootCmd.PersistentFlags().Lookup("eth-chain-id"))
+	viper.BindPFlag("ethereum.strictChainID", rootCmd.PersistentFlags().Lookup("eth-strict-chain-id"))
+	viper.BindPFlag("ethereum.chainConfigFromNode", rootCmd.PersistentFlags().Lookup("eth-chain-config-from-node"))
+	viper.BindPFlag("ethereum.rpcRateLimit", rootCmd.PersistentFlags().Lookup("eth-rpc-rate-limit"))
+	viper.BindPFlag("ethereum.multihashes", rootCmd.PersistentFlags().Lookup("eth-multihashes"))
 }
 
 func initConfig() {
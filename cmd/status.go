@@ -0,0 +1,88 @@
+// Copyright © 2020 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/node"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+	"github.com/vulcanize/ipld-eth-indexer/utils"
+)
+
+// statusLagTimeout is the timeout for fetching the head of the chain when reporting the indexing lag
+const statusLagTimeout = 15 * time.Second
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Print a summary of the indexed data as JSON",
+	Long: `Use this command to print the lowest and highest indexed block, the highest block below which no block is missing,
+the number of indexed headers, and the number of gaps along with the number of blocks they span, as a single JSON document
+Blocks validated fewer than --validation-level times are counted as gaps, as they are by the backfill process
+If --eth-http-path is set, the distance between the head of the chain and the highest indexed block is included`,
+	Run: func(cmd *cobra.Command, args []string) {
+		subCommand = cmd.CalledAs()
+		logWithCommand = *log.WithField("SubCommand", subCommand)
+		printStatus()
+	},
+}
+
+func printStatus() {
+	var dbConfig postgres.Config
+	dbConfig.Init()
+	db := utils.LoadPostgres(dbConfig, node.Info{})
+	var lagTracker *eth.LagTracker
+	viper.BindEnv("ethereum.httpPath", shared.ETH_HTTP_PATH)
+	if ethHTTP := viper.GetString("ethereum.httpPath"); ethHTTP != "" {
+		client, err := rpc.Dial(shared.EthEndpoint(ethHTTP, "http"))
+		if err != nil {
+			logWithCommand.Fatal(err)
+		}
+		lagTracker = eth.NewLagTracker(eth.NewRateLimitedHeaderClient(ethclient.NewClient(client), shared.RPCRateLimiter()), eth.NewGapRetriever(&db), statusLagTimeout)
+	}
+	indexStatus, err := eth.NewStatusReporter(&db, lagTracker).Status(viper.GetInt("status.validationLevel"))
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	out, err := json.MarshalIndent(indexStatus, "", "  ")
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	fmt.Println(string(out))
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	// flags
+	statusCmd.PersistentFlags().Int("validation-level", 1, "blocks validated fewer than this many times are counted as gaps")
+	statusCmd.PersistentFlags().String("eth-http-path", "", "http url for ethereum node, the indexing lag is only reported if this is set")
+
+	// and their .toml config bindings
+	viper.BindPFlag("status.validationLevel", statusCmd.PersistentFlags().Lookup("validation-level"))
+	viper.BindPFlag("ethereum.httpPath", statusCmd.PersistentFlags().Lookup("eth-http-path"))
+}
@@ -78,9 +78,15 @@ func init() {
 
 	// flags
 	syncCmd.PersistentFlags().Int("sync-workers", 0, "how many worker goroutines to publish and index data")
+	syncCmd.PersistentFlags().Bool("sync-persist-payloads", false, "also store the raw payload of each block in eth.payloads so that it can be reprocessed without a node")
+	syncCmd.PersistentFlags().String("sync-event-socket", "", "path of a unix socket to write a json event to for each committed block")
+	syncCmd.PersistentFlags().Int("sync-confirmations", 0, "only index each block once it is this many blocks behind the highest block received")
 	syncCmd.PersistentFlags().String("eth-ws-path", "", "ws url for ethereum node")
 
 	// and their .toml config bindings
 	viper.BindPFlag("sync.workers", syncCmd.PersistentFlags().Lookup("sync-workers"))
+	viper.BindPFlag("sync.persistPayloads", syncCmd.PersistentFlags().Lookup("sync-persist-payloads"))
+	viper.BindPFlag("sync.eventSocket", syncCmd.PersistentFlags().Lookup("sync-event-socket"))
+	viper.BindPFlag("sync.confirmations", syncCmd.PersistentFlags().Lookup("sync-confirmations"))
 	viper.BindPFlag("ethereum.wsPath", syncCmd.PersistentFlags().Lookup("eth-ws-path"))
 }
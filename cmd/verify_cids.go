@@ -0,0 +1,109 @@
+// Copyright © 2020 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+	"github.com/vulcanize/ipld-eth-indexer/utils"
+	v "github.com/vulcanize/ipld-eth-indexer/version"
+)
+
+// verifyCIDsCmd represents the verify-cids command
+var verifyCIDsCmd = &cobra.Command{
+	Use:   "verify-cids",
+	Short: "Check the CIDs generated for a block range without storing anything",
+	Long: `Use this command to confirm that the CIDs the indexer generates are correct for an explicit block range
+The statediff payload of each block is fetched and transformed as a dry run, and instead of being stored each IPLD
+has its expected CID and multihash key derived from its raw data, any mismatches are reported with their node type and block number
+Nothing is written to Postgres, so no database is needed unless --check-indexed is set, in which case the CIDs indexed for
+each block in the configured database are also checked against those derived from its payload
+
+NOTE: Requires a syncmode=full gcmode=archive statediffing go-ethereum node`,
+	Run: func(cmd *cobra.Command, args []string) {
+		subCommand = cmd.CalledAs()
+		logWithCommand = *log.WithField("SubCommand", subCommand)
+		verifyCIDs()
+	},
+}
+
+func verifyCIDs() {
+	logWithCommand.Infof("running ipld-eth-indexer version: %s", v.VersionWithMeta)
+	viper.BindEnv("ethereum.httpPath", shared.ETH_HTTP_PATH)
+	viper.BindEnv("verifyCIDs.timeout", shared.HTTP_TIMEOUT)
+	timeout := viper.GetInt("verifyCIDs.timeout")
+	if timeout < 5 {
+		timeout = 5
+	}
+	nodeInfo, client, err := shared.GetEthNodeAndClient(shared.EthEndpoint(viper.GetString("ethereum.httpPath"), "http"))
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	chainConfig, err := eth.ResolveChainConfig(client, nodeInfo.ChainID, shared.ChainConfigFromNode())
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	verifier := eth.NewCIDVerifier(chainConfig, eth.NewPayloadFetcher(eth.NewRateLimitedBatchClient(client, shared.RPCRateLimiter()), time.Second*time.Duration(timeout)))
+	multihashes, err := eth.ParseMultihashes(shared.MultihashEntries())
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	if err := verifier.SetMultihashes(multihashes); err != nil {
+		logWithCommand.Fatal(err)
+	}
+	if viper.GetBool("verifyCIDs.checkIndexed") {
+		var dbConfig postgres.Config
+		dbConfig.Init()
+		db := utils.LoadPostgres(dbConfig, nodeInfo)
+		verifier.Retriever = eth.NewCIDRetriever(&db)
+	}
+	start, stop := uint64(viper.GetInt64("verifyCIDs.start")), uint64(viper.GetInt64("verifyCIDs.stop"))
+	logWithCommand.Infof("verifying ethereum cids from %d to %d", start, stop)
+	mismatches, err := verifier.Verify(start, stop)
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	for _, mismatch := range mismatches {
+		logWithCommand.Warnf("block %d: %s node cid %s, derived cid %s, mh key %s, derived mh key %s", mismatch.BlockNumber,
+			mismatch.NodeType, mismatch.CID, mismatch.DerivedCID, mismatch.MhKey, mismatch.DerivedMhKey)
+	}
+	logWithCommand.Infof("ethereum cid verification finished, found %d mismatched cids", len(mismatches))
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCIDsCmd)
+
+	// flags
+	verifyCIDsCmd.PersistentFlags().Int("start", 0, "block height to start verification")
+	verifyCIDsCmd.PersistentFlags().Int("stop", 0, "block height to stop verification")
+	verifyCIDsCmd.PersistentFlags().Int("timeout", 15, "timeout used for the statediff http requests (in seconds)")
+	verifyCIDsCmd.PersistentFlags().String("eth-http-path", "", "http url for ethereum node")
+	verifyCIDsCmd.PersistentFlags().Bool("check-indexed", false, "also check the cids indexed in the database against those derived for each block")
+
+	// and their .toml config bindings
+	viper.BindPFlag("verifyCIDs.start", verifyCIDsCmd.PersistentFlags().Lookup("start"))
+	viper.BindPFlag("verifyCIDs.stop", verifyCIDsCmd.PersistentFlags().Lookup("stop"))
+	viper.BindPFlag("verifyCIDs.timeout", verifyCIDsCmd.PersistentFlags().Lookup("timeout"))
+	viper.BindPFlag("ethereum.httpPath", verifyCIDsCmd.PersistentFlags().Lookup("eth-http-path"))
+	viper.BindPFlag("verifyCIDs.checkIndexed", verifyCIDsCmd.PersistentFlags().Lookup("check-indexed"))
+}
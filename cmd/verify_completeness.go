@@ -0,0 +1,77 @@
+// Copyright © 2020 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/node"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/utils"
+	v "github.com/vulcanize/ipld-eth-indexer/version"
+)
+
+// verifyCompletenessCmd represents the verify-completeness command
+var verifyCompletenessCmd = &cobra.Command{
+	Use:   "verify-completeness",
+	Short: "Check that the txs and receipts of each indexed header are all indexed",
+	Long: `Use this command to find the blocks in an explicit range that were only partially indexed
+For each indexed header the trie root of its indexed transactions is derived from their IPLDs and compared to the header's
+transaction root, and its number of indexed receipts is compared to its number of indexed transactions
+The gap finder only checks that a header is indexed at each height, so it does not catch these blocks
+Headers recorded as gaps, such as those indexed in headers only mode, are skipped
+
+NOTE: blocks indexed with receipt filtering enabled are reported, as their filtered out receipts are not indexed`,
+	Run: func(cmd *cobra.Command, args []string) {
+		subCommand = cmd.CalledAs()
+		logWithCommand = *log.WithField("SubCommand", subCommand)
+		verifyCompleteness()
+	},
+}
+
+func verifyCompleteness() {
+	logWithCommand.Infof("running ipld-eth-indexer version: %s", v.VersionWithMeta)
+	var dbConfig postgres.Config
+	dbConfig.Init()
+	db := utils.LoadPostgres(dbConfig, node.Info{})
+	verifier := eth.NewCompletenessVerifier(&db)
+	start, stop := viper.GetUint64("verifyCompleteness.start"), viper.GetUint64("verifyCompleteness.stop")
+	logWithCommand.Infof("verifying the completeness of the ethereum blocks indexed from %d to %d", start, stop)
+	incomplete, err := verifier.Verify(start, stop)
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	for _, block := range incomplete {
+		logWithCommand.Warnf("block %d (%s): %d txs and %d receipts indexed, %s", block.BlockNumber, block.BlockHash,
+			block.TxCount, block.RctCount, block.Reason)
+	}
+	logWithCommand.Infof("ethereum completeness verification finished, found %d incomplete blocks", len(incomplete))
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCompletenessCmd)
+
+	// flags
+	verifyCompletenessCmd.PersistentFlags().Uint64("start", 0, "block height to start verification")
+	verifyCompletenessCmd.PersistentFlags().Uint64("stop", 0, "block height to stop verification")
+
+	// and their .toml config bindings
+	viper.BindPFlag("verifyCompleteness.start", verifyCompletenessCmd.PersistentFlags().Lookup("start"))
+	viper.BindPFlag("verifyCompleteness.stop", verifyCompletenessCmd.PersistentFlags().Lookup("stop"))
+}
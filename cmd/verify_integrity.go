@@ -0,0 +1,83 @@
+// Copyright © 2020 Vulcanize, Inc
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/node"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/utils"
+	v "github.com/vulcanize/ipld-eth-indexer/version"
+)
+
+// verifyIntegrityCmd represents the verify-integrity command
+var verifyIntegrityCmd = &cobra.Command{
+	Use:   "verify-integrity",
+	Short: "Check the database for orphaned rows",
+	Long: `Use this command to check the indexed data for rows whose foreign key references a row that does not exist
+It checks receipts against transactions, transactions against headers, storage nodes against state nodes
+and state nodes against headers, reporting the number of orphaned rows found by each check along with a sample of their ids
+
+With --fix the orphaned rows, and the rows that reference them, are deleted in a single transaction`,
+	Run: func(cmd *cobra.Command, args []string) {
+		subCommand = cmd.CalledAs()
+		logWithCommand = *log.WithField("SubCommand", subCommand)
+		verifyIntegrity()
+	},
+}
+
+func verifyIntegrity() {
+	logWithCommand.Infof("running ipld-eth-indexer version: %s", v.VersionWithMeta)
+	var dbConfig postgres.Config
+	dbConfig.Init()
+	db := utils.LoadPostgres(dbConfig, node.Info{})
+	checker := eth.NewIntegrityChecker(&db)
+	var orphans []eth.Orphans
+	var err error
+	if viper.GetBool("verifyIntegrity.fix") {
+		logWithCommand.Info("deleting orphaned rows")
+		orphans, err = checker.Fix()
+	} else {
+		orphans, err = checker.Verify()
+	}
+	if err != nil {
+		logWithCommand.Fatal(err)
+	}
+	var total int64
+	for _, o := range orphans {
+		if o.Count == 0 {
+			logWithCommand.Infof("%s: no orphaned rows", o.Check)
+			continue
+		}
+		logWithCommand.Warnf("%s: %d orphaned rows, sample ids %v", o.Check, o.Count, o.SampleIDs)
+		total += o.Count
+	}
+	logWithCommand.Infof("integrity check finished, found %d orphaned rows", total)
+}
+
+func init() {
+	rootCmd.AddCommand(verifyIntegrityCmd)
+
+	// flags
+	verifyIntegrityCmd.PersistentFlags().Bool("fix", false, "delete the orphaned rows that are found")
+
+	// and their .toml config bindings
+	viper.BindPFlag("verifyIntegrity.fix", verifyIntegrityCmd.PersistentFlags().Lookup("fix"))
+}
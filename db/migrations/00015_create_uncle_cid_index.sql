@@ -0,0 +1,5 @@
+-- +goose Up
+CREATE INDEX uncle_cid_index ON eth.uncle_cids USING btree (cid);
+
+-- +goose Down
+DROP INDEX eth.uncle_cid_index;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE eth.transaction_cids
+ADD COLUMN r NUMERIC,
+ADD COLUMN s NUMERIC,
+ADD COLUMN v NUMERIC;
+
+-- +goose Down
+ALTER TABLE eth.transaction_cids
+DROP COLUMN v,
+DROP COLUMN s,
+DROP COLUMN r;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE eth.uncle_cids
+ADD COLUMN block_number BIGINT,
+ADD COLUMN coinbase VARCHAR(66);
+
+-- +goose Down
+ALTER TABLE eth.uncle_cids
+DROP COLUMN coinbase,
+DROP COLUMN block_number;
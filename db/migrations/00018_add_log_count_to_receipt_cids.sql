@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE eth.receipt_cids
+ADD COLUMN log_count INTEGER;
+
+-- +goose Down
+ALTER TABLE eth.receipt_cids
+DROP COLUMN log_count;
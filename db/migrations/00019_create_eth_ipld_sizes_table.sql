@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE eth.ipld_sizes (
+  header_id             INTEGER PRIMARY KEY REFERENCES eth.header_cids (id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+  header_bytes          BIGINT NOT NULL,
+  uncle_bytes           BIGINT NOT NULL,
+  tx_bytes              BIGINT NOT NULL,
+  receipt_bytes         BIGINT NOT NULL,
+  state_bytes           BIGINT NOT NULL,
+  storage_bytes         BIGINT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE eth.ipld_sizes;
@@ -0,0 +1,100 @@
+-- +goose Up
+CREATE TABLE eth.addresses (
+  id                    SERIAL PRIMARY KEY,
+  address               VARCHAR(66) NOT NULL UNIQUE
+);
+
+INSERT INTO eth.addresses (address)
+SELECT src FROM eth.transaction_cids WHERE src <> ''
+UNION SELECT dst FROM eth.transaction_cids WHERE dst <> ''
+UNION SELECT contract FROM eth.receipt_cids WHERE contract <> ''
+UNION SELECT unnest(log_contracts) FROM eth.receipt_cids;
+
+ALTER TABLE eth.transaction_cids
+ADD COLUMN src_id INTEGER REFERENCES eth.addresses (id),
+ADD COLUMN dst_id INTEGER REFERENCES eth.addresses (id);
+
+UPDATE eth.transaction_cids
+SET src_id = (SELECT id FROM eth.addresses WHERE address = src),
+    dst_id = (SELECT id FROM eth.addresses WHERE address = dst);
+
+ALTER TABLE eth.transaction_cids
+ALTER COLUMN src_id SET NOT NULL,
+DROP COLUMN src,
+DROP COLUMN dst;
+
+ALTER TABLE eth.receipt_cids
+ADD COLUMN contract_id INTEGER REFERENCES eth.addresses (id),
+ADD COLUMN log_contract_ids INTEGER[];
+
+UPDATE eth.receipt_cids
+SET contract_id = (SELECT id FROM eth.addresses WHERE address = contract),
+    log_contract_ids = ARRAY(SELECT addresses.id FROM unnest(log_contracts) WITH ORDINALITY AS c (address, ord)
+                             INNER JOIN eth.addresses ON (addresses.address = c.address) ORDER BY c.ord);
+
+ALTER TABLE eth.receipt_cids
+DROP COLUMN contract,
+DROP COLUMN log_contracts;
+
+CREATE INDEX tx_src_id_index ON eth.transaction_cids USING btree (src_id);
+
+CREATE INDEX tx_dst_id_index ON eth.transaction_cids USING btree (dst_id);
+
+CREATE INDEX rct_contract_id_index ON eth.receipt_cids USING btree (contract_id);
+
+CREATE INDEX rct_log_contract_ids_index ON eth.receipt_cids USING gin (log_contract_ids);
+
+-- the views join the address ids back to their hex, with the columns the tables had before they were normalized
+CREATE VIEW eth.transaction_cids_with_addresses AS
+SELECT transaction_cids.*, src.address AS src, COALESCE(dst.address, '') AS dst
+FROM eth.transaction_cids
+INNER JOIN eth.addresses src ON (transaction_cids.src_id = src.id)
+LEFT JOIN eth.addresses dst ON (transaction_cids.dst_id = dst.id);
+
+CREATE VIEW eth.receipt_cids_with_addresses AS
+SELECT receipt_cids.*, COALESCE(contract.address, '') AS contract,
+       ARRAY(SELECT addresses.address FROM unnest(receipt_cids.log_contract_ids) WITH ORDINALITY AS c (id, ord)
+             INNER JOIN eth.addresses ON (addresses.id = c.id) ORDER BY c.ord) AS log_contracts
+FROM eth.receipt_cids
+LEFT JOIN eth.addresses contract ON (receipt_cids.contract_id = contract.id);
+
+-- +goose Down
+DROP VIEW eth.receipt_cids_with_addresses;
+DROP VIEW eth.transaction_cids_with_addresses;
+
+ALTER TABLE eth.receipt_cids
+ADD COLUMN contract VARCHAR(66),
+ADD COLUMN log_contracts VARCHAR(66)[];
+
+UPDATE eth.receipt_cids
+SET contract = COALESCE((SELECT address FROM eth.addresses WHERE id = contract_id), ''),
+    log_contracts = ARRAY(SELECT addresses.address FROM unnest(log_contract_ids) WITH ORDINALITY AS c (id, ord)
+                          INNER JOIN eth.addresses ON (addresses.id = c.id) ORDER BY c.ord);
+
+ALTER TABLE eth.receipt_cids
+DROP COLUMN contract_id,
+DROP COLUMN log_contract_ids;
+
+ALTER TABLE eth.transaction_cids
+ADD COLUMN src VARCHAR(66),
+ADD COLUMN dst VARCHAR(66);
+
+UPDATE eth.transaction_cids
+SET src = (SELECT address FROM eth.addresses WHERE id = src_id),
+    dst = COALESCE((SELECT address FROM eth.addresses WHERE id = dst_id), '');
+
+ALTER TABLE eth.transaction_cids
+ALTER COLUMN src SET NOT NULL,
+ALTER COLUMN dst SET NOT NULL,
+DROP COLUMN src_id,
+DROP COLUMN dst_id;
+
+CREATE INDEX tx_src_index ON eth.transaction_cids USING btree (src);
+
+CREATE INDEX tx_dst_index ON eth.transaction_cids USING btree (dst);
+
+CREATE INDEX rct_contract_index ON eth.receipt_cids USING btree (contract);
+
+CREATE INDEX rct_log_contract_index ON eth.receipt_cids USING gin (log_contracts);
+
+DROP TABLE eth.addresses;
@@ -0,0 +1,32 @@
+-- +goose Up
+ALTER TABLE eth.transaction_cids
+ADD COLUMN method_id VARCHAR(10);
+
+UPDATE eth.transaction_cids
+SET method_id = '0x' || encode(substring(tx_data FROM 1 FOR 4), 'hex')
+WHERE dst_id IS NOT NULL AND length(tx_data) >= 4;
+
+CREATE INDEX tx_method_id_index ON eth.transaction_cids USING btree (method_id);
+
+-- the view's columns were fixed when it was created, so it is recreated to pick up the new column
+DROP VIEW eth.transaction_cids_with_addresses;
+
+CREATE VIEW eth.transaction_cids_with_addresses AS
+SELECT transaction_cids.*, src.address AS src, COALESCE(dst.address, '') AS dst
+FROM eth.transaction_cids
+INNER JOIN eth.addresses src ON (transaction_cids.src_id = src.id)
+LEFT JOIN eth.addresses dst ON (transaction_cids.dst_id = dst.id);
+
+-- +goose Down
+DROP VIEW eth.transaction_cids_with_addresses;
+
+DROP INDEX eth.tx_method_id_index;
+
+ALTER TABLE eth.transaction_cids
+DROP COLUMN method_id;
+
+CREATE VIEW eth.transaction_cids_with_addresses AS
+SELECT transaction_cids.*, src.address AS src, COALESCE(dst.address, '') AS dst
+FROM eth.transaction_cids
+INNER JOIN eth.addresses src ON (transaction_cids.src_id = src.id)
+LEFT JOIN eth.addresses dst ON (transaction_cids.dst_id = dst.id);
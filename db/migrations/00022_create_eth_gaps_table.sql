@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE eth.gaps (
+  header_id             INTEGER PRIMARY KEY REFERENCES eth.header_cids (id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+  block_number          BIGINT NOT NULL,
+  phase                 VARCHAR(16) NOT NULL
+);
+
+CREATE INDEX gaps_block_number_index ON eth.gaps USING btree (block_number);
+
+-- +goose Down
+DROP INDEX eth.gaps_block_number_index;
+
+DROP TABLE eth.gaps;
@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE eth.uncle_cids
+ADD COLUMN index INTEGER;
+
+-- uncles have been indexed in the order they are listed in their block, so their ids preserve that order
+UPDATE eth.uncle_cids
+SET index = ordered.index
+FROM (SELECT id, row_number() OVER (PARTITION BY header_id ORDER BY id) - 1 AS index FROM eth.uncle_cids) AS ordered
+WHERE uncle_cids.id = ordered.id;
+
+ALTER TABLE eth.uncle_cids
+ALTER COLUMN index SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE eth.uncle_cids
+DROP COLUMN index;
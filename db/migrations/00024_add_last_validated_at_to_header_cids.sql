@@ -0,0 +1,12 @@
+-- +goose Up
+-- headers indexed before this column existed have no recorded validation time and are left NULL
+ALTER TABLE eth.header_cids
+ADD COLUMN last_validated_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX header_last_validated_index ON eth.header_cids USING btree (last_validated_at);
+
+-- +goose Down
+DROP INDEX eth.header_last_validated_index;
+
+ALTER TABLE eth.header_cids
+DROP COLUMN last_validated_at;
@@ -0,0 +1,25 @@
+-- +goose Up
+-- only populated by transformers with raw transaction indexing enabled
+ALTER TABLE eth.transaction_cids
+ADD COLUMN tx_rlp BYTEA;
+
+-- the view's columns were fixed when it was created, so it is recreated to pick up the new column
+DROP VIEW eth.transaction_cids_with_addresses;
+
+CREATE VIEW eth.transaction_cids_with_addresses AS
+SELECT transaction_cids.*, src.address AS src, COALESCE(dst.address, '') AS dst
+FROM eth.transaction_cids
+INNER JOIN eth.addresses src ON (transaction_cids.src_id = src.id)
+LEFT JOIN eth.addresses dst ON (transaction_cids.dst_id = dst.id);
+
+-- +goose Down
+DROP VIEW eth.transaction_cids_with_addresses;
+
+ALTER TABLE eth.transaction_cids
+DROP COLUMN tx_rlp;
+
+CREATE VIEW eth.transaction_cids_with_addresses AS
+SELECT transaction_cids.*, src.address AS src, COALESCE(dst.address, '') AS dst
+FROM eth.transaction_cids
+INNER JOIN eth.addresses src ON (transaction_cids.src_id = src.id)
+LEFT JOIN eth.addresses dst ON (transaction_cids.dst_id = dst.id);
@@ -0,0 +1,14 @@
+-- +goose Up
+-- the raw statediff payloads of indexed blocks, only written by transformers with payload persistence enabled
+-- so that blocks can be reprocessed without refetching them from a node
+CREATE TABLE eth.payloads (
+  block_number          BIGINT PRIMARY KEY,
+  block_hash            VARCHAR(66) NOT NULL,
+  td                    NUMERIC NOT NULL,
+  block_rlp             BYTEA NOT NULL,
+  receipts_rlp          BYTEA NOT NULL,
+  state_object_rlp      BYTEA NOT NULL
+);
+
+-- +goose Down
+DROP TABLE eth.payloads;
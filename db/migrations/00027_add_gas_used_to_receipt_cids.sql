@@ -0,0 +1,31 @@
+-- +goose Up
+-- the gas used by the receipt's own transaction, rather than the block's cumulative gas used up to and including it
+ALTER TABLE eth.receipt_cids
+ADD COLUMN gas_used BIGINT;
+
+CREATE INDEX rct_gas_used_index ON eth.receipt_cids USING btree (gas_used);
+
+-- the view's columns were fixed when it was created, so it is recreated to pick up the new column
+DROP VIEW eth.receipt_cids_with_addresses;
+
+CREATE VIEW eth.receipt_cids_with_addresses AS
+SELECT receipt_cids.*, COALESCE(contract.address, '') AS contract,
+       ARRAY(SELECT addresses.address FROM unnest(receipt_cids.log_contract_ids) WITH ORDINALITY AS c (id, ord)
+             INNER JOIN eth.addresses ON (addresses.id = c.id) ORDER BY c.ord) AS log_contracts
+FROM eth.receipt_cids
+LEFT JOIN eth.addresses contract ON (receipt_cids.contract_id = contract.id);
+
+-- +goose Down
+DROP VIEW eth.receipt_cids_with_addresses;
+
+DROP INDEX eth.rct_gas_used_index;
+
+ALTER TABLE eth.receipt_cids
+DROP COLUMN gas_used;
+
+CREATE VIEW eth.receipt_cids_with_addresses AS
+SELECT receipt_cids.*, COALESCE(contract.address, '') AS contract,
+       ARRAY(SELECT addresses.address FROM unnest(receipt_cids.log_contract_ids) WITH ORDINALITY AS c (id, ord)
+             INNER JOIN eth.addresses ON (addresses.id = c.id) ORDER BY c.ord) AS log_contracts
+FROM eth.receipt_cids
+LEFT JOIN eth.addresses contract ON (receipt_cids.contract_id = contract.id);
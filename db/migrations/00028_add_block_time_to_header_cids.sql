@@ -0,0 +1,17 @@
+-- +goose Up
+-- the header's timestamp as a timestamptz, so time range queries don't need to convert from the raw epoch seconds
+ALTER TABLE eth.header_cids
+ADD COLUMN block_time TIMESTAMP WITH TIME ZONE;
+
+UPDATE eth.header_cids SET block_time = to_timestamp("timestamp");
+
+ALTER TABLE eth.header_cids
+ALTER COLUMN block_time SET NOT NULL;
+
+CREATE INDEX block_time_index ON eth.header_cids USING brin (block_time);
+
+-- +goose Down
+DROP INDEX eth.block_time_index;
+
+ALTER TABLE eth.header_cids
+DROP COLUMN block_time;
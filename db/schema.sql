@@ -55,6 +55,36 @@ SET default_tablespace = '';
 
 SET default_table_access_method = heap;
 
+--
+-- Name: addresses; Type: TABLE; Schema: eth; Owner: -
+--
+
+CREATE TABLE eth.addresses (
+    id integer NOT NULL,
+    address character varying(66) NOT NULL
+);
+
+
+--
+-- Name: addresses_id_seq; Type: SEQUENCE; Schema: eth; Owner: -
+--
+
+CREATE SEQUENCE eth.addresses_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: addresses_id_seq; Type: SEQUENCE OWNED BY; Schema: eth; Owner: -
+--
+
+ALTER SEQUENCE eth.addresses_id_seq OWNED BY eth.addresses.id;
+
+
 --
 -- Name: header_cids; Type: TABLE; Schema: eth; Owner: -
 --
@@ -75,7 +105,9 @@ CREATE TABLE eth.header_cids (
     uncle_root character varying(66) NOT NULL,
     bloom bytea NOT NULL,
     "timestamp" numeric NOT NULL,
-    times_validated integer DEFAULT 1 NOT NULL
+    times_validated integer DEFAULT 1 NOT NULL,
+    last_validated_at timestamp with time zone,
+    block_time timestamp with time zone NOT NULL
 );
 
 
@@ -122,13 +154,15 @@ CREATE TABLE eth.receipt_cids (
     tx_id integer NOT NULL,
     cid text NOT NULL,
     mh_key text NOT NULL,
-    contract character varying(66),
     contract_hash character varying(66),
     topic0s character varying(66)[],
     topic1s character varying(66)[],
     topic2s character varying(66)[],
     topic3s character varying(66)[],
-    log_contracts character varying(66)[]
+    log_count integer,
+    contract_id integer,
+    log_contract_ids integer[],
+    gas_used bigint
 );
 
 
@@ -152,6 +186,46 @@ CREATE SEQUENCE eth.receipt_cids_id_seq
 ALTER SEQUENCE eth.receipt_cids_id_seq OWNED BY eth.receipt_cids.id;
 
 
+--
+-- Name: gaps; Type: TABLE; Schema: eth; Owner: -
+--
+
+CREATE TABLE eth.gaps (
+    header_id integer NOT NULL,
+    block_number bigint NOT NULL,
+    phase character varying(16) NOT NULL
+);
+
+
+--
+-- Name: ipld_sizes; Type: TABLE; Schema: eth; Owner: -
+--
+
+CREATE TABLE eth.ipld_sizes (
+    header_id integer NOT NULL,
+    header_bytes bigint NOT NULL,
+    uncle_bytes bigint NOT NULL,
+    tx_bytes bigint NOT NULL,
+    receipt_bytes bigint NOT NULL,
+    state_bytes bigint NOT NULL,
+    storage_bytes bigint NOT NULL
+);
+
+
+--
+-- Name: payloads; Type: TABLE; Schema: eth; Owner: -
+--
+
+CREATE TABLE eth.payloads (
+    block_number bigint NOT NULL,
+    block_hash character varying(66) NOT NULL,
+    td numeric NOT NULL,
+    block_rlp bytea NOT NULL,
+    receipts_rlp bytea NOT NULL,
+    state_object_rlp bytea NOT NULL
+);
+
+
 --
 -- Name: state_accounts; Type: TABLE; Schema: eth; Owner: -
 --
@@ -269,10 +343,15 @@ CREATE TABLE eth.transaction_cids (
     index integer NOT NULL,
     cid text NOT NULL,
     mh_key text NOT NULL,
-    dst character varying(66) NOT NULL,
-    src character varying(66) NOT NULL,
     deployment boolean NOT NULL,
-    tx_data bytea
+    tx_data bytea,
+    r numeric,
+    s numeric,
+    v numeric,
+    src_id integer NOT NULL,
+    dst_id integer,
+    method_id character varying(10),
+    tx_rlp bytea
 );
 
 
@@ -303,6 +382,60 @@ CREATE SEQUENCE eth.transaction_cids_id_seq
 ALTER SEQUENCE eth.transaction_cids_id_seq OWNED BY eth.transaction_cids.id;
 
 
+--
+-- Name: receipt_cids_with_addresses; Type: VIEW; Schema: eth; Owner: -
+--
+
+CREATE VIEW eth.receipt_cids_with_addresses AS
+ SELECT receipt_cids.id,
+    receipt_cids.tx_id,
+    receipt_cids.cid,
+    receipt_cids.mh_key,
+    receipt_cids.contract_hash,
+    receipt_cids.topic0s,
+    receipt_cids.topic1s,
+    receipt_cids.topic2s,
+    receipt_cids.topic3s,
+    receipt_cids.log_count,
+    receipt_cids.contract_id,
+    receipt_cids.log_contract_ids,
+    receipt_cids.gas_used,
+    COALESCE(contract.address, ''::character varying) AS contract,
+    ARRAY( SELECT addresses.address
+           FROM (unnest(receipt_cids.log_contract_ids) WITH ORDINALITY c(id, ord)
+             JOIN eth.addresses ON ((addresses.id = c.id)))
+          ORDER BY c.ord) AS log_contracts
+   FROM (eth.receipt_cids
+     LEFT JOIN eth.addresses contract ON ((receipt_cids.contract_id = contract.id)));
+
+
+--
+-- Name: transaction_cids_with_addresses; Type: VIEW; Schema: eth; Owner: -
+--
+
+CREATE VIEW eth.transaction_cids_with_addresses AS
+ SELECT transaction_cids.id,
+    transaction_cids.header_id,
+    transaction_cids.tx_hash,
+    transaction_cids.index,
+    transaction_cids.cid,
+    transaction_cids.mh_key,
+    transaction_cids.deployment,
+    transaction_cids.tx_data,
+    transaction_cids.r,
+    transaction_cids.s,
+    transaction_cids.v,
+    transaction_cids.src_id,
+    transaction_cids.dst_id,
+    transaction_cids.method_id,
+    transaction_cids.tx_rlp,
+    src.address AS src,
+    COALESCE(dst.address, ''::character varying) AS dst
+   FROM ((eth.transaction_cids
+     JOIN eth.addresses src ON ((transaction_cids.src_id = src.id)))
+     LEFT JOIN eth.addresses dst ON ((transaction_cids.dst_id = dst.id)));
+
+
 --
 -- Name: uncle_cids; Type: TABLE; Schema: eth; Owner: -
 --
@@ -314,7 +447,10 @@ CREATE TABLE eth.uncle_cids (
     parent_hash character varying(66) NOT NULL,
     cid text NOT NULL,
     mh_key text NOT NULL,
-    reward numeric NOT NULL
+    reward numeric NOT NULL,
+    block_number bigint,
+    coinbase character varying(66),
+    index integer NOT NULL
 );
 
 
@@ -428,6 +564,13 @@ CREATE SEQUENCE public.nodes_id_seq
 ALTER SEQUENCE public.nodes_id_seq OWNED BY public.nodes.id;
 
 
+--
+-- Name: addresses id; Type: DEFAULT; Schema: eth; Owner: -
+--
+
+ALTER TABLE ONLY eth.addresses ALTER COLUMN id SET DEFAULT nextval('eth.addresses_id_seq'::regclass);
+
+
 --
 -- Name: header_cids id; Type: DEFAULT; Schema: eth; Owner: -
 --
@@ -491,6 +634,22 @@ ALTER TABLE ONLY public.goose_db_version ALTER COLUMN id SET DEFAULT nextval('pu
 ALTER TABLE ONLY public.nodes ALTER COLUMN id SET DEFAULT nextval('public.nodes_id_seq'::regclass);
 
 
+--
+-- Name: addresses addresses_address_key; Type: CONSTRAINT; Schema: eth; Owner: -
+--
+
+ALTER TABLE ONLY eth.addresses
+    ADD CONSTRAINT addresses_address_key UNIQUE (address);
+
+
+--
+-- Name: addresses addresses_pkey; Type: CONSTRAINT; Schema: eth; Owner: -
+--
+
+ALTER TABLE ONLY eth.addresses
+    ADD CONSTRAINT addresses_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: header_cids header_cids_block_number_block_hash_key; Type: CONSTRAINT; Schema: eth; Owner: -
 --
@@ -523,6 +682,30 @@ ALTER TABLE ONLY eth.receipt_cids
     ADD CONSTRAINT receipt_cids_tx_id_key UNIQUE (tx_id);
 
 
+--
+-- Name: gaps gaps_pkey; Type: CONSTRAINT; Schema: eth; Owner: -
+--
+
+ALTER TABLE ONLY eth.gaps
+    ADD CONSTRAINT gaps_pkey PRIMARY KEY (header_id);
+
+
+--
+-- Name: ipld_sizes ipld_sizes_pkey; Type: CONSTRAINT; Schema: eth; Owner: -
+--
+
+ALTER TABLE ONLY eth.ipld_sizes
+    ADD CONSTRAINT ipld_sizes_pkey PRIMARY KEY (header_id);
+
+
+--
+-- Name: payloads payloads_pkey; Type: CONSTRAINT; Schema: eth; Owner: -
+--
+
+ALTER TABLE ONLY eth.payloads
+    ADD CONSTRAINT payloads_pkey PRIMARY KEY (block_number);
+
+
 --
 -- Name: state_accounts state_accounts_pkey; Type: CONSTRAINT; Schema: eth; Owner: -
 --
@@ -656,6 +839,20 @@ CREATE INDEX block_hash_index ON eth.header_cids USING btree (block_hash);
 CREATE INDEX block_number_index ON eth.header_cids USING brin (block_number);
 
 
+--
+-- Name: block_time_index; Type: INDEX; Schema: eth; Owner: -
+--
+
+CREATE INDEX block_time_index ON eth.header_cids USING brin (block_time);
+
+
+--
+-- Name: gaps_block_number_index; Type: INDEX; Schema: eth; Owner: -
+--
+
+CREATE INDEX gaps_block_number_index ON eth.gaps USING btree (block_number);
+
+
 --
 -- Name: header_cid_index; Type: INDEX; Schema: eth; Owner: -
 --
@@ -663,6 +860,13 @@ CREATE INDEX block_number_index ON eth.header_cids USING brin (block_number);
 CREATE INDEX header_cid_index ON eth.header_cids USING btree (cid);
 
 
+--
+-- Name: header_last_validated_index; Type: INDEX; Schema: eth; Owner: -
+--
+
+CREATE INDEX header_last_validated_index ON eth.header_cids USING btree (last_validated_at);
+
+
 --
 -- Name: header_mh_index; Type: INDEX; Schema: eth; Owner: -
 --
@@ -685,17 +889,24 @@ CREATE INDEX rct_contract_hash_index ON eth.receipt_cids USING btree (contract_h
 
 
 --
--- Name: rct_contract_index; Type: INDEX; Schema: eth; Owner: -
+-- Name: rct_contract_id_index; Type: INDEX; Schema: eth; Owner: -
 --
 
-CREATE INDEX rct_contract_index ON eth.receipt_cids USING btree (contract);
+CREATE INDEX rct_contract_id_index ON eth.receipt_cids USING btree (contract_id);
 
 
 --
--- Name: rct_log_contract_index; Type: INDEX; Schema: eth; Owner: -
+-- Name: rct_gas_used_index; Type: INDEX; Schema: eth; Owner: -
 --
 
-CREATE INDEX rct_log_contract_index ON eth.receipt_cids USING gin (log_contracts);
+CREATE INDEX rct_gas_used_index ON eth.receipt_cids USING btree (gas_used);
+
+
+--
+-- Name: rct_log_contract_ids_index; Type: INDEX; Schema: eth; Owner: -
+--
+
+CREATE INDEX rct_log_contract_ids_index ON eth.receipt_cids USING gin (log_contract_ids);
 
 
 --
@@ -846,10 +1057,10 @@ CREATE INDEX tx_data_index ON eth.transaction_cids USING btree (tx_data);
 
 
 --
--- Name: tx_dst_index; Type: INDEX; Schema: eth; Owner: -
+-- Name: tx_dst_id_index; Type: INDEX; Schema: eth; Owner: -
 --
 
-CREATE INDEX tx_dst_index ON eth.transaction_cids USING btree (dst);
+CREATE INDEX tx_dst_id_index ON eth.transaction_cids USING btree (dst_id);
 
 
 --
@@ -866,6 +1077,13 @@ CREATE INDEX tx_hash_index ON eth.transaction_cids USING btree (tx_hash);
 CREATE INDEX tx_header_id_index ON eth.transaction_cids USING btree (header_id);
 
 
+--
+-- Name: tx_method_id_index; Type: INDEX; Schema: eth; Owner: -
+--
+
+CREATE INDEX tx_method_id_index ON eth.transaction_cids USING btree (method_id);
+
+
 --
 -- Name: tx_mh_index; Type: INDEX; Schema: eth; Owner: -
 --
@@ -874,10 +1092,17 @@ CREATE INDEX tx_mh_index ON eth.transaction_cids USING btree (mh_key);
 
 
 --
--- Name: tx_src_index; Type: INDEX; Schema: eth; Owner: -
+-- Name: tx_src_id_index; Type: INDEX; Schema: eth; Owner: -
 --
 
-CREATE INDEX tx_src_index ON eth.transaction_cids USING btree (src);
+CREATE INDEX tx_src_id_index ON eth.transaction_cids USING btree (src_id);
+
+
+--
+-- Name: uncle_cid_index; Type: INDEX; Schema: eth; Owner: -
+--
+
+CREATE INDEX uncle_cid_index ON eth.uncle_cids USING btree (cid);
 
 
 --
@@ -945,6 +1170,14 @@ ALTER TABLE ONLY eth.header_cids
     ADD CONSTRAINT header_cids_node_id_fkey FOREIGN KEY (node_id) REFERENCES public.nodes(id) ON DELETE CASCADE;
 
 
+--
+-- Name: receipt_cids receipt_cids_contract_id_fkey; Type: FK CONSTRAINT; Schema: eth; Owner: -
+--
+
+ALTER TABLE ONLY eth.receipt_cids
+    ADD CONSTRAINT receipt_cids_contract_id_fkey FOREIGN KEY (contract_id) REFERENCES eth.addresses(id);
+
+
 --
 -- Name: receipt_cids receipt_cids_mh_key_fkey; Type: FK CONSTRAINT; Schema: eth; Owner: -
 --
@@ -961,6 +1194,22 @@ ALTER TABLE ONLY eth.receipt_cids
     ADD CONSTRAINT receipt_cids_tx_id_fkey FOREIGN KEY (tx_id) REFERENCES eth.transaction_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
 
 
+--
+-- Name: gaps gaps_header_id_fkey; Type: FK CONSTRAINT; Schema: eth; Owner: -
+--
+
+ALTER TABLE ONLY eth.gaps
+    ADD CONSTRAINT gaps_header_id_fkey FOREIGN KEY (header_id) REFERENCES eth.header_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
+
+
+--
+-- Name: ipld_sizes ipld_sizes_header_id_fkey; Type: FK CONSTRAINT; Schema: eth; Owner: -
+--
+
+ALTER TABLE ONLY eth.ipld_sizes
+    ADD CONSTRAINT ipld_sizes_header_id_fkey FOREIGN KEY (header_id) REFERENCES eth.header_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
+
+
 --
 -- Name: state_accounts state_accounts_state_id_fkey; Type: FK CONSTRAINT; Schema: eth; Owner: -
 --
@@ -1001,6 +1250,14 @@ ALTER TABLE ONLY eth.storage_cids
     ADD CONSTRAINT storage_cids_state_id_fkey FOREIGN KEY (state_id) REFERENCES eth.state_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
 
 
+--
+-- Name: transaction_cids transaction_cids_dst_id_fkey; Type: FK CONSTRAINT; Schema: eth; Owner: -
+--
+
+ALTER TABLE ONLY eth.transaction_cids
+    ADD CONSTRAINT transaction_cids_dst_id_fkey FOREIGN KEY (dst_id) REFERENCES eth.addresses(id);
+
+
 --
 -- Name: transaction_cids transaction_cids_header_id_fkey; Type: FK CONSTRAINT; Schema: eth; Owner: -
 --
@@ -1017,6 +1274,14 @@ ALTER TABLE ONLY eth.transaction_cids
     ADD CONSTRAINT transaction_cids_mh_key_fkey FOREIGN KEY (mh_key) REFERENCES public.blocks(key) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
 
 
+--
+-- Name: transaction_cids transaction_cids_src_id_fkey; Type: FK CONSTRAINT; Schema: eth; Owner: -
+--
+
+ALTER TABLE ONLY eth.transaction_cids
+    ADD CONSTRAINT transaction_cids_src_id_fkey FOREIGN KEY (src_id) REFERENCES eth.addresses(id);
+
+
 --
 -- Name: uncle_cids uncle_cids_header_id_fkey; Type: FK CONSTRAINT; Schema: eth; Owner: -
 --
@@ -0,0 +1,85 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultAddressCacheSize is the number of address ids a CIDIndexer caches
+const DefaultAddressCacheSize = 1 << 16
+
+// addressIDs holds the ids of the addresses resolved within a Postgres tx
+// they are only added to the CIDIndexer's cache once the tx has committed, as a rolled back tx takes the addresses it
+// inserted with it
+type addressIDs map[string]int64
+
+// addressIDCache is a bounded cache of the ids of the addresses in eth.addresses, keyed by their hex
+// once full the least recently used are evicted first, it is safe for concurrent use by the indexer's workers
+type addressIDCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	ids   map[string]*list.Element
+}
+
+// addressIDEntry is an element of the addressIDCache's order
+type addressIDEntry struct {
+	address string
+	id      int64
+}
+
+// newAddressIDCache returns an addressIDCache that holds up to size ids
+func newAddressIDCache(size int) *addressIDCache {
+	return &addressIDCache{
+		size:  size,
+		order: list.New(),
+		ids:   make(map[string]*list.Element, size),
+	}
+}
+
+// get returns the cached id of the address, an address that is found becomes the most recently used
+func (c *addressIDCache) get(address string) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.ids[address]
+	if !ok {
+		return 0, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*addressIDEntry).id, true
+}
+
+// add caches the ids, it must only be called once the tx they were resolved in has been committed
+func (c *addressIDCache) add(ids addressIDs) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for address, id := range ids {
+		if e, ok := c.ids[address]; ok {
+			e.Value.(*addressIDEntry).id = id
+			c.order.MoveToFront(e)
+			continue
+		}
+		if c.order.Len() >= c.size {
+			evicted := c.order.Back()
+			c.order.Remove(evicted)
+			delete(c.ids, evicted.Value.(*addressIDEntry).address)
+		}
+		c.ids[address] = c.order.PushFront(&addressIDEntry{address: address, id: id})
+	}
+}
@@ -0,0 +1,121 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+)
+
+// BlockDump is the indexed representation of a block, the rows of each of the cid tables that belong to it
+type BlockDump struct {
+	Header       HeaderModel
+	Uncles       []UncleModel
+	Transactions []TxModel
+	Receipts     []ReceiptModel
+	StateNodes   []StateNodeModel
+	StorageNodes []StorageNodeModel
+}
+
+// BlockDumper looks up everything that has been indexed for a block
+type BlockDumper struct {
+	db *postgres.DB
+}
+
+// NewBlockDumper returns a pointer to a new BlockDumper
+func NewBlockDumper(db *postgres.DB) *BlockDumper {
+	return &BlockDumper{
+		db: db,
+	}
+}
+
+// DumpByHash returns the indexed representation of the block with the provided hash
+// it returns sql.ErrNoRows if the block has not been indexed
+func (bd *BlockDumper) DumpByHash(hash string) (BlockDump, error) {
+	var header HeaderModel
+	pgStr := fmt.Sprintf(`SELECT * FROM %s.header_cids WHERE block_hash = $1`, bd.db.Schema)
+	if err := bd.db.Get(&header, pgStr, hash); err != nil {
+		return BlockDump{}, err
+	}
+	return bd.dump(header)
+}
+
+// DumpByNumber returns the indexed representation of each of the blocks indexed at the provided height
+// there is more than one if the height has been reorged, and none if it has not been indexed
+func (bd *BlockDumper) DumpByNumber(number int64) ([]BlockDump, error) {
+	headers := make([]HeaderModel, 0)
+	pgStr := fmt.Sprintf(`SELECT * FROM %s.header_cids WHERE block_number = $1 ORDER BY id`, bd.db.Schema)
+	if err := bd.db.Select(&headers, pgStr, number); err != nil {
+		return nil, err
+	}
+	dumps := make([]BlockDump, 0, len(headers))
+	for _, header := range headers {
+		dump, err := bd.dump(header)
+		if err != nil {
+			return nil, err
+		}
+		dumps = append(dumps, dump)
+	}
+	return dumps, nil
+}
+
+// dump collects the rows that reference the provided header
+// columns added after the tables were created are read as their zero value for rows indexed before they were added
+func (bd *BlockDumper) dump(header HeaderModel) (BlockDump, error) {
+	dump := BlockDump{
+		Header:       header,
+		Uncles:       make([]UncleModel, 0),
+		Transactions: make([]TxModel, 0),
+		Receipts:     make([]ReceiptModel, 0),
+		StateNodes:   make([]StateNodeModel, 0),
+		StorageNodes: make([]StorageNodeModel, 0),
+	}
+	pgStr := fmt.Sprintf(`SELECT id, header_id, block_hash, parent_hash, cid, mh_key, reward,
+				COALESCE(block_number::TEXT, '') AS block_number, COALESCE(coinbase, '') AS coinbase, index
+				FROM %s.uncle_cids WHERE header_id = $1 ORDER BY index`, bd.db.Schema)
+	if err := bd.db.Select(&dump.Uncles, pgStr, header.ID); err != nil {
+		return BlockDump{}, err
+	}
+	pgStr = fmt.Sprintf(`SELECT * FROM %s.transaction_cids_with_addresses WHERE header_id = $1 ORDER BY index`, bd.db.Schema)
+	if err := bd.db.Select(&dump.Transactions, pgStr, header.ID); err != nil {
+		return BlockDump{}, err
+	}
+	pgStr = fmt.Sprintf(`SELECT receipt_cids.id, receipt_cids.tx_id, receipt_cids.cid, receipt_cids.mh_key, receipt_cids.contract,
+				receipt_cids.contract_id, receipt_cids.contract_hash, receipt_cids.log_contracts, receipt_cids.log_contract_ids,
+				receipt_cids.topic0s, receipt_cids.topic1s, receipt_cids.topic2s, receipt_cids.topic3s,
+				COALESCE(receipt_cids.log_count, 0) AS log_count, COALESCE(receipt_cids.gas_used, 0) AS gas_used
+				FROM %[1]s.receipt_cids_with_addresses receipt_cids
+				INNER JOIN %[1]s.transaction_cids ON (receipt_cids.tx_id = transaction_cids.id)
+				WHERE transaction_cids.header_id = $1
+				ORDER BY transaction_cids.index`, bd.db.Schema)
+	if err := bd.db.Select(&dump.Receipts, pgStr, header.ID); err != nil {
+		return BlockDump{}, err
+	}
+	pgStr = fmt.Sprintf(`SELECT * FROM %s.state_cids WHERE header_id = $1 ORDER BY state_path`, bd.db.Schema)
+	if err := bd.db.Select(&dump.StateNodes, pgStr, header.ID); err != nil {
+		return BlockDump{}, err
+	}
+	pgStr = fmt.Sprintf(`SELECT storage_cids.* FROM %[1]s.storage_cids
+				INNER JOIN %[1]s.state_cids ON (storage_cids.state_id = state_cids.id)
+				WHERE state_cids.header_id = $1
+				ORDER BY state_cids.state_path, storage_cids.storage_path`, bd.db.Schema)
+	if err := bd.db.Select(&dump.StorageNodes, pgStr, header.ID); err != nil {
+		return BlockDump{}, err
+	}
+	return dump, nil
+}
@@ -0,0 +1,78 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"database/sql"
+
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("BlockDumper", func() {
+	var (
+		db     *postgres.DB
+		err    error
+		dumper *eth.BlockDumper
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		transformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+		_, err = transformer.Transform(1, mocks.MockStateDiffPayload)
+		Expect(err).ToNot(HaveOccurred())
+		dumper = eth.NewBlockDumper(db)
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	It("Dumps the rows indexed for the block with the provided hash", func() {
+		dump, err := dumper.DumpByHash(mocks.MockBlock.Hash().String())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dump.Header.CID).To(Equal(mocks.HeaderCID.String()))
+		Expect(dump.Uncles).To(BeEmpty())
+		Expect(len(dump.Transactions)).To(Equal(len(mocks.MockTransactions)))
+		Expect(dump.Transactions[0].CID).To(Equal(mocks.Trx1CID.String()))
+		Expect(len(dump.Receipts)).To(Equal(len(mocks.MockReceipts)))
+		Expect(dump.Receipts[0].CID).To(Equal(mocks.Rct1CID.String()))
+		Expect(len(dump.StateNodes)).To(Equal(len(mocks.StateDiffs)))
+		Expect(len(dump.StorageNodes)).To(Equal(1))
+		Expect(dump.StorageNodes[0].CID).To(Equal(mocks.StorageCID.String()))
+	})
+
+	It("Dumps each of the blocks indexed at the provided height", func() {
+		dumps, err := dumper.DumpByNumber(mocks.BlockNumber.Int64())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(dumps)).To(Equal(1))
+		Expect(dumps[0].Header.BlockHash).To(Equal(mocks.MockBlock.Hash().String()))
+		dumps, err = dumper.DumpByNumber(mocks.BlockNumber.Int64() + 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dumps).To(BeEmpty())
+	})
+
+	It("Returns sql.ErrNoRows for a hash that has not been indexed", func() {
+		_, err := dumper.DumpByHash(mocks.MockBlock.ParentHash().String())
+		Expect(err).To(Equal(sql.ErrNoRows))
+	})
+})
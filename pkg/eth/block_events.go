@@ -0,0 +1,149 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/prom"
+)
+
+// blockEventBuffer is the number of events buffered for each consumer, once a consumer falls this far behind further
+// events are dropped for it until it catches up
+const blockEventBuffer = 256
+
+// BlockEvent summarizes a block once everything indexed for it has been committed
+// the counts are of the CIDs indexed for the block, which can be fewer than are in the block if the transformer filters them
+type BlockEvent struct {
+	BlockNumber  uint64 `json:"blockNumber"`
+	BlockHash    string `json:"blockHash"`
+	Uncles       int    `json:"uncles"`
+	Transactions int    `json:"transactions"`
+	Receipts     int    `json:"receipts"`
+	Deployments  int    `json:"deployments"`
+	StateNodes   int    `json:"stateNodes"`
+	StorageNodes int    `json:"storageNodes"`
+	// DedupedNodes are the state and storage nodes whose IPLD had already been committed by a recent block, they are
+	// indexed for this block but their DedupedBytes of data were not written again
+	DedupedNodes int `json:"dedupedNodes,omitempty"`
+	DedupedBytes int `json:"dedupedBytes,omitempty"`
+	// DryRun is set on the events of a dry run, whose counts are of what would have been indexed, nothing was committed
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// BlockEventSink receives the BlockEvent of each block the transformer commits
+// Emit is called by the transformer workers, so it must be safe for concurrent use and must not block indexing
+type BlockEventSink interface {
+	Emit(event BlockEvent)
+}
+
+// UnixSocketEmitter is a BlockEventSink that writes each event as a line of JSON to every consumer connected to a Unix socket
+// an event is dropped for a consumer that is too slow to keep up, rather than holding up the transformer
+type UnixSocketEmitter struct {
+	listener  net.Listener
+	mu        sync.Mutex
+	consumers map[net.Conn]chan []byte
+}
+
+// NewUnixSocketEmitter returns a UnixSocketEmitter listening for consumers on a Unix socket at the provided path
+// a socket file left behind at the path by a previous run is replaced
+func NewUnixSocketEmitter(path string) (*UnixSocketEmitter, error) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	e := &UnixSocketEmitter{
+		listener:  listener,
+		consumers: make(map[net.Conn]chan []byte),
+	}
+	go e.accept()
+	logrus.Infof("emitting block events on unix socket %s", path)
+	return e, nil
+}
+
+// Emit satisfies the BlockEventSink interface
+func (e *UnixSocketEmitter) Emit(event BlockEvent) {
+	line, err := json.Marshal(event)
+	if err != nil {
+		logrus.Errorf("block event marshalling error: %v", err)
+		return
+	}
+	line = append(line, '\n')
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, events := range e.consumers {
+		select {
+		case events <- line:
+		default:
+			prom.IncDroppedBlockEvents()
+		}
+	}
+}
+
+// Close stops accepting consumers and disconnects the connected ones, the socket file is removed
+func (e *UnixSocketEmitter) Close() error {
+	err := e.listener.Close()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, events := range e.consumers {
+		close(events)
+	}
+	e.consumers = nil
+	return err
+}
+
+// accept registers each consumer that connects until the listener is closed
+func (e *UnixSocketEmitter) accept() {
+	for {
+		conn, err := e.listener.Accept()
+		if err != nil {
+			return
+		}
+		events := make(chan []byte, blockEventBuffer)
+		e.mu.Lock()
+		if e.consumers == nil {
+			// the emitter was closed while this consumer was connecting
+			e.mu.Unlock()
+			conn.Close()
+			return
+		}
+		e.consumers[conn] = events
+		e.mu.Unlock()
+		go e.serve(conn, events)
+	}
+}
+
+// serve writes the events buffered for a consumer to it until either it disconnects or the emitter is closed
+func (e *UnixSocketEmitter) serve(conn net.Conn, events chan []byte) {
+	defer conn.Close()
+	for line := range events {
+		if _, err := conn.Write(line); err != nil {
+			e.mu.Lock()
+			delete(e.consumers, conn)
+			e.mu.Unlock()
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+)
+
+var _ = Describe("UnixSocketEmitter", func() {
+	var (
+		dir     string
+		path    string
+		emitter *eth.UnixSocketEmitter
+		event   eth.BlockEvent
+	)
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "block-events")
+		Expect(err).ToNot(HaveOccurred())
+		path = filepath.Join(dir, "events.sock")
+		emitter, err = eth.NewUnixSocketEmitter(path)
+		Expect(err).ToNot(HaveOccurred())
+		event = eth.BlockEvent{
+			BlockNumber:  1,
+			BlockHash:    "0xabc",
+			Transactions: 3,
+			Receipts:     3,
+			StateNodes:   2,
+			StorageNodes: 1,
+		}
+	})
+	AfterEach(func() {
+		Expect(emitter.Close()).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("Writes each event as a line of JSON to a connected consumer", func() {
+		conn, err := net.Dial("unix", path)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		received := make(chan string, 1)
+		go func() {
+			line, _ := bufio.NewReader(conn).ReadString('\n')
+			received <- line
+		}()
+		// the consumer is registered asynchronously, so emit until it receives an event
+		var line string
+		Eventually(func() bool {
+			emitter.Emit(event)
+			select {
+			case line = <-received:
+				return true
+			default:
+				return false
+			}
+		}).Should(BeTrue())
+		var decoded eth.BlockEvent
+		Expect(json.Unmarshal([]byte(line), &decoded)).To(Succeed())
+		Expect(decoded).To(Equal(event))
+	})
+
+	It("Drops events for a consumer that is not reading rather than blocking", func() {
+		conn, err := net.Dial("unix", path)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		done := make(chan struct{})
+		go func() {
+			for i := 0; i < 100000; i++ {
+				emitter.Emit(event)
+			}
+			close(done)
+		}()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("Replaces a socket file left behind by a previous run", func() {
+		Expect(emitter.Close()).To(Succeed())
+		Expect(ioutil.WriteFile(path, nil, 0600)).To(Succeed())
+		var err error
+		emitter, err = eth.NewUnixSocketEmitter(path)
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
@@ -0,0 +1,78 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/sirupsen/logrus"
+)
+
+// chainConfigTimeout is the timeout of the admin_nodeInfo request made to read the chain config of the node
+const chainConfigTimeout = 15 * time.Second
+
+// CallClient is an interface to a geth rpc client that makes single calls; created to allow mock insertion
+type CallClient interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// adminNodeInfo is the part of the admin_nodeInfo response that holds the chain config of the node's eth protocol
+type adminNodeInfo struct {
+	Protocols struct {
+		Eth struct {
+			Config *params.ChainConfig `json:"config"`
+		} `json:"eth"`
+	} `json:"protocols"`
+}
+
+// NodeChainConfig reads the chain config the node is running with from its admin_nodeInfo
+// this is only available if the node exposes the admin api over the transport the client is connected with
+func NodeChainConfig(client CallClient) (*params.ChainConfig, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), chainConfigTimeout)
+	defer cancel()
+	var info adminNodeInfo
+	if err := client.CallContext(ctx, &info, "admin_nodeInfo"); err != nil {
+		return nil, err
+	}
+	config := info.Protocols.Eth.Config
+	if config == nil || config.ChainID == nil {
+		return nil, fmt.Errorf("admin_nodeInfo of the ethereum node does not include its chain config")
+	}
+	return config, nil
+}
+
+// ResolveChainConfig returns the chain config of the node if fromNode is set and the node reports it, and otherwise
+// the known chain config for the configured chain id
+// the node's chain config is used even if its chain id is not the configured one, as it is the config the node's data was produced with
+func ResolveChainConfig(client CallClient, chainID uint64, fromNode bool) (*params.ChainConfig, error) {
+	if fromNode {
+		config, err := NodeChainConfig(client)
+		if err == nil {
+			if !config.ChainID.IsUint64() || config.ChainID.Uint64() != chainID {
+				logrus.Warnf("chain config of the ethereum node is for chain id %s rather than the configured chain id %d, using the node's",
+					config.ChainID.String(), chainID)
+			}
+			logrus.Infof("using the chain config of the ethereum node: %s", config.String())
+			return config, nil
+		}
+		logrus.Warnf("unable to read the chain config of the ethereum node, falling back to the known chain config for chain id %d: %v", chainID, err)
+	}
+	return ChainConfig(chainID)
+}
@@ -0,0 +1,73 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+)
+
+var _ = Describe("ResolveChainConfig", func() {
+	var client *mocks.CallClient
+	BeforeEach(func() {
+		client = &mocks.CallClient{
+			ResultsToReturn: map[string]interface{}{
+				"admin_nodeInfo": map[string]interface{}{
+					"protocols": map[string]interface{}{
+						"eth": map[string]interface{}{
+							"config": params.RinkebyChainConfig,
+						},
+					},
+				},
+			},
+		}
+	})
+
+	It("Uses the chain config reported by the node", func() {
+		config, err := eth.ResolveChainConfig(client, 4, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.ChainID.Uint64()).To(Equal(uint64(4)))
+		Expect(config.ByzantiumBlock).To(Equal(params.RinkebyChainConfig.ByzantiumBlock))
+		Expect(config.IstanbulBlock).To(Equal(params.RinkebyChainConfig.IstanbulBlock))
+		Expect(config.Clique).ToNot(BeNil())
+		Expect(client.Called).To(Equal([]string{"admin_nodeInfo"}))
+	})
+
+	It("Falls back to the known chain config when the node does not expose its chain config", func() {
+		client.ResultsToReturn = map[string]interface{}{}
+		config, err := eth.ResolveChainConfig(client, 1, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config).To(Equal(params.MainnetChainConfig))
+	})
+
+	It("Does not ask the node for its chain config unless told to", func() {
+		config, err := eth.ResolveChainConfig(client, 1, false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config).To(Equal(params.MainnetChainConfig))
+		Expect(client.Called).To(BeEmpty())
+	})
+
+	It("Returns an error when neither the node nor the known chain configs have one", func() {
+		client.ResultsToReturn = map[string]interface{}{}
+		_, err := eth.ResolveChainConfig(client, 1337, true)
+		Expect(err).To(HaveOccurred())
+	})
+})
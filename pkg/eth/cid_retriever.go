@@ -0,0 +1,198 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+)
+
+// The kinds of node that can be returned by BlockNumberForCID
+const (
+	HeaderNodeKind  = "header"
+	UncleNodeKind   = "uncle"
+	TxNodeKind      = "transaction"
+	ReceiptNodeKind = "receipt"
+	StateNodeKind   = "state"
+	StorageNodeKind = "storage"
+)
+
+// Cursors for HeadersPage
+const (
+	// HeadersPageStart is the cursor of the first page of headers
+	HeadersPageStart int64 = -1
+	// HeadersPageEnd is the cursor returned with the last page of headers, paging from it returns an empty page
+	HeadersPageEnd int64 = math.MaxInt64
+)
+
+// CIDRetriever looks up the indexed metadata for cids
+type CIDRetriever struct {
+	db *postgres.DB
+}
+
+// NewCIDRetriever returns a pointer to a new CIDRetriever
+func NewCIDRetriever(db *postgres.DB) *CIDRetriever {
+	return &CIDRetriever{
+		db: db,
+	}
+}
+
+// BlockNumberForCID returns the number of the block the provided cid was indexed at and the kind of node it is
+// It returns ErrCIDNotIndexed if none of the cid tables reference the cid
+// Every branch of the union is served by the cid index on its table
+func (cr *CIDRetriever) BlockNumberForCID(c string) (int64, string, error) {
+	if _, err := cid.Decode(c); err != nil {
+		return 0, "", err
+	}
+	pgStr := fmt.Sprintf(`SELECT block_number, kind FROM (
+				SELECT block_number, '%[2]s' AS kind FROM %[1]s.header_cids
+				WHERE cid = $1
+				UNION ALL
+				SELECT header_cids.block_number, '%[3]s' FROM %[1]s.uncle_cids
+				INNER JOIN %[1]s.header_cids ON (uncle_cids.header_id = header_cids.id)
+				WHERE uncle_cids.cid = $1
+				UNION ALL
+				SELECT header_cids.block_number, '%[4]s' FROM %[1]s.transaction_cids
+				INNER JOIN %[1]s.header_cids ON (transaction_cids.header_id = header_cids.id)
+				WHERE transaction_cids.cid = $1
+				UNION ALL
+				SELECT header_cids.block_number, '%[5]s' FROM %[1]s.receipt_cids
+				INNER JOIN %[1]s.transaction_cids ON (receipt_cids.tx_id = transaction_cids.id)
+				INNER JOIN %[1]s.header_cids ON (transaction_cids.header_id = header_cids.id)
+				WHERE receipt_cids.cid = $1
+				UNION ALL
+				SELECT header_cids.block_number, '%[6]s' FROM %[1]s.state_cids
+				INNER JOIN %[1]s.header_cids ON (state_cids.header_id = header_cids.id)
+				WHERE state_cids.cid = $1
+				UNION ALL
+				SELECT header_cids.block_number, '%[7]s' FROM %[1]s.storage_cids
+				INNER JOIN %[1]s.state_cids ON (storage_cids.state_id = state_cids.id)
+				INNER JOIN %[1]s.header_cids ON (state_cids.header_id = header_cids.id)
+				WHERE storage_cids.cid = $1
+			) AS matches
+			ORDER BY block_number ASC LIMIT 1`, cr.db.Schema,
+		HeaderNodeKind, UncleNodeKind, TxNodeKind, ReceiptNodeKind, StateNodeKind, StorageNodeKind)
+	var res struct {
+		BlockNumber int64  `db:"block_number"`
+		Kind        string `db:"kind"`
+	}
+	if err := cr.db.Get(&res, pgStr, c); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, "", fmt.Errorf("%w: %s", ErrCIDNotIndexed, c)
+		}
+		return 0, "", err
+	}
+	return res.BlockNumber, res.Kind, nil
+}
+
+// CIDsForBlock returns every cid indexed for the block at the provided height, keyed by the kind of node, which are the
+// same kinds BlockNumberForCID returns; each kind has an entry, and the cids of each are in the order they were indexed
+// a height with more than one indexed header (e.g. during a reorg) has the cids of each of its headers
+// It returns ErrHeaderNotIndexed if there is no header indexed at the height
+// Every branch of the union is served by the block number index on header_cids and the parent id indexes on the other tables
+func (cr *CIDRetriever) CIDsForBlock(blockNumber int64) (map[string][]string, error) {
+	pgStr := fmt.Sprintf(`SELECT kind, cid FROM (
+				SELECT '%[2]s' AS kind, cid, id FROM %[1]s.header_cids
+				WHERE block_number = $1
+				UNION ALL
+				SELECT '%[3]s', uncle_cids.cid, uncle_cids.id FROM %[1]s.uncle_cids
+				INNER JOIN %[1]s.header_cids ON (uncle_cids.header_id = header_cids.id)
+				WHERE header_cids.block_number = $1
+				UNION ALL
+				SELECT '%[4]s', transaction_cids.cid, transaction_cids.id FROM %[1]s.transaction_cids
+				INNER JOIN %[1]s.header_cids ON (transaction_cids.header_id = header_cids.id)
+				WHERE header_cids.block_number = $1
+				UNION ALL
+				SELECT '%[5]s', receipt_cids.cid, receipt_cids.id FROM %[1]s.receipt_cids
+				INNER JOIN %[1]s.transaction_cids ON (receipt_cids.tx_id = transaction_cids.id)
+				INNER JOIN %[1]s.header_cids ON (transaction_cids.header_id = header_cids.id)
+				WHERE header_cids.block_number = $1
+				UNION ALL
+				SELECT '%[6]s', state_cids.cid, state_cids.id FROM %[1]s.state_cids
+				INNER JOIN %[1]s.header_cids ON (state_cids.header_id = header_cids.id)
+				WHERE header_cids.block_number = $1
+				UNION ALL
+				SELECT '%[7]s', storage_cids.cid, storage_cids.id FROM %[1]s.storage_cids
+				INNER JOIN %[1]s.state_cids ON (storage_cids.state_id = state_cids.id)
+				INNER JOIN %[1]s.header_cids ON (state_cids.header_id = header_cids.id)
+				WHERE header_cids.block_number = $1
+			) AS block_cids
+			ORDER BY id ASC`, cr.db.Schema,
+		HeaderNodeKind, UncleNodeKind, TxNodeKind, ReceiptNodeKind, StateNodeKind, StorageNodeKind)
+	rows := make([]struct {
+		Kind string `db:"kind"`
+		CID  string `db:"cid"`
+	}, 0)
+	if err := cr.db.Select(&rows, pgStr, blockNumber); err != nil {
+		return nil, err
+	}
+	cids := map[string][]string{
+		HeaderNodeKind:  {},
+		UncleNodeKind:   {},
+		TxNodeKind:      {},
+		ReceiptNodeKind: {},
+		StateNodeKind:   {},
+		StorageNodeKind: {},
+	}
+	for _, row := range rows {
+		cids[row.Kind] = append(cids[row.Kind], row.CID)
+	}
+	if len(cids[HeaderNodeKind]) == 0 {
+		return nil, fmt.Errorf("%w: block %d", ErrHeaderNotIndexed, blockNumber)
+	}
+	return cids, nil
+}
+
+// HeadersPage returns the headers of the next limit block heights above afterBlock, ordered by block number, and the cursor
+// of the next page, which is HeadersPageEnd if this is the last page
+// a page holds every header indexed at each of its heights, so that a height with more than one header (e.g. during a reorg)
+// is never split across two pages
+// both queries are served by the (block_number, block_hash) unique index
+func (cr *CIDRetriever) HeadersPage(afterBlock int64, limit int) ([]HeaderModel, int64, error) {
+	if limit <= 0 {
+		return nil, 0, fmt.Errorf("ethereum headers page limit needs to be greater than zero")
+	}
+	// fetch one height more than the limit to find out whether there is another page
+	heightsPgStr := fmt.Sprintf(`SELECT DISTINCT block_number FROM %s.header_cids
+				WHERE block_number > $1
+				ORDER BY block_number ASC LIMIT $2`, cr.db.Schema)
+	heights := make([]int64, 0, limit+1)
+	if err := cr.db.Select(&heights, heightsPgStr, afterBlock, limit+1); err != nil {
+		return nil, 0, err
+	}
+	headers := make([]HeaderModel, 0)
+	if len(heights) == 0 {
+		return headers, HeadersPageEnd, nil
+	}
+	next := HeadersPageEnd
+	if len(heights) > limit {
+		heights = heights[:limit]
+		next = heights[limit-1]
+	}
+	pgStr := fmt.Sprintf(`SELECT * FROM %s.header_cids
+				WHERE block_number BETWEEN $1 AND $2
+				ORDER BY block_number ASC, id ASC`, cr.db.Schema)
+	if err := cr.db.Select(&headers, pgStr, heights[0], heights[len(heights)-1]); err != nil {
+		return nil, 0, err
+	}
+	return headers, next, nil
+}
@@ -0,0 +1,143 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("CIDRetriever", func() {
+	var (
+		db        *postgres.DB
+		err       error
+		retriever *eth.CIDRetriever
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		transformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+		_, err = transformer.Transform(1, mocks.MockStateDiffPayload)
+		Expect(err).ToNot(HaveOccurred())
+		retriever = eth.NewCIDRetriever(db)
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	Describe("BlockNumberForCID", func() {
+		It("Returns the block number and kind of node for indexed cids", func() {
+			expected := map[string]string{
+				mocks.HeaderCID.String():  eth.HeaderNodeKind,
+				mocks.Trx1CID.String():    eth.TxNodeKind,
+				mocks.Rct1CID.String():    eth.ReceiptNodeKind,
+				mocks.State1CID.String():  eth.StateNodeKind,
+				mocks.StorageCID.String(): eth.StorageNodeKind,
+			}
+			for c, kind := range expected {
+				blockNumber, nodeKind, err := retriever.BlockNumberForCID(c)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(blockNumber).To(Equal(mocks.BlockNumber.Int64()))
+				Expect(nodeKind).To(Equal(kind))
+			}
+		})
+
+		It("Returns ErrCIDNotIndexed for cids that aren't indexed", func() {
+			_, _, err := retriever.BlockNumberForCID(shared.TestCID([]byte("not indexed")).String())
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, eth.ErrCIDNotIndexed)).To(BeTrue())
+		})
+
+		It("Returns an error for an invalid cid", func() {
+			_, _, err := retriever.BlockNumberForCID("notacid")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("CIDsForBlock", func() {
+		It("Returns every cid indexed for the block, keyed by the kind of node", func() {
+			cids, err := retriever.CIDsForBlock(mocks.BlockNumber.Int64())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cids[eth.HeaderNodeKind]).To(Equal([]string{mocks.HeaderCID.String()}))
+			Expect(cids[eth.UncleNodeKind]).To(BeEmpty())
+			Expect(cids[eth.TxNodeKind]).To(Equal([]string{mocks.Trx1CID.String(), mocks.Trx2CID.String(), mocks.Trx3CID.String()}))
+			Expect(cids[eth.ReceiptNodeKind]).To(Equal([]string{mocks.Rct1CID.String(), mocks.Rct2CID.String(), mocks.Rct3CID.String()}))
+			Expect(cids[eth.StateNodeKind]).To(ConsistOf(mocks.State1CID.String(), mocks.State2CID.String()))
+			Expect(cids[eth.StorageNodeKind]).To(Equal([]string{mocks.StorageCID.String()}))
+		})
+
+		It("Returns ErrHeaderNotIndexed for a block that hasn't been indexed", func() {
+			_, err := retriever.CIDsForBlock(mocks.BlockNumber.Int64() + 1)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, eth.ErrHeaderNotIndexed)).To(BeTrue())
+		})
+	})
+
+	Describe("HeadersPage", func() {
+		BeforeEach(func() {
+			// block 1 has been indexed by the transformer, index 0, 2 and 3 alongside it
+			publisher := eth.NewIPLDPublisher(db)
+			for _, block := range []*types.Block{mockBlock0, mockBlock2, mockBlock3} {
+				payload := mocks.MockConvertedPayload
+				payload.Block = block
+				err = publisher.Publish(payload)
+				Expect(err).ToNot(HaveOccurred())
+			}
+		})
+
+		It("Pages through the headers in block number order", func() {
+			headers, next, err := retriever.HeadersPage(eth.HeadersPageStart, 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(headers)).To(Equal(2))
+			Expect(headers[0].BlockNumber).To(Equal("0"))
+			Expect(headers[1].BlockNumber).To(Equal("1"))
+			Expect(next).To(Equal(int64(1)))
+
+			headers, next, err = retriever.HeadersPage(next, 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(headers)).To(Equal(2))
+			Expect(headers[0].BlockNumber).To(Equal("2"))
+			Expect(headers[1].BlockNumber).To(Equal("3"))
+			Expect(next).To(Equal(eth.HeadersPageEnd))
+		})
+
+		It("Returns an empty page and the end cursor past the last header", func() {
+			headers, next, err := retriever.HeadersPage(3, 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(headers).To(BeEmpty())
+			Expect(next).To(Equal(eth.HeadersPageEnd))
+			headers, next, err = retriever.HeadersPage(eth.HeadersPageEnd, 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(headers).To(BeEmpty())
+			Expect(next).To(Equal(eth.HeadersPageEnd))
+		})
+
+		It("Rejects a limit below one", func() {
+			_, _, err := retriever.HeadersPage(eth.HeadersPageStart, 0)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
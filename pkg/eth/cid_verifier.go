@@ -0,0 +1,217 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/ipfs/ipld"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+// CIDMismatch describes an IPLD whose CID, or the multihash key derived from it, does not match its raw data
+// an indexed CID that no IPLD of its block's payload is derived to has no DerivedCID or keys
+type CIDMismatch struct {
+	BlockNumber  uint64
+	NodeType     string
+	CID          string
+	DerivedCID   string
+	MhKey        string
+	DerivedMhKey string
+}
+
+// VerifyingBlockstore satisfies the BlockPutter and DerivedCIDHandler interfaces, but instead of storing the blocks it is
+// given it derives each block's CID from its raw data and records the blocks whose CID or multihash key doesn't match
+// the expected CID is derived under the hash function configured for the block's codec, rather than from the block's
+// own CID, so that a CID generated with the wrong hash function is caught
+type VerifyingBlockstore struct {
+	lock        sync.Mutex
+	multihashes map[uint64]uint64
+	mismatches  []CIDMismatch
+	derived     map[string]bool
+}
+
+// NewVerifyingBlockstore returns a pointer to a new VerifyingBlockstore that expects the DefaultMultihashes
+func NewVerifyingBlockstore() *VerifyingBlockstore {
+	return &VerifyingBlockstore{
+		multihashes: DefaultMultihashes(),
+		mismatches:  make([]CIDMismatch, 0),
+		derived:     make(map[string]bool),
+	}
+}
+
+// SetMultihashes sets the hash function the CIDs of each IPLD node type are expected to be derived with, keyed by its
+// multicodec, node types that are left out keep their current hash function
+func (vb *VerifyingBlockstore) SetMultihashes(multihashes map[uint64]uint64) error {
+	if err := ValidateMultihashes(multihashes); err != nil {
+		return err
+	}
+	vb.lock.Lock()
+	defer vb.lock.Unlock()
+	for codec, mh := range multihashes {
+		vb.multihashes[codec] = mh
+	}
+	return nil
+}
+
+// Put derives the expected CID of the raw data and compares the provided CID and its multihash key to it
+// a mismatch is recorded rather than returned, so that every block of a payload is checked
+func (vb *VerifyingBlockstore) Put(c cid.Cid, raw []byte) error {
+	vb.lock.Lock()
+	defer vb.lock.Unlock()
+	// contract code and any other node type without a configured hash function is keyed under keccak256
+	mh, ok := vb.multihashes[c.Type()]
+	if !ok {
+		mh = multihash.KECCAK_256
+	}
+	expected, err := ipld.RawdataToCid(c.Type(), raw, mh)
+	if err != nil {
+		return err
+	}
+	vb.derived[expected.String()] = true
+	mhKey, expectedMhKey := shared.MultihashKeyFromCID(c), shared.MultihashKeyFromCID(expected)
+	if expected.Equals(c) && mhKey == expectedMhKey {
+		return nil
+	}
+	nodeType, ok := cid.CodecToStr[c.Type()]
+	if !ok {
+		nodeType = fmt.Sprintf("0x%x", c.Type())
+	}
+	vb.mismatches = append(vb.mismatches, CIDMismatch{
+		NodeType:     nodeType,
+		CID:          c.String(),
+		DerivedCID:   expected.String(),
+		MhKey:        mhKey,
+		DerivedMhKey: expectedMhKey,
+	})
+	return nil
+}
+
+// HandleDerivedCID satisfies the DerivedCIDHandler interface, the block is checked as it is by Put
+func (vb *VerifyingBlockstore) HandleDerivedCID(c cid.Cid, raw []byte) error {
+	return vb.Put(c, raw)
+}
+
+// Mismatches returns the mismatches recorded since it was last called
+func (vb *VerifyingBlockstore) Mismatches() []CIDMismatch {
+	vb.lock.Lock()
+	defer vb.lock.Unlock()
+	mismatches := vb.mismatches
+	vb.mismatches = make([]CIDMismatch, 0)
+	return mismatches
+}
+
+// DerivedCIDs returns the set of expected CIDs derived since it was last called
+func (vb *VerifyingBlockstore) DerivedCIDs() map[string]bool {
+	vb.lock.Lock()
+	defer vb.lock.Unlock()
+	derived := vb.derived
+	vb.derived = make(map[string]bool)
+	return derived
+}
+
+// CIDVerifier checks the CIDs generated for a range of blocks without writing anything to Postgres
+type CIDVerifier struct {
+	fetcher     Fetcher
+	transformer *StateDiffTransformer
+	blockstore  *VerifyingBlockstore
+	// If not nil, the CIDs indexed for each block in its database are also checked against those derived from the block's
+	// payload, an indexed CID that none of them match is reported as a mismatch
+	Retriever *CIDRetriever
+}
+
+// NewCIDVerifier returns a new CIDVerifier that fetches payloads with the provided Fetcher
+// it does not need a database connection, the payloads are transformed as a dry run
+func NewCIDVerifier(chainConfig *params.ChainConfig, fetcher Fetcher) *CIDVerifier {
+	blockstore := NewVerifyingBlockstore()
+	transformer := NewStateDiffTransformer(chainConfig, nil)
+	transformer.DryRun = true
+	transformer.DerivedCIDs = blockstore
+	return &CIDVerifier{
+		fetcher:     fetcher,
+		transformer: transformer,
+		blockstore:  blockstore,
+	}
+}
+
+// SetMultihashes sets the hash function each IPLD node type is keyed under, see StateDiffTransformer.SetMultihashes
+// the CIDs of the node types are expected to be derived with the same hash functions
+func (cv *CIDVerifier) SetMultihashes(multihashes map[uint64]uint64) error {
+	if err := cv.transformer.SetMultihashes(multihashes); err != nil {
+		return err
+	}
+	return cv.blockstore.SetMultihashes(multihashes)
+}
+
+// Verify generates the IPLDs of each block in the inclusive range and returns those whose CID does not match their data
+// the blocks are fetched and checked one at a time, so that each mismatch can be attributed to its block
+func (cv *CIDVerifier) Verify(start, stop uint64) ([]CIDMismatch, error) {
+	if stop < start {
+		return nil, fmt.Errorf("ethereum cid verification range ending block number needs to be greater than the starting block number")
+	}
+	mismatches := make([]CIDMismatch, 0)
+	for height := start; height <= stop; height++ {
+		payloads, err := cv.fetcher.FetchAt([]uint64{height})
+		if err != nil {
+			return nil, fmt.Errorf("ethereum cid verification error fetching payload at height %d: %v", height, err)
+		}
+		for _, payload := range payloads {
+			if _, err := cv.transformer.Transform(0, payload); err != nil {
+				return nil, fmt.Errorf("ethereum cid verification error transforming payload at height %d: %v", height, err)
+			}
+		}
+		blockMismatches := cv.blockstore.Mismatches()
+		derived := cv.blockstore.DerivedCIDs()
+		if cv.Retriever != nil {
+			indexedMismatches, err := cv.verifyIndexed(height, derived)
+			if err != nil {
+				return nil, err
+			}
+			blockMismatches = append(blockMismatches, indexedMismatches...)
+		}
+		for _, mismatch := range blockMismatches {
+			mismatch.BlockNumber = height
+			logrus.Warnf("ethereum cid verification mismatch at height %d for %s node: cid %s, derived %s",
+				height, mismatch.NodeType, mismatch.CID, mismatch.DerivedCID)
+			mismatches = append(mismatches, mismatch)
+		}
+	}
+	return mismatches, nil
+}
+
+// verifyIndexed returns a mismatch for each CID indexed at the height that is not among the CIDs derived for it
+func (cv *CIDVerifier) verifyIndexed(height uint64, derived map[string]bool) ([]CIDMismatch, error) {
+	indexed, err := cv.Retriever.CIDsForBlock(int64(height))
+	if err != nil {
+		return nil, fmt.Errorf("ethereum cid verification error retrieving the cids indexed at height %d: %w", height, err)
+	}
+	mismatches := make([]CIDMismatch, 0)
+	for _, kind := range []string{HeaderNodeKind, UncleNodeKind, TxNodeKind, ReceiptNodeKind, StateNodeKind, StorageNodeKind} {
+		for _, c := range indexed[kind] {
+			if !derived[c] {
+				mismatches = append(mismatches, CIDMismatch{NodeType: kind, CID: c})
+			}
+		}
+	}
+	return mismatches, nil
+}
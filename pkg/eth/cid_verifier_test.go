@@ -0,0 +1,124 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/statediff"
+	"github.com/multiformats/go-multihash"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/ipfs/ipld"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("CIDVerifier", func() {
+	Describe("VerifyingBlockstore", func() {
+		It("Only records blocks whose CID doesn't match their data", func() {
+			blockstore := eth.NewVerifyingBlockstore()
+			Expect(blockstore.Put(mocks.HeaderCID, mocks.MockHeaderRlp)).To(Succeed())
+			Expect(blockstore.Mismatches()).To(BeEmpty())
+			Expect(blockstore.Put(mocks.HeaderCID, []byte{1, 2, 3})).To(Succeed())
+			mismatches := blockstore.Mismatches()
+			Expect(len(mismatches)).To(Equal(1))
+			Expect(mismatches[0].NodeType).To(Equal("eth-block"))
+			Expect(mismatches[0].CID).To(Equal(mocks.HeaderCID.String()))
+			Expect(mismatches[0].DerivedCID).ToNot(Equal(mocks.HeaderCID.String()))
+			Expect(mismatches[0].MhKey).ToNot(Equal(mismatches[0].DerivedMhKey))
+			// the mismatches are drained
+			Expect(blockstore.Mismatches()).To(BeEmpty())
+		})
+
+		It("Records a CID derived with a different hash function than the one expected for its node type", func() {
+			blockstore := eth.NewVerifyingBlockstore()
+			corrupted, err := ipld.RawdataToCid(ipld.MEthHeader, mocks.MockHeaderRlp, multihash.SHA2_256)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockstore.Put(corrupted, mocks.MockHeaderRlp)).To(Succeed())
+			mismatches := blockstore.Mismatches()
+			Expect(len(mismatches)).To(Equal(1))
+			Expect(mismatches[0].CID).To(Equal(corrupted.String()))
+			Expect(mismatches[0].DerivedCID).To(Equal(mocks.HeaderCID.String()))
+			Expect(mismatches[0].DerivedMhKey).To(Equal(mocks.HeaderMhKey))
+			// once that hash function is expected for headers the CID is consistent
+			Expect(blockstore.SetMultihashes(map[uint64]uint64{ipld.MEthHeader: multihash.SHA2_256})).To(Succeed())
+			Expect(blockstore.Put(corrupted, mocks.MockHeaderRlp)).To(Succeed())
+			Expect(blockstore.Mismatches()).To(BeEmpty())
+		})
+	})
+
+	Describe("Verify", func() {
+		It("Finds no mismatches in the CIDs generated for a valid payload", func() {
+			fetcher := &mocks.PayloadFetcher{
+				PayloadsToReturn: map[uint64]statediff.Payload{
+					1: mocks.MockStateDiffPayload,
+				},
+			}
+			verifier := eth.NewCIDVerifier(params.MainnetChainConfig, fetcher)
+			mismatches, err := verifier.Verify(1, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mismatches).To(BeEmpty())
+			Expect(fetcher.CalledAtBlockHeights).To(Equal([][]uint64{{1}}))
+		})
+
+		It("Verifies CIDs under other hash functions", func() {
+			fetcher := &mocks.PayloadFetcher{
+				PayloadsToReturn: map[uint64]statediff.Payload{
+					1: mocks.MockStateDiffPayload,
+				},
+			}
+			verifier := eth.NewCIDVerifier(params.MainnetChainConfig, fetcher)
+			Expect(verifier.SetMultihashes(map[uint64]uint64{ipld.MEthHeader: multihash.SHA2_256})).To(Succeed())
+			mismatches, err := verifier.Verify(1, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mismatches).To(BeEmpty())
+		})
+
+		It("Reports an indexed CID that does not match the CIDs derived from the block's payload", func() {
+			db, err := shared.SetupDB()
+			Expect(err).ToNot(HaveOccurred())
+			defer eth.TearDownDB(db)
+			_, err = eth.NewStateDiffTransformer(params.MainnetChainConfig, db).Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			fetcher := &mocks.PayloadFetcher{
+				PayloadsToReturn: map[uint64]statediff.Payload{
+					1: mocks.MockStateDiffPayload,
+				},
+			}
+			verifier := eth.NewCIDVerifier(params.MainnetChainConfig, fetcher)
+			verifier.Retriever = eth.NewCIDRetriever(db)
+			mismatches, err := verifier.Verify(1, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mismatches).To(BeEmpty())
+
+			corrupted := shared.TestCID([]byte("corrupted")).String()
+			_, err = db.Exec(`UPDATE eth.header_cids SET cid = $1 WHERE block_number = $2`, corrupted, 1)
+			Expect(err).ToNot(HaveOccurred())
+			mismatches, err = verifier.Verify(1, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mismatches).To(Equal([]eth.CIDMismatch{{BlockNumber: 1, NodeType: eth.HeaderNodeKind, CID: corrupted}}))
+		})
+
+		It("Rejects an inverted range", func() {
+			verifier := eth.NewCIDVerifier(params.MainnetChainConfig, &mocks.PayloadFetcher{})
+			_, err := verifier.Verify(2, 1)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
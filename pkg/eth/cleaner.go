@@ -52,9 +52,9 @@ func (c *DBCleaner) ResetValidation(rngs [][2]uint64) error {
 	}
 	for _, rng := range rngs {
 		logrus.Infof("eth db cleaner resetting validation level to 0 for block range %d to %d", rng[0], rng[1])
-		pgStr := `UPDATE eth.header_cids
+		pgStr := fmt.Sprintf(`UPDATE %s.header_cids
 				SET times_validated = 0
-				WHERE block_number BETWEEN $1 AND $2`
+				WHERE block_number BETWEEN $1 AND $2`, c.db.Schema)
 		if _, err := tx.Exec(pgStr, rng[0], rng[1]); err != nil {
 			shared.Rollback(tx)
 			return err
@@ -79,6 +79,8 @@ func (c *DBCleaner) Clean(rngs [][2]uint64, t shared.DataType) error {
 	if err := tx.Commit(); err != nil {
 		return err
 	}
+	// the removed IPLDs may still be remembered as published, which would skip their reinsertion
+	shared.ForgetPublished()
 	logrus.Infof("eth db cleaner vacuum analyzing cleaned tables to free up space from deleted rows")
 	return c.vacuumAnalyze(t)
 }
@@ -185,37 +187,37 @@ func (c *DBCleaner) vacuumFull() error {
 }
 
 func (c *DBCleaner) vacuumHeaders() error {
-	_, err := c.db.Exec(`VACUUM ANALYZE eth.header_cids`)
+	_, err := c.db.Exec(fmt.Sprintf(`VACUUM ANALYZE %s.header_cids`, c.db.Schema))
 	return err
 }
 
 func (c *DBCleaner) vacuumUncles() error {
-	_, err := c.db.Exec(`VACUUM ANALYZE eth.uncle_cids`)
+	_, err := c.db.Exec(fmt.Sprintf(`VACUUM ANALYZE %s.uncle_cids`, c.db.Schema))
 	return err
 }
 
 func (c *DBCleaner) vacuumTxs() error {
-	_, err := c.db.Exec(`VACUUM ANALYZE eth.transaction_cids`)
+	_, err := c.db.Exec(fmt.Sprintf(`VACUUM ANALYZE %s.transaction_cids`, c.db.Schema))
 	return err
 }
 
 func (c *DBCleaner) vacuumRcts() error {
-	_, err := c.db.Exec(`VACUUM ANALYZE eth.receipt_cids`)
+	_, err := c.db.Exec(fmt.Sprintf(`VACUUM ANALYZE %s.receipt_cids`, c.db.Schema))
 	return err
 }
 
 func (c *DBCleaner) vacuumState() error {
-	_, err := c.db.Exec(`VACUUM ANALYZE eth.state_cids`)
+	_, err := c.db.Exec(fmt.Sprintf(`VACUUM ANALYZE %s.state_cids`, c.db.Schema))
 	return err
 }
 
 func (c *DBCleaner) vacuumAccounts() error {
-	_, err := c.db.Exec(`VACUUM ANALYZE eth.state_accounts`)
+	_, err := c.db.Exec(fmt.Sprintf(`VACUUM ANALYZE %s.state_accounts`, c.db.Schema))
 	return err
 }
 
 func (c *DBCleaner) vacuumStorage() error {
-	_, err := c.db.Exec(`VACUUM ANALYZE eth.storage_cids`)
+	_, err := c.db.Exec(fmt.Sprintf(`VACUUM ANALYZE %s.storage_cids`, c.db.Schema))
 	return err
 }
 
@@ -247,116 +249,116 @@ func (c *DBCleaner) cleanFull(tx *sqlx.Tx, rng [2]uint64) error {
 }
 
 func (c *DBCleaner) cleanStorageIPLDs(tx *sqlx.Tx, rng [2]uint64) error {
-	pgStr := `DELETE FROM public.blocks A
-			USING eth.storage_cids B, eth.state_cids C, eth.header_cids D
+	pgStr := fmt.Sprintf(`DELETE FROM public.blocks A
+			USING %[1]s.storage_cids B, %[1]s.state_cids C, %[1]s.header_cids D
 			WHERE A.key = B.mh_key
 			AND B.state_id = C.id
 			AND C.header_id = D.id
-			AND D.block_number BETWEEN $1 AND $2`
+			AND D.block_number BETWEEN $1 AND $2`, c.db.Schema)
 	_, err := tx.Exec(pgStr, rng[0], rng[1])
 	return err
 }
 
 func (c *DBCleaner) cleanStorageMetaData(tx *sqlx.Tx, rng [2]uint64) error {
-	pgStr := `DELETE FROM eth.storage_cids A
-			USING eth.state_cids B, eth.header_cids C
+	pgStr := fmt.Sprintf(`DELETE FROM %[1]s.storage_cids A
+			USING %[1]s.state_cids B, %[1]s.header_cids C
 			WHERE A.state_id = B.id
 			AND B.header_id = C.id
-			AND C.block_number BETWEEN $1 AND $2`
+			AND C.block_number BETWEEN $1 AND $2`, c.db.Schema)
 	_, err := tx.Exec(pgStr, rng[0], rng[1])
 	return err
 }
 
 func (c *DBCleaner) cleanStateIPLDs(tx *sqlx.Tx, rng [2]uint64) error {
-	pgStr := `DELETE FROM public.blocks A
-			USING eth.state_cids B, eth.header_cids C
+	pgStr := fmt.Sprintf(`DELETE FROM public.blocks A
+			USING %[1]s.state_cids B, %[1]s.header_cids C
 			WHERE A.key = B.mh_key
 			AND B.header_id = C.id
-			AND C.block_number BETWEEN $1 AND $2`
+			AND C.block_number BETWEEN $1 AND $2`, c.db.Schema)
 	_, err := tx.Exec(pgStr, rng[0], rng[1])
 	return err
 }
 
 func (c *DBCleaner) cleanStateMetaData(tx *sqlx.Tx, rng [2]uint64) error {
-	pgStr := `DELETE FROM eth.state_cids A
-			USING eth.header_cids B
+	pgStr := fmt.Sprintf(`DELETE FROM %[1]s.state_cids A
+			USING %[1]s.header_cids B
 			WHERE A.header_id = B.id
-			AND B.block_number BETWEEN $1 AND $2`
+			AND B.block_number BETWEEN $1 AND $2`, c.db.Schema)
 	_, err := tx.Exec(pgStr, rng[0], rng[1])
 	return err
 }
 
 func (c *DBCleaner) cleanReceiptIPLDs(tx *sqlx.Tx, rng [2]uint64) error {
-	pgStr := `DELETE FROM public.blocks A
-			USING eth.receipt_cids B, eth.transaction_cids C, eth.header_cids D
+	pgStr := fmt.Sprintf(`DELETE FROM public.blocks A
+			USING %[1]s.receipt_cids B, %[1]s.transaction_cids C, %[1]s.header_cids D
 			WHERE A.key = B.mh_key
 			AND B.tx_id = C.id
 			AND C.header_id = D.id
-			AND D.block_number BETWEEN $1 AND $2`
+			AND D.block_number BETWEEN $1 AND $2`, c.db.Schema)
 	_, err := tx.Exec(pgStr, rng[0], rng[1])
 	return err
 }
 
 func (c *DBCleaner) cleanReceiptMetaData(tx *sqlx.Tx, rng [2]uint64) error {
-	pgStr := `DELETE FROM eth.receipt_cids A
-			USING eth.transaction_cids B, eth.header_cids C
+	pgStr := fmt.Sprintf(`DELETE FROM %[1]s.receipt_cids A
+			USING %[1]s.transaction_cids B, %[1]s.header_cids C
 			WHERE A.tx_id = B.id
 			AND B.header_id = C.id
-			AND C.block_number BETWEEN $1 AND $2`
+			AND C.block_number BETWEEN $1 AND $2`, c.db.Schema)
 	_, err := tx.Exec(pgStr, rng[0], rng[1])
 	return err
 }
 
 func (c *DBCleaner) cleanTransactionIPLDs(tx *sqlx.Tx, rng [2]uint64) error {
-	pgStr := `DELETE FROM public.blocks A
-			USING eth.transaction_cids B, eth.header_cids C
+	pgStr := fmt.Sprintf(`DELETE FROM public.blocks A
+			USING %[1]s.transaction_cids B, %[1]s.header_cids C
 			WHERE A.key = B.mh_key
 			AND B.header_id = C.id
-			AND C.block_number BETWEEN $1 AND $2`
+			AND C.block_number BETWEEN $1 AND $2`, c.db.Schema)
 	_, err := tx.Exec(pgStr, rng[0], rng[1])
 	return err
 }
 
 func (c *DBCleaner) cleanTransactionMetaData(tx *sqlx.Tx, rng [2]uint64) error {
-	pgStr := `DELETE FROM eth.transaction_cids A
-			USING eth.header_cids B
+	pgStr := fmt.Sprintf(`DELETE FROM %[1]s.transaction_cids A
+			USING %[1]s.header_cids B
 			WHERE A.header_id = B.id
-			AND B.block_number BETWEEN $1 AND $2`
+			AND B.block_number BETWEEN $1 AND $2`, c.db.Schema)
 	_, err := tx.Exec(pgStr, rng[0], rng[1])
 	return err
 }
 
 func (c *DBCleaner) cleanUncleIPLDs(tx *sqlx.Tx, rng [2]uint64) error {
-	pgStr := `DELETE FROM public.blocks A
-			USING eth.uncle_cids B, eth.header_cids C
+	pgStr := fmt.Sprintf(`DELETE FROM public.blocks A
+			USING %[1]s.uncle_cids B, %[1]s.header_cids C
 			WHERE A.key = B.mh_key
 			AND B.header_id = C.id
-			AND C.block_number BETWEEN $1 AND $2`
+			AND C.block_number BETWEEN $1 AND $2`, c.db.Schema)
 	_, err := tx.Exec(pgStr, rng[0], rng[1])
 	return err
 }
 
 func (c *DBCleaner) cleanUncleMetaData(tx *sqlx.Tx, rng [2]uint64) error {
-	pgStr := `DELETE FROM eth.uncle_cids A
-			USING eth.header_cids B
+	pgStr := fmt.Sprintf(`DELETE FROM %[1]s.uncle_cids A
+			USING %[1]s.header_cids B
 			WHERE A.header_id = B.id
-			AND B.block_number BETWEEN $1 AND $2`
+			AND B.block_number BETWEEN $1 AND $2`, c.db.Schema)
 	_, err := tx.Exec(pgStr, rng[0], rng[1])
 	return err
 }
 
 func (c *DBCleaner) cleanHeaderIPLDs(tx *sqlx.Tx, rng [2]uint64) error {
-	pgStr := `DELETE FROM public.blocks A
-			USING eth.header_cids B
+	pgStr := fmt.Sprintf(`DELETE FROM public.blocks A
+			USING %s.header_cids B
 			WHERE A.key = B.mh_key
-			AND B.block_number BETWEEN $1 AND $2`
+			AND B.block_number BETWEEN $1 AND $2`, c.db.Schema)
 	_, err := tx.Exec(pgStr, rng[0], rng[1])
 	return err
 }
 
 func (c *DBCleaner) cleanHeaderMetaData(tx *sqlx.Tx, rng [2]uint64) error {
-	pgStr := `DELETE FROM eth.header_cids
-			WHERE block_number BETWEEN $1 AND $2`
+	pgStr := fmt.Sprintf(`DELETE FROM %s.header_cids
+			WHERE block_number BETWEEN $1 AND $2`, c.db.Schema)
 	_, err := tx.Exec(pgStr, rng[0], rng[1])
 	return err
 }
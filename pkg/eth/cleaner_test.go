@@ -81,11 +81,13 @@ var (
 	uncleReward     = "1000000000000000000"
 	uncleModels1    = []eth.UncleModel{
 		{
-			CID:        uncleCID.String(),
-			MhKey:      uncleMhKey,
-			Reward:     uncleReward,
-			BlockHash:  uncleHash.String(),
-			ParentHash: uncleParentHash.String(),
+			CID:         uncleCID.String(),
+			MhKey:       uncleMhKey,
+			Reward:      uncleReward,
+			BlockHash:   uncleHash.String(),
+			ParentHash:  uncleParentHash.String(),
+			BlockNumber: blocKNumber1.String(),
+			Coinbase:    common.HexToAddress("0x0101").String(),
 		},
 	}
 
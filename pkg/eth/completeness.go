@@ -0,0 +1,132 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+)
+
+// IncompleteBlock describes an indexed header whose indexed txs or receipts do not account for all of those in its block
+type IncompleteBlock struct {
+	BlockNumber uint64
+	BlockHash   string
+	TxCount     int
+	RctCount    int
+	Reason      string
+}
+
+// CompletenessVerifier checks that the txs and receipts indexed for each header are all of those in its block
+// the gap finder only checks that a header has been indexed at each height, so it misses blocks that were partially indexed
+type CompletenessVerifier struct {
+	db *postgres.DB
+}
+
+// NewCompletenessVerifier returns a new CompletenessVerifier
+func NewCompletenessVerifier(db *postgres.DB) *CompletenessVerifier {
+	return &CompletenessVerifier{
+		db: db,
+	}
+}
+
+// completenessHeader is the part of a header row the CompletenessVerifier checks against
+type completenessHeader struct {
+	ID          int64  `db:"id"`
+	BlockNumber uint64 `db:"block_number"`
+	BlockHash   string `db:"block_hash"`
+	TxRoot      string `db:"tx_root"`
+}
+
+// Verify returns the incomplete blocks among the headers indexed in the provided range
+// the txs indexed for a header are complete if the trie root derived from their IPLDs is the header's tx root, and its
+// receipts are complete if there is one for each of those txs
+// headers recorded in eth.gaps, such as those indexed in headers only mode, are already known to be incomplete and are skipped
+func (v *CompletenessVerifier) Verify(start, stop uint64) ([]IncompleteBlock, error) {
+	if stop < start {
+		return nil, fmt.Errorf("ethereum completeness verification range ending block number needs to be greater than the starting block number")
+	}
+	headers := make([]completenessHeader, 0)
+	pgStr := fmt.Sprintf(`SELECT id, block_number, block_hash, tx_root FROM %[1]s.header_cids
+			WHERE block_number BETWEEN $1 AND $2
+			AND NOT EXISTS (SELECT 1 FROM %[1]s.gaps WHERE gaps.header_id = header_cids.id)
+			ORDER BY block_number, id`, v.db.Schema)
+	if err := v.db.Select(&headers, pgStr, start, stop); err != nil {
+		return nil, err
+	}
+	incomplete := make([]IncompleteBlock, 0)
+	for _, header := range headers {
+		block, err := v.verify(header)
+		if err != nil {
+			return nil, err
+		}
+		if block != nil {
+			incomplete = append(incomplete, *block)
+		}
+	}
+	return incomplete, nil
+}
+
+// verify checks the txs and receipts indexed for the header, it returns nil if they are complete
+func (v *CompletenessVerifier) verify(header completenessHeader) (*IncompleteBlock, error) {
+	// the IPLD of a tx whose row references a missing block is returned as nil, rather than dropped by the join
+	pgStr := fmt.Sprintf(`SELECT blocks.data FROM %s.transaction_cids
+			LEFT JOIN public.blocks ON (transaction_cids.mh_key = blocks.key)
+			WHERE transaction_cids.header_id = $1
+			ORDER BY transaction_cids.index`, v.db.Schema)
+	rawTxs := make([][]byte, 0)
+	if err := v.db.Select(&rawTxs, pgStr, header.ID); err != nil {
+		return nil, err
+	}
+	var rctCount int
+	pgStr = fmt.Sprintf(`SELECT COUNT(*) FROM %[1]s.receipt_cids
+			INNER JOIN %[1]s.transaction_cids ON (receipt_cids.tx_id = transaction_cids.id)
+			WHERE transaction_cids.header_id = $1`, v.db.Schema)
+	if err := v.db.Get(&rctCount, pgStr, header.ID); err != nil {
+		return nil, err
+	}
+	block := &IncompleteBlock{
+		BlockNumber: header.BlockNumber,
+		BlockHash:   header.BlockHash,
+		TxCount:     len(rawTxs),
+		RctCount:    rctCount,
+	}
+	txs := make(types.Transactions, len(rawTxs))
+	for i, raw := range rawTxs {
+		if raw == nil {
+			block.Reason = fmt.Sprintf("the IPLD of tx %d is missing", i)
+			return block, nil
+		}
+		txs[i] = new(types.Transaction)
+		if err := rlp.DecodeBytes(raw, txs[i]); err != nil {
+			block.Reason = fmt.Sprintf("the IPLD of tx %d can't be decoded: %v", i, err)
+			return block, nil
+		}
+	}
+	if derived := types.DeriveSha(txs); derived.String() != header.TxRoot {
+		block.Reason = fmt.Sprintf("the indexed txs derive tx root %s rather than the header's %s", derived.String(), header.TxRoot)
+		return block, nil
+	}
+	if rctCount != len(txs) {
+		block.Reason = fmt.Sprintf("%d receipts are indexed for %d txs", rctCount, len(txs))
+		return block, nil
+	}
+	return nil, nil
+}
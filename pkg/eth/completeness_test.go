@@ -0,0 +1,94 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("CompletenessVerifier", func() {
+	var (
+		db          *postgres.DB
+		err         error
+		verifier    *eth.CompletenessVerifier
+		blockNumber uint64
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		transformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+		_, err = transformer.Transform(1, mocks.MockStateDiffPayload)
+		Expect(err).ToNot(HaveOccurred())
+		verifier = eth.NewCompletenessVerifier(db)
+		blockNumber = mocks.BlockNumber.Uint64()
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	It("Finds no incomplete blocks when every tx and receipt is indexed", func() {
+		incomplete, err := verifier.Verify(blockNumber, blockNumber)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(incomplete).To(BeEmpty())
+	})
+
+	It("Reports a block missing one of its txs", func() {
+		_, err = db.Exec(`DELETE FROM eth.transaction_cids WHERE tx_hash = $1`, mocks.MockTransactions[1].Hash().String())
+		Expect(err).ToNot(HaveOccurred())
+		incomplete, err := verifier.Verify(blockNumber, blockNumber)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(incomplete)).To(Equal(1))
+		Expect(incomplete[0].BlockNumber).To(Equal(blockNumber))
+		Expect(incomplete[0].BlockHash).To(Equal(mocks.MockBlock.Hash().String()))
+		Expect(incomplete[0].TxCount).To(Equal(2))
+		Expect(incomplete[0].RctCount).To(Equal(2))
+		Expect(incomplete[0].Reason).To(ContainSubstring("tx root"))
+	})
+
+	It("Reports a block missing one of its receipts", func() {
+		_, err = db.Exec(`DELETE FROM eth.receipt_cids WHERE cid = $1`, mocks.Rct2CID.String())
+		Expect(err).ToNot(HaveOccurred())
+		incomplete, err := verifier.Verify(blockNumber, blockNumber)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(incomplete)).To(Equal(1))
+		Expect(incomplete[0].TxCount).To(Equal(3))
+		Expect(incomplete[0].RctCount).To(Equal(2))
+		Expect(incomplete[0].Reason).To(Equal("2 receipts are indexed for 3 txs"))
+	})
+
+	It("Skips headers recorded as gaps", func() {
+		_, err = db.Exec(`DELETE FROM eth.receipt_cids WHERE cid = $1`, mocks.Rct2CID.String())
+		Expect(err).ToNot(HaveOccurred())
+		_, err = db.Exec(`INSERT INTO eth.gaps (header_id, block_number, phase) SELECT id, block_number, 'headers' FROM eth.header_cids`)
+		Expect(err).ToNot(HaveOccurred())
+		incomplete, err := verifier.Verify(blockNumber, blockNumber)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(incomplete).To(BeEmpty())
+	})
+
+	It("Rejects a range that ends before it starts", func() {
+		_, err := verifier.Verify(2, 1)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,154 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/statediff"
+)
+
+// DecodedTransformer is a Transformer that can also process a payload which has already been decoded
+// the block, receipts and state diff passed to TransformDecoded are shared with the other transformers in a
+// CompositeTransformer and must not be modified
+type DecodedTransformer interface {
+	Transformer
+	TransformDecoded(workerID int, block *types.Block, receipts types.Receipts, stateDiff *statediff.StateObject, td *big.Int) (uint64, error)
+}
+
+// CompositeTransformer satisfies the Transformer interface by running an ordered chain of transformers over each payload
+// this allows custom transformers to run off the same payloads as the StateDiffTransformer without fetching them twice
+type CompositeTransformer struct {
+	chainConfig  *params.ChainConfig
+	transformers []Transformer
+	// decoders for the supported state object layouts, keyed by their number of top-level fields
+	stateObjectDecoders map[int]StateObjectDecoder
+	// The maximum size in bytes of each of a payload's rlp fields, defaults to DefaultMaxPayloadBytes
+	MaxPayloadBytes int
+}
+
+// NewCompositeTransformer creates a pointer to a new CompositeTransformer which runs the provided transformers in order
+func NewCompositeTransformer(chainConfig *params.ChainConfig, transformers ...Transformer) *CompositeTransformer {
+	return &CompositeTransformer{
+		chainConfig:         chainConfig,
+		transformers:        transformers,
+		stateObjectDecoders: defaultStateObjectDecoders(),
+	}
+}
+
+// Transform passes the payload to each transformer in turn, stopping at the first error
+// the payload is decoded at most once and the result is shared by every transformer that implements DecodedTransformer,
+// the others are passed the raw payload
+// each transformer commits its own work, so a failure part way down the chain does not undo the transformers before it
+func (ct *CompositeTransformer) Transform(workerID int, payload statediff.Payload) (uint64, error) {
+	var decoded *decodedPayload
+	var height uint64
+	for i, transformer := range ct.transformers {
+		var err error
+		if decodedTransformer, ok := transformer.(DecodedTransformer); ok {
+			if decoded == nil {
+				if decoded, err = decodePayload(ct.chainConfig, ct.stateObjectDecoders, ct.MaxPayloadBytes, payload); err != nil {
+					return 0, err
+				}
+			}
+			height, err = decodedTransformer.TransformDecoded(workerID, decoded.block, decoded.receipts, decoded.stateDiff, payload.TotalDifficulty)
+		} else {
+			height, err = transformer.Transform(workerID, payload)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("transformer %d of %d failed: %w", i+1, len(ct.transformers), err)
+		}
+	}
+	return height, nil
+}
+
+// decodedPayload holds the decoded contents of a statediff payload
+type decodedPayload struct {
+	block     *types.Block
+	receipts  types.Receipts
+	stateDiff *statediff.StateObject
+}
+
+// DefaultMaxPayloadBytes is the default limit on the size of each of a payload's rlp fields
+// it is far above the size of any mainnet block, receipt set or state diff
+const DefaultMaxPayloadBytes = 1 << 30
+
+// checkPayloadSize returns ErrPayloadTooLarge if any of the payload's rlp fields is larger than maxBytes
+// a maxBytes of 0 or less applies DefaultMaxPayloadBytes
+func checkPayloadSize(payload statediff.Payload, maxBytes int) error {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxPayloadBytes
+	}
+	fields := []struct {
+		name string
+		rlp  []byte
+	}{
+		{"block", payload.BlockRlp},
+		{"receipts", payload.ReceiptsRlp},
+		{"state object", payload.StateObjectRlp},
+	}
+	for _, field := range fields {
+		if len(field.rlp) > maxBytes {
+			return fmt.Errorf("%w: %s rlp is %d bytes, the limit is %d", ErrPayloadTooLarge, field.name, len(field.rlp), maxBytes)
+		}
+	}
+	return nil
+}
+
+// checkReceiptCount returns ErrReceiptCountMismatch if there is not exactly one receipt for each of the transactions
+func checkReceiptCount(txs types.Transactions, receipts types.Receipts) error {
+	if len(receipts) != len(txs) {
+		return fmt.Errorf("%w: transactions (%d), receipts (%d)", ErrReceiptCountMismatch, len(txs), len(receipts))
+	}
+	return nil
+}
+
+// decodePayload decodes the block, receipts and state object of a payload and derives the receipts' missing fields
+// the payload is rejected before decoding if any of its rlp fields is larger than maxBytes
+func decodePayload(chainConfig *params.ChainConfig, decoders map[int]StateObjectDecoder, maxBytes int, payload statediff.Payload) (*decodedPayload, error) {
+	if err := checkPayloadSize(payload, maxBytes); err != nil {
+		return nil, err
+	}
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(payload.BlockRlp, block); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecodeBlock, err)
+	}
+	receipts := make(types.Receipts, 0)
+	if err := rlp.DecodeBytes(payload.ReceiptsRlp, &receipts); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecodeReceipts, err)
+	}
+	stateDiff, err := decodeStateObjectWith(decoders, payload.StateObjectRlp)
+	if err != nil {
+		return nil, err
+	}
+	// the counts are checked before the receipts' fields are derived, which rejects a mismatch with an untyped error
+	if err := checkReceiptCount(block.Transactions(), receipts); err != nil {
+		return nil, err
+	}
+	if err := receipts.DeriveFields(chainConfig, block.Hash(), block.NumberU64(), block.Transactions()); err != nil {
+		return nil, fmt.Errorf("%w: deriving receipt fields: %v", ErrDecodeReceipts, err)
+	}
+	return &decodedPayload{
+		block:     block,
+		receipts:  receipts,
+		stateDiff: stateDiff,
+	}, nil
+}
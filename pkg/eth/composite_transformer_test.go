@@ -0,0 +1,62 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+)
+
+var _ = Describe("CompositeTransformer", func() {
+	Describe("Transform", func() {
+		It("Runs each transformer over the payload, sharing the decoded payload", func() {
+			rawTransformer := &mocks.Transformer{ReturnHeight: mocks.BlockNumber.Uint64()}
+			firstDecoded := &mocks.DecodedTransformer{Transformer: mocks.Transformer{ReturnHeight: mocks.BlockNumber.Uint64()}}
+			secondDecoded := &mocks.DecodedTransformer{Transformer: mocks.Transformer{ReturnHeight: mocks.BlockNumber.Uint64()}}
+			composite := eth.NewCompositeTransformer(params.MainnetChainConfig, rawTransformer, firstDecoded, secondDecoded)
+			height, err := composite.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(height).To(Equal(mocks.BlockNumber.Uint64()))
+
+			Expect(rawTransformer.PassedWorkerID).To(Equal(1))
+			Expect(rawTransformer.PassedStateDiff).To(Equal(mocks.MockStateDiffPayload))
+			Expect(firstDecoded.PassedWorkerID).To(Equal(1))
+			Expect(firstDecoded.PassedBlock.Hash()).To(Equal(mocks.MockBlock.Hash()))
+			Expect(len(firstDecoded.PassedReceipts)).To(Equal(len(mocks.MockReceipts)))
+			Expect(firstDecoded.PassedStateObject.BlockHash).To(Equal(mocks.MockStateDiff.BlockHash))
+			Expect(firstDecoded.PassedTD).To(Equal(mocks.MockStateDiffPayload.TotalDifficulty))
+			Expect(secondDecoded.PassedBlock).To(BeIdenticalTo(firstDecoded.PassedBlock))
+			Expect(secondDecoded.PassedStateObject).To(BeIdenticalTo(firstDecoded.PassedStateObject))
+		})
+
+		It("Stops at the first transformer that fails", func() {
+			failing := &mocks.Transformer{ReturnErr: errors.New("mock transformer error")}
+			next := &mocks.DecodedTransformer{}
+			composite := eth.NewCompositeTransformer(params.MainnetChainConfig, failing, next)
+			_, err := composite.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("mock transformer error"))
+			Expect(next.PassedBlock).To(BeNil())
+		})
+	})
+})
@@ -17,8 +17,6 @@
 package eth
 
 import (
-	"fmt"
-
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -72,23 +70,23 @@ func (pc *PayloadConverter) Convert(payload statediff.Payload) (*ConvertedPayloa
 	if err := rlp.DecodeBytes(payload.ReceiptsRlp, &receipts); err != nil {
 		return nil, err
 	}
+	// Ensure we have matching numbers of rcts and txs
+	if err := checkReceiptCount(transactions, receipts); err != nil {
+		return nil, err
+	}
 	// Derive any missing fields
 	if err := receipts.DeriveFields(pc.chainConfig, block.Hash(), block.NumberU64(), block.Transactions()); err != nil {
 		return nil, err
 	}
-	// Ensure we have matching numbers of rcts and txs
-	if len(receipts) != trxLen {
-		return nil, fmt.Errorf("expected number of transactions (%d) to be equal to the number of receipts (%d)", trxLen, len(receipts))
-	}
 	// Process receipts and txs
 	for i, receipt := range receipts {
 		// Extract topic and contract data from the receipt for indexing
-		topicSets := make([][]string, 4)
+		topicSets, err := logTopicSets(receipt.Logs)
+		if err != nil {
+			return nil, err
+		}
 		mappedContracts := make(map[string]bool) // use map to avoid duplicate addresses
 		for _, log := range receipt.Logs {
-			for i, topic := range log.Topics {
-				topicSets[i] = append(topicSets[i], topic.Hex())
-			}
 			mappedContracts[log.Address.String()] = true
 		}
 		// These are the contracts seen in the logs
@@ -99,9 +97,7 @@ func (pc *PayloadConverter) Convert(payload statediff.Payload) (*ConvertedPayloa
 		// This is the contract address if this receipt is for a contract creation tx
 		contract := shared.HandleZeroAddr(receipt.ContractAddress)
 		var contractHash string
-		deployment := false
 		if contract != "" {
-			deployment = true
 			contractHash = crypto.Keccak256Hash(common.HexToAddress(contract).Bytes()).String()
 		}
 		// receipt and rctMeta will have same indexes
@@ -114,6 +110,8 @@ func (pc *PayloadConverter) Convert(payload statediff.Payload) (*ConvertedPayloa
 			Contract:     contract,
 			ContractHash: contractHash,
 			LogContracts: logContracts,
+			LogCount:     int64(len(receipt.Logs)),
+			GasUsed:      receipt.GasUsed,
 		})
 		// process tx that corresponds with this rct
 		trx := transactions[i]
@@ -128,7 +126,8 @@ func (pc *PayloadConverter) Convert(payload statediff.Payload) (*ConvertedPayloa
 			TxHash:     trx.Hash().String(),
 			Index:      int64(i),
 			Data:       trx.Data(),
-			Deployment: deployment,
+			MethodID:   methodID(trx),
+			Deployment: trx.To() == nil, // a tx without a recipient is a contract deployment, whether or not it succeeded
 		})
 	}
 
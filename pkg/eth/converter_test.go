@@ -17,8 +17,15 @@
 package eth_test
 
 import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/statediff"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -48,5 +55,95 @@ var _ = Describe("Converter", func() {
 			Expect(payload.TxMetaData).To(Equal(mocks.MockTrxMeta))
 			Expect(payload.ReceiptMetaData).To(Equal(mocks.MockRctMeta))
 		})
+
+		It("Only indexes the topics of anonymous events at the positions they have", func() {
+			receipts := receiptsWithLogs(&types.Log{
+				Address: mocks.Address,
+				Topics:  []common.Hash{common.HexToHash("0x05")},
+			}, &types.Log{
+				Address: mocks.Address,
+			})
+			statediffPayload := mocks.MockStateDiffPayload
+			var err error
+			statediffPayload.ReceiptsRlp, err = rlp.EncodeToBytes(receipts)
+			Expect(err).ToNot(HaveOccurred())
+			converter := eth.NewPayloadConverter(params.MainnetChainConfig)
+			payload, err := converter.Convert(statediffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			rct := payload.ReceiptMetaData[0]
+			Expect(rct.Topic0s).To(Equal([]string{common.HexToHash("0x05").Hex()}))
+			Expect(rct.Topic1s).To(BeEmpty())
+			Expect(rct.Topic2s).To(BeEmpty())
+			Expect(rct.Topic3s).To(BeEmpty())
+			Expect(rct.LogCount).To(Equal(int64(2)))
+		})
+
+		It("Derives the method id of contract calls only", func() {
+			key, err := crypto.GenerateKey()
+			Expect(err).ToNot(HaveOccurred())
+			signer := types.MakeSigner(params.MainnetChainConfig, mocks.BlockNumber)
+			call, err := types.SignTx(types.NewTransaction(0, mocks.Address, big.NewInt(0), 50000, big.NewInt(1),
+				common.Hex2Bytes("a9059cbb0000")), signer, key)
+			Expect(err).ToNot(HaveOccurred())
+			transfer, err := types.SignTx(types.NewTransaction(1, mocks.Address, big.NewInt(1), 21000, big.NewInt(1),
+				common.Hex2Bytes("a905")), signer, key)
+			Expect(err).ToNot(HaveOccurred())
+			txs := types.Transactions{call, transfer}
+			receipts := types.Receipts{
+				types.NewReceipt(common.HexToHash("0x0").Bytes(), false, 50000),
+				types.NewReceipt(common.HexToHash("0x1").Bytes(), false, 71000),
+			}
+			blockRlp, err := rlp.EncodeToBytes(types.NewBlock(&mocks.MockHeader, txs, nil, receipts))
+			Expect(err).ToNot(HaveOccurred())
+			receiptsRlp, err := rlp.EncodeToBytes(receipts)
+			Expect(err).ToNot(HaveOccurred())
+			converter := eth.NewPayloadConverter(params.MainnetChainConfig)
+			payload, err := converter.Convert(statediff.Payload{
+				BlockRlp:        blockRlp,
+				ReceiptsRlp:     receiptsRlp,
+				StateObjectRlp:  mocks.MockStateDiffBytes,
+				TotalDifficulty: mocks.MockBlock.Difficulty(),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payload.TxMetaData[0].MethodID).ToNot(BeNil())
+			Expect(*payload.TxMetaData[0].MethodID).To(Equal("0xa9059cbb"))
+			Expect(payload.TxMetaData[1].MethodID).To(BeNil())
+			// the mock deployment's init code is not a call
+			payload, err = converter.Convert(mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payload.TxMetaData[2].MethodID).To(BeNil())
+		})
+
+		It("Rejects a receipt with a log that has more than four topics", func() {
+			receipts := receiptsWithLogs(&types.Log{
+				Address: mocks.Address,
+				Topics: []common.Hash{
+					common.HexToHash("0x01"),
+					common.HexToHash("0x02"),
+					common.HexToHash("0x03"),
+					common.HexToHash("0x04"),
+					common.HexToHash("0x05"),
+				},
+			})
+			statediffPayload := mocks.MockStateDiffPayload
+			var err error
+			statediffPayload.ReceiptsRlp, err = rlp.EncodeToBytes(receipts)
+			Expect(err).ToNot(HaveOccurred())
+			converter := eth.NewPayloadConverter(params.MainnetChainConfig)
+			_, err = converter.Convert(statediffPayload)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, eth.ErrTooManyTopics)).To(BeTrue())
+		})
 	})
 })
+
+// receiptsWithLogs returns a copy of the mock receipts with the first receipt's logs replaced
+func receiptsWithLogs(logs ...*types.Log) types.Receipts {
+	receipts := make(types.Receipts, len(mocks.MockReceipts))
+	for i, receipt := range mocks.MockReceipts {
+		rct := *receipt
+		receipts[i] = &rct
+	}
+	receipts[0].Logs = logs
+	return receipts
+}
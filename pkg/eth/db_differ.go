@@ -0,0 +1,126 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+)
+
+// BlockDivergence describes the first difference found between the data two databases have indexed at a height
+type BlockDivergence struct {
+	BlockNumber uint64
+	// The data that differs, e.g. "header hashes" or "storage cids"
+	Field string
+	// The row counts in each database if they differ, otherwise the first hash or cid that differs
+	Left  string
+	Right string
+}
+
+// diffQueries are the queries for the sorted hashes and cids compared at each height, by the data they select
+// the %[1]s verb is replaced by the schema name
+var diffQueries = []struct {
+	field string
+	query string
+}{
+	{"header hashes", `SELECT block_hash FROM %[1]s.header_cids WHERE block_number = $1 ORDER BY block_hash`},
+	{"header cids", `SELECT cid FROM %[1]s.header_cids WHERE block_number = $1 ORDER BY cid`},
+	{"uncle cids", `SELECT uncle_cids.cid FROM %[1]s.uncle_cids
+			INNER JOIN %[1]s.header_cids ON (uncle_cids.header_id = header_cids.id)
+			WHERE header_cids.block_number = $1 ORDER BY uncle_cids.cid`},
+	{"transaction cids", `SELECT transaction_cids.cid FROM %[1]s.transaction_cids
+			INNER JOIN %[1]s.header_cids ON (transaction_cids.header_id = header_cids.id)
+			WHERE header_cids.block_number = $1 ORDER BY transaction_cids.cid`},
+	{"receipt cids", `SELECT receipt_cids.cid FROM %[1]s.receipt_cids
+			INNER JOIN %[1]s.transaction_cids ON (receipt_cids.tx_id = transaction_cids.id)
+			INNER JOIN %[1]s.header_cids ON (transaction_cids.header_id = header_cids.id)
+			WHERE header_cids.block_number = $1 ORDER BY receipt_cids.cid`},
+	{"state cids", `SELECT state_cids.cid FROM %[1]s.state_cids
+			INNER JOIN %[1]s.header_cids ON (state_cids.header_id = header_cids.id)
+			WHERE header_cids.block_number = $1 ORDER BY state_cids.cid`},
+	{"storage cids", `SELECT storage_cids.cid FROM %[1]s.storage_cids
+			INNER JOIN %[1]s.state_cids ON (storage_cids.state_id = state_cids.id)
+			INNER JOIN %[1]s.header_cids ON (state_cids.header_id = header_cids.id)
+			WHERE header_cids.block_number = $1 ORDER BY storage_cids.cid`},
+}
+
+// DBDiffer compares the data indexed in two databases, e.g. to confirm that a new version of the indexer produces the
+// same data as the one it replaces; the databases can also be two schemas of the same database
+type DBDiffer struct {
+	left  *postgres.DB
+	right *postgres.DB
+}
+
+// NewDBDiffer returns a pointer to a new DBDiffer
+func NewDBDiffer(left, right *postgres.DB) *DBDiffer {
+	return &DBDiffer{
+		left:  left,
+		right: right,
+	}
+}
+
+// Diff compares the header hashes, the row counts and the cid sets of each height in the provided range, in order,
+// and returns the first divergence found, or nil if the databases have indexed identical data over the range
+func (d *DBDiffer) Diff(start, stop uint64) (*BlockDivergence, error) {
+	if stop < start {
+		return nil, fmt.Errorf("ethereum db diff range ending block number needs to be greater than the starting block number")
+	}
+	for height := start; height <= stop; height++ {
+		for _, q := range diffQueries {
+			divergence, err := d.diff(height, q.field, q.query)
+			if err != nil {
+				return nil, fmt.Errorf("ethereum db diff error comparing %s at height %d: %v", q.field, height, err)
+			}
+			if divergence != nil {
+				return divergence, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// diff compares the sorted values selected by the query at the provided height in both databases
+func (d *DBDiffer) diff(height uint64, field, query string) (*BlockDivergence, error) {
+	left := make([]string, 0)
+	if err := d.left.Select(&left, fmt.Sprintf(query, d.left.Schema), height); err != nil {
+		return nil, err
+	}
+	right := make([]string, 0)
+	if err := d.right.Select(&right, fmt.Sprintf(query, d.right.Schema), height); err != nil {
+		return nil, err
+	}
+	if len(left) != len(right) {
+		return &BlockDivergence{
+			BlockNumber: height,
+			Field:       field,
+			Left:        fmt.Sprintf("%d rows", len(left)),
+			Right:       fmt.Sprintf("%d rows", len(right)),
+		}, nil
+	}
+	for i := range left {
+		if left[i] != right[i] {
+			return &BlockDivergence{
+				BlockNumber: height,
+				Field:       field,
+				Left:        left[i],
+				Right:       right[i],
+			}, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,80 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/node"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("DBDiffer", func() {
+	var (
+		db, otherDB *postgres.DB
+		err         error
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		otherDB, err = postgres.NewDB(postgres.Config{
+			Hostname: "localhost",
+			Name:     "vulcanize_testing",
+			Port:     5432,
+			Schema:   "eth_diff_testing",
+		}, node.Info{})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = eth.NewStateDiffTransformer(params.MainnetChainConfig, db).Transform(1, mocks.MockStateDiffPayload)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = eth.NewStateDiffTransformer(params.MainnetChainConfig, otherDB).Transform(1, mocks.MockStateDiffPayload)
+		Expect(err).ToNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		otherDB.Exec(`DROP SCHEMA eth_diff_testing CASCADE`)
+		eth.TearDownDB(db)
+	})
+
+	Describe("Diff", func() {
+		It("Finds no divergence between databases that have indexed the same blocks", func() {
+			divergence, err := eth.NewDBDiffer(db, otherDB).Diff(0, 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(divergence).To(BeNil())
+		})
+
+		It("Reports the first divergence between the databases", func() {
+			_, err = otherDB.Exec(`DELETE FROM eth_diff_testing.storage_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			var storageCount int
+			err = db.Get(&storageCount, `SELECT COUNT(*) FROM eth.storage_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			divergence, err := eth.NewDBDiffer(db, otherDB).Diff(0, 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(divergence).ToNot(BeNil())
+			Expect(divergence.BlockNumber).To(Equal(uint64(1)))
+			Expect(divergence.Field).To(Equal("storage cids"))
+			Expect(divergence.Left).To(Equal(fmt.Sprintf("%d rows", storageCount)))
+			Expect(divergence.Right).To(Equal("0 rows"))
+		})
+	})
+})
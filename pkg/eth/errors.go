@@ -0,0 +1,70 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import "errors"
+
+// ErrDecodeBlock is returned by the transformer when a payload's block rlp cannot be decoded
+var ErrDecodeBlock = errors.New("error decoding payload block rlp")
+
+// ErrDecodeReceipts is returned by the transformer when a payload's receipts rlp cannot be decoded,
+// or the derived fields of the decoded receipts cannot be set from the block
+var ErrDecodeReceipts = errors.New("error decoding payload receipts rlp")
+
+// ErrDecodeStateObject is returned by the transformer when a payload's state object rlp cannot be decoded
+var ErrDecodeStateObject = errors.New("error decoding payload state object rlp")
+
+// ErrDecodeStateLeaf is returned by the transformer when a state leaf node or the account it holds cannot be decoded
+var ErrDecodeStateLeaf = errors.New("error decoding state leaf node")
+
+// ErrNodeCountMismatch is returned by the transformer when the number of transactions, receipts and their trie nodes
+// generated from a payload do not match
+// none of the decoding errors nor this one are resolved by retrying the same payload
+var ErrNodeCountMismatch = errors.New("transaction and receipt node counts do not match")
+
+// ErrReceiptCountMismatch is returned by the transformer when a payload does not have exactly one receipt for each of its
+// transactions, it is checked before the receipts' fields are derived and before any IPLDs are generated, as every receipt
+// is indexed against the transaction at its position
+var ErrReceiptCountMismatch = errors.New("receipt and transaction counts do not match")
+
+// ErrParentNotIndexed is returned by the transformer in strict parent mode when a block's parent header has not been indexed yet
+// the payload can be re-queued and transformed once its parent has been
+var ErrParentNotIndexed = errors.New("parent header is not indexed")
+
+// ErrCIDNotIndexed is returned when looking up a cid that is not referenced by any of the cid tables
+var ErrCIDNotIndexed = errors.New("cid is not indexed")
+
+// ErrBloomMismatch is returned by the transformer in bloom verification mode when the header's logs bloom
+// does not match the aggregate of the payload's receipt logs, indicating logs were dropped from the payload
+var ErrBloomMismatch = errors.New("header bloom does not match receipt logs")
+
+// ErrUnrecognizedStateObject is returned by the transformer when a payload's state object is in a layout it has no decoder for
+var ErrUnrecognizedStateObject = errors.New("unrecognized state object layout")
+
+// ErrTooManyTopics is returned when a receipt has a log with more topics than the EVM's LOG4 can emit
+var ErrTooManyTopics = errors.New("log has more than four topics")
+
+// ErrPayloadTooLarge is returned by the transformer when one of a payload's rlp fields exceeds the configured size limit
+// it is checked before decoding so that a corrupt or malicious payload cannot exhaust a worker's memory
+var ErrPayloadTooLarge = errors.New("payload exceeds the size limit")
+
+// ErrHeaderNotIndexed is returned when looking up a block by a hash or height that no indexed header has
+var ErrHeaderNotIndexed = errors.New("header is not indexed")
+
+// ErrProofNodeMissing is returned when a state trie node on the path from the state root to an account has not been
+// published, so that the account's proof cannot be built from the indexed data
+var ErrProofNodeMissing = errors.New("state trie node on the proof path is not indexed")
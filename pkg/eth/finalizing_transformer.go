@@ -0,0 +1,155 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/statediff"
+	"github.com/sirupsen/logrus"
+)
+
+// FinalizingTransformer satisfies the Transformer interface by holding back each payload until its block is a number of
+// confirmations behind the highest block it has seen, and only then passing it to the wrapped transformer
+// a held back block that is reorged out before it matures, i.e. another block at its height is an ancestor of the highest
+// block, is dropped without ever being indexed; this avoids the churn of indexing and then replacing reorged blocks at the
+// cost of the indexed data trailing the head by the confirmations
+// payloads still held back when the process stops are not indexed, and are left as gaps for the backfill to fill
+type FinalizingTransformer struct {
+	transformer   Transformer
+	confirmations uint64
+
+	lock sync.Mutex
+	// payloads held back until they mature, keyed by block hash
+	pending map[common.Hash]pendingPayload
+	// number and hash of the highest block seen, the most recently seen one if there are several at that height
+	head uint64
+	tip  common.Hash
+}
+
+// pendingPayload is a payload held back by a FinalizingTransformer along with its block's number and parent
+type pendingPayload struct {
+	number  uint64
+	parent  common.Hash
+	payload statediff.Payload
+}
+
+// NewFinalizingTransformer creates a pointer to a new FinalizingTransformer which passes each payload to the provided
+// transformer once its block is the provided number of confirmations behind the highest block seen
+func NewFinalizingTransformer(transformer Transformer, confirmations uint64) *FinalizingTransformer {
+	return &FinalizingTransformer{
+		transformer:   transformer,
+		confirmations: confirmations,
+		pending:       make(map[common.Hash]pendingPayload),
+	}
+}
+
+// Transform satisfies the Transformer interface
+// it holds back the payload and transforms those held back payloads that have matured, in order of block number, returning
+// the height of the highest of them, or 0 if none have matured
+// every matured payload is transformed even if one of them fails, the first failure is returned
+func (ft *FinalizingTransformer) Transform(workerID int, payload statediff.Payload) (uint64, error) {
+	header, err := decodePayloadHeader(payload)
+	if err != nil {
+		return 0, err
+	}
+	var height uint64
+	var firstErr error
+	for _, matured := range ft.hold(header, payload) {
+		if _, err := ft.transformer.Transform(workerID, matured.payload); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("transforming matured payload at %d: %w", matured.number, err)
+			}
+			continue
+		}
+		height = matured.number
+	}
+	return height, firstErr
+}
+
+// Pending returns the number of payloads currently held back
+func (ft *FinalizingTransformer) Pending() int {
+	ft.lock.Lock()
+	defer ft.lock.Unlock()
+	return len(ft.pending)
+}
+
+// hold adds the payload to those held back and removes and returns the ones that have matured and are still canonical
+func (ft *FinalizingTransformer) hold(header *types.Header, payload statediff.Payload) []pendingPayload {
+	ft.lock.Lock()
+	defer ft.lock.Unlock()
+	number, hash := header.Number.Uint64(), header.Hash()
+	ft.pending[hash] = pendingPayload{
+		number:  number,
+		parent:  header.ParentHash,
+		payload: payload,
+	}
+	if number >= ft.head {
+		ft.head, ft.tip = number, hash
+	}
+	if ft.head < ft.confirmations {
+		return nil
+	}
+	finalized := ft.head - ft.confirmations
+	// the held back blocks the tip descends from, by height
+	canonical := make(map[uint64]common.Hash)
+	for hash := ft.tip; ; {
+		held, ok := ft.pending[hash]
+		if !ok {
+			break
+		}
+		canonical[held.number] = hash
+		hash = held.parent
+	}
+	matured := make([]pendingPayload, 0)
+	for hash, held := range ft.pending {
+		if held.number > finalized {
+			continue
+		}
+		delete(ft.pending, hash)
+		// if the walk from the tip didn't reach this height, e.g. because a block was never received, it can't be orphaned
+		if canonicalHash, ok := canonical[held.number]; ok && canonicalHash != hash {
+			logrus.Infof("dropping payload at %d with hash %s, it was reorged out before it was confirmed", held.number, hash.String())
+			continue
+		}
+		matured = append(matured, held)
+	}
+	sort.Slice(matured, func(i, j int) bool { return matured[i].number < matured[j].number })
+	return matured
+}
+
+// decodePayloadHeader decodes only the header of a payload's block
+func decodePayloadHeader(payload statediff.Payload) (*types.Header, error) {
+	blockContent, _, err := rlp.SplitList(payload.BlockRlp)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecodeBlock, err)
+	}
+	_, _, rest, err := rlp.Split(blockContent)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecodeBlock, err)
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(blockContent[:len(blockContent)-len(rest)], header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecodeBlock, err)
+	}
+	return header, nil
+}
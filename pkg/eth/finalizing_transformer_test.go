@@ -0,0 +1,90 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/statediff"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+)
+
+var _ = Describe("FinalizingTransformer", func() {
+	var (
+		inner      *mocks.IterativeTransformer
+		finalizing *eth.FinalizingTransformer
+	)
+	BeforeEach(func() {
+		inner = &mocks.IterativeTransformer{ReturnHeights: make([]uint64, 10)}
+		finalizing = eth.NewFinalizingTransformer(inner, 2)
+	})
+
+	// newPayload returns a payload for a block at the provided height and its hash, extra distinguishes competing blocks
+	newPayload := func(number int64, parent common.Hash, extra byte) (statediff.Payload, common.Hash) {
+		block := types.NewBlock(&types.Header{Number: big.NewInt(number), ParentHash: parent, Extra: []byte{extra}}, nil, nil, nil)
+		blockRlp, err := rlp.EncodeToBytes(block)
+		Expect(err).ToNot(HaveOccurred())
+		return statediff.Payload{BlockRlp: blockRlp}, block.Hash()
+	}
+
+	It("Holds back each payload until it has the configured number of confirmations", func() {
+		payload1, hash1 := newPayload(1, common.Hash{}, 0)
+		payload2, hash2 := newPayload(2, hash1, 0)
+		payload3, _ := newPayload(3, hash2, 0)
+		height, err := finalizing.Transform(1, payload1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(height).To(Equal(uint64(0)))
+		_, err = finalizing.Transform(1, payload2)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inner.PassedStateDiffs).To(BeEmpty())
+
+		height, err = finalizing.Transform(1, payload3)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(height).To(Equal(uint64(1)))
+		Expect(inner.PassedStateDiffs).To(Equal([]statediff.Payload{payload1}))
+		Expect(finalizing.Pending()).To(Equal(2))
+	})
+
+	It("Drops a held back block that is reorged out before it is confirmed", func() {
+		payload1, hash1 := newPayload(1, common.Hash{}, 0)
+		payload2a, _ := newPayload(2, hash1, 'a')
+		payload2b, hash2b := newPayload(2, hash1, 'b')
+		payload3, hash3 := newPayload(3, hash2b, 0)
+		payload4, _ := newPayload(4, hash3, 0)
+		for _, payload := range []statediff.Payload{payload1, payload2a, payload2b, payload3, payload4} {
+			_, err := finalizing.Transform(1, payload)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(inner.PassedStateDiffs).To(Equal([]statediff.Payload{payload1, payload2b}))
+		Expect(finalizing.Pending()).To(Equal(2))
+	})
+
+	It("Returns ErrDecodeBlock for a payload whose block can't be decoded", func() {
+		_, err := finalizing.Transform(1, statediff.Payload{BlockRlp: []byte{1, 2, 3}})
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, eth.ErrDecodeBlock)).To(BeTrue())
+		Expect(finalizing.Pending()).To(Equal(0))
+	})
+})
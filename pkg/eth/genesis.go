@@ -0,0 +1,197 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/statediff"
+)
+
+var emptyStorageRoot = common.HexToHash("0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+// IndexGenesis builds the genesis block and allocation state for the provided genesis spec
+// and indexes them as block 0, with every allocated account indexed as a state leaf
+// This allows the genesis state to be indexed without relying on a statediff payload from the node
+func (sdt *StateDiffTransformer) IndexGenesis(genesis *core.Genesis) (uint64, error) {
+	memDB := rawdb.NewMemoryDatabase()
+	block := genesis.ToBlock(memDB)
+	if block.NumberU64() != 0 {
+		return 0, fmt.Errorf("expected genesis block number to be 0, got %d", block.NumberU64())
+	}
+	stateDB := state.NewDatabase(memDB)
+	nodes, err := genesisStateNodes(stateDB, block.Root())
+	if err != nil {
+		return 0, fmt.Errorf("error collecting genesis state nodes: %s", err.Error())
+	}
+	blockRlp, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		return 0, err
+	}
+	receiptsRlp, err := rlp.EncodeToBytes(types.Receipts{})
+	if err != nil {
+		return 0, err
+	}
+	stateObjectRlp, err := rlp.EncodeToBytes(statediff.StateObject{
+		BlockNumber: block.Number(),
+		BlockHash:   block.Hash(),
+		Nodes:       nodes,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return sdt.Transform(0, statediff.Payload{
+		BlockRlp:        blockRlp,
+		ReceiptsRlp:     receiptsRlp,
+		StateObjectRlp:  stateObjectRlp,
+		TotalDifficulty: block.Difficulty(),
+	})
+}
+
+// genesisStateNodes iterates the state trie at the provided root and collects every node, and the storage nodes
+// of every account with non-empty storage, in statediff form
+func genesisStateNodes(db state.Database, root common.Hash) ([]statediff.StateNode, error) {
+	stateTrie, err := db.OpenTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	stateNodes := make([]statediff.StateNode, 0)
+	it := stateTrie.NodeIterator(nil)
+	for it.Next(true) {
+		// skip value nodes, they are embedded in their parent leaf
+		if it.Leaf() || it.Hash() == (common.Hash{}) {
+			continue
+		}
+		nodeRLP, err := db.TrieDB().Node(it.Hash())
+		if err != nil {
+			return nil, err
+		}
+		nodeType, leafKey, err := resolveTrieNode(it.Path(), nodeRLP)
+		if err != nil {
+			return nil, err
+		}
+		stateNode := statediff.StateNode{
+			NodeType:  nodeType,
+			Path:      common.CopyBytes(it.Path()),
+			NodeValue: nodeRLP,
+			LeafKey:   leafKey,
+		}
+		if nodeType == statediff.Leaf {
+			account, err := decodeStateAccount(nodeRLP)
+			if err != nil {
+				return nil, err
+			}
+			if account.Root != emptyStorageRoot {
+				storageNodes, err := genesisStorageNodes(db, common.BytesToHash(leafKey), account.Root)
+				if err != nil {
+					return nil, err
+				}
+				stateNode.StorageNodes = storageNodes
+			}
+		}
+		stateNodes = append(stateNodes, stateNode)
+	}
+	return stateNodes, it.Error()
+}
+
+// genesisStorageNodes iterates the storage trie at the provided root and collects every node in statediff form
+func genesisStorageNodes(db state.Database, leafKey, root common.Hash) ([]statediff.StorageNode, error) {
+	storageTrie, err := db.OpenStorageTrie(leafKey, root)
+	if err != nil {
+		return nil, err
+	}
+	storageNodes := make([]statediff.StorageNode, 0)
+	it := storageTrie.NodeIterator(nil)
+	for it.Next(true) {
+		if it.Leaf() || it.Hash() == (common.Hash{}) {
+			continue
+		}
+		nodeRLP, err := db.TrieDB().Node(it.Hash())
+		if err != nil {
+			return nil, err
+		}
+		nodeType, storageLeafKey, err := resolveTrieNode(it.Path(), nodeRLP)
+		if err != nil {
+			return nil, err
+		}
+		storageNodes = append(storageNodes, statediff.StorageNode{
+			NodeType:  nodeType,
+			Path:      common.CopyBytes(it.Path()),
+			NodeValue: nodeRLP,
+			LeafKey:   storageLeafKey,
+		})
+	}
+	return storageNodes, it.Error()
+}
+
+// resolveTrieNode returns the type of the rlp encoded trie node found at the provided (nibble) path
+// for leaf nodes it also returns the full leaf key
+func resolveTrieNode(path, nodeRLP []byte) (statediff.NodeType, []byte, error) {
+	var elements []interface{}
+	if err := rlp.DecodeBytes(nodeRLP, &elements); err != nil {
+		return statediff.Unknown, nil, err
+	}
+	switch len(elements) {
+	case 17:
+		return statediff.Branch, nil, nil
+	case 2:
+		compactKey, ok := elements[0].([]byte)
+		if !ok || len(compactKey) == 0 {
+			return statediff.Unknown, nil, fmt.Errorf("unable to decode trie node partial path")
+		}
+		// the first nibble of the hex prefix encoding is 2 or 3 for leaf nodes, 0 or 1 for extension nodes
+		switch compactKey[0] >> 4 {
+		case 0, 1:
+			return statediff.Extension, nil, nil
+		case 2, 3:
+			nibbles := append(common.CopyBytes(path), compactToNibbles(compactKey)...)
+			return statediff.Leaf, nibblesToKey(nibbles), nil
+		default:
+			return statediff.Unknown, nil, fmt.Errorf("unknown hex prefix on trie node partial path")
+		}
+	default:
+		return statediff.Unknown, nil, fmt.Errorf("unexpected number of elements (%d) in trie node", len(elements))
+	}
+}
+
+// compactToNibbles converts a hex prefix encoded partial path into its nibbles, dropping the prefix
+func compactToNibbles(compact []byte) []byte {
+	nibbles := make([]byte, 0, len(compact)*2)
+	for _, b := range compact {
+		nibbles = append(nibbles, b/16, b%16)
+	}
+	// odd length paths have one prefix nibble, even length paths have two
+	if nibbles[0]&1 == 1 {
+		return nibbles[1:]
+	}
+	return nibbles[2:]
+}
+
+// nibblesToKey packs a full nibble path back into key bytes
+func nibblesToKey(nibbles []byte) []byte {
+	key := make([]byte, len(nibbles)/2)
+	for i := range key {
+		key[i] = nibbles[i*2]<<4 | nibbles[i*2+1]
+	}
+	return key
+}
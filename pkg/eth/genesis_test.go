@@ -0,0 +1,84 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("IndexGenesis", func() {
+	var (
+		db          *postgres.DB
+		err         error
+		transformer *eth.StateDiffTransformer
+		allocAddr   = common.HexToAddress("0x0D3ab14BBaD3D99F4203bd7a11aCB94882050E7e")
+		genesis     = &core.Genesis{
+			Config:     params.MainnetChainConfig,
+			Difficulty: big.NewInt(1),
+			Alloc: core.GenesisAlloc{
+				allocAddr: {Balance: big.NewInt(1000)},
+			},
+		}
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		transformer = eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	It("Indexes the genesis header and allocation accounts", func() {
+		blockNumber, err := transformer.IndexGenesis(genesis)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(blockNumber).To(Equal(uint64(0)))
+
+		var header eth.HeaderModel
+		err = db.Get(&header, `SELECT block_number, parent_hash, reward FROM eth.header_cids WHERE block_number = 0`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(header.ParentHash).To(Equal(common.Hash{}.String()))
+		Expect(header.Reward).To(Equal("0"))
+
+		stateNodes := make([]eth.StateNodeModel, 0)
+		pgStr := `SELECT state_cids.id, state_cids.state_leaf_key, state_cids.node_type
+				FROM eth.state_cids INNER JOIN eth.header_cids ON (state_cids.header_id = header_cids.id)
+				WHERE header_cids.block_number = 0`
+		err = db.Select(&stateNodes, pgStr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(stateNodes)).To(Equal(1))
+		Expect(stateNodes[0].NodeType).To(Equal(2))
+		Expect(stateNodes[0].StateKey).To(Equal(crypto.Keccak256Hash(allocAddr.Bytes()).String()))
+
+		var account eth.StateAccountModel
+		err = db.Get(&account, `SELECT balance, nonce FROM eth.state_accounts WHERE state_id = $1`, stateNodes[0].ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(account.Balance).To(Equal("1000"))
+		Expect(account.Nonce).To(Equal(uint64(0)))
+	})
+})
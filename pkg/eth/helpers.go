@@ -19,7 +19,10 @@ package eth
 import (
 	"fmt"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/statediff"
 )
 
@@ -38,6 +41,59 @@ func ResolveFromNodeType(nodeType statediff.NodeType) int {
 	}
 }
 
+// maxLogTopics is the most topics a log can have, those emitted by LOG4
+const maxLogTopics = 4
+
+// logTopicSets collects the topics of the logs into a set for each topic position, the topic0s to topic3s of a receipt
+// a log only adds to the sets of the positions it has topics at, so a log with fewer than four topics is left out of the
+// remaining sets; an anonymous event has no event signature topic, so its first indexed argument (if any) is its topic0
+// it returns ErrTooManyTopics if a log has more than four topics, which the EVM cannot emit
+func logTopicSets(logs []*types.Log) ([][]string, error) {
+	topicSets := make([][]string, maxLogTopics)
+	for _, log := range logs {
+		if len(log.Topics) > maxLogTopics {
+			return nil, fmt.Errorf("%w: log %d of tx %s has %d topics", ErrTooManyTopics, log.Index, log.TxHash.String(), len(log.Topics))
+		}
+		for i, topic := range log.Topics {
+			topicSets[i] = append(topicSets[i], topic.Hex())
+		}
+	}
+	return topicSets, nil
+}
+
+// methodIDLength is the length in bytes of a function selector, the leading bytes of a contract call's input data
+const methodIDLength = 4
+
+// methodID returns the hex function selector of a transaction's input data
+// it returns nil for a transaction with fewer input bytes than a selector, such as a plain transfer, and for a contract
+// deployment, whose input data is the contract's init code rather than a call
+func methodID(trx *types.Transaction) *string {
+	data := trx.Data()
+	if trx.To() == nil || len(data) < methodIDLength {
+		return nil
+	}
+	id := hexutil.Encode(data[:methodIDLength])
+	return &id
+}
+
+// rawTx returns the rlp encoding of a signed transaction
+// the encoding is decoded again and rejected if it doesn't reproduce the transaction's hash, so that the stored bytes
+// can be relied on to reconstruct the exact transaction
+func rawTx(trx *types.Transaction) ([]byte, error) {
+	raw, err := rlp.EncodeToBytes(trx)
+	if err != nil {
+		return nil, err
+	}
+	decoded := new(types.Transaction)
+	if err := rlp.DecodeBytes(raw, decoded); err != nil {
+		return nil, fmt.Errorf("error decoding rlp of transaction %s: %v", trx.Hash().String(), err)
+	}
+	if decoded.Hash() != trx.Hash() {
+		return nil, fmt.Errorf("rlp of transaction %s decodes to a transaction with hash %s", trx.Hash().String(), decoded.Hash().String())
+	}
+	return raw, nil
+}
+
 // ChainConfig returns the appropriate ethereum chain config for the provided chain id
 func ChainConfig(chainID uint64) (*params.ChainConfig, error) {
 	switch chainID {
@@ -17,8 +17,13 @@
 package eth
 
 import (
+	"database/sql"
+	"fmt"
+
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/statediff"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
@@ -37,12 +42,15 @@ type Indexer interface {
 // Indexer satisfies the Indexer interface for ethereum
 type CIDIndexer struct {
 	db *postgres.DB
+	// cache of the ids of the addresses in eth.addresses that have been committed
+	addressCache *addressIDCache
 }
 
 // NewCIDIndexer creates a new pointer to a Indexer which satisfies the CIDIndexer interface
 func NewCIDIndexer(db *postgres.DB) *CIDIndexer {
 	return &CIDIndexer{
-		db: db,
+		db:           db,
+		addressCache: newAddressIDCache(DefaultAddressCacheSize),
 	}
 }
 
@@ -53,6 +61,7 @@ func (in *CIDIndexer) Index(cids CIDPayload) error {
 	if err != nil {
 		return err
 	}
+	addresses := make(addressIDs)
 	defer func() {
 		if p := recover(); p != nil {
 			shared.Rollback(tx)
@@ -61,6 +70,9 @@ func (in *CIDIndexer) Index(cids CIDPayload) error {
 			shared.Rollback(tx)
 		} else {
 			err = tx.Commit()
+			if err == nil {
+				in.cacheAddressIDs(addresses)
+			}
 		}
 	}()
 
@@ -75,7 +87,7 @@ func (in *CIDIndexer) Index(cids CIDPayload) error {
 			return err
 		}
 	}
-	if err := in.indexTransactionAndReceiptCIDs(tx, cids, headerID); err != nil {
+	if err := in.indexTransactionAndReceiptCIDs(tx, addresses, cids, headerID); err != nil {
 		log.Error("eth indexer error when indexing transactions and receipts")
 		return err
 	}
@@ -88,35 +100,31 @@ func (in *CIDIndexer) Index(cids CIDPayload) error {
 
 func (in *CIDIndexer) indexHeaderCID(tx *sqlx.Tx, header HeaderModel) (int64, error) {
 	var headerID int64
-	err := tx.QueryRowx(`INSERT INTO eth.header_cids (block_number, block_hash, parent_hash, cid, td, node_id, reward, state_root, tx_root, receipt_root, uncle_root, bloom, timestamp, mh_key, times_validated)
-								VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
-								ON CONFLICT (block_number, block_hash) DO UPDATE SET (parent_hash, cid, td, node_id, reward, state_root, tx_root, receipt_root, uncle_root, bloom, timestamp, mh_key, times_validated) = ($3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, eth.header_cids.times_validated + 1)
-								RETURNING id`,
+	err := tx.QueryRowx(fmt.Sprintf(`INSERT INTO %[1]s.header_cids (block_number, block_hash, parent_hash, cid, td, node_id, reward, state_root, tx_root, receipt_root, uncle_root, bloom, timestamp, mh_key, times_validated, last_validated_at, block_time)
+								VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, now(), $16)
+								ON CONFLICT (block_number, block_hash) DO UPDATE SET (parent_hash, cid, td, node_id, reward, state_root, tx_root, receipt_root, uncle_root, bloom, timestamp, mh_key, times_validated, last_validated_at, block_time) = ($3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, %[1]s.header_cids.times_validated + 1, now(), $16)
+								RETURNING id`, in.db.Schema),
 		header.BlockNumber, header.BlockHash, header.ParentHash, header.CID, header.TotalDifficulty, in.db.NodeID, header.Reward, header.StateRoot, header.TxRoot,
-		header.RctRoot, header.UncleRoot, header.Bloom, header.Timestamp, header.MhKey, 1).Scan(&headerID)
+		header.RctRoot, header.UncleRoot, header.Bloom, header.Timestamp, header.MhKey, 1, header.BlockTime).Scan(&headerID)
 	return headerID, err
 }
 
 func (in *CIDIndexer) indexUncleCID(tx *sqlx.Tx, uncle UncleModel, headerID int64) error {
-	_, err := tx.Exec(`INSERT INTO eth.uncle_cids (block_hash, header_id, parent_hash, cid, reward, mh_key) VALUES ($1, $2, $3, $4, $5, $6)
-								ON CONFLICT (header_id, block_hash) DO UPDATE SET (parent_hash, cid, reward, mh_key) = ($3, $4, $5, $6)`,
-		uncle.BlockHash, headerID, uncle.ParentHash, uncle.CID, uncle.Reward, uncle.MhKey)
+	_, err := tx.Exec(fmt.Sprintf(`INSERT INTO %s.uncle_cids (block_hash, header_id, parent_hash, cid, reward, mh_key, block_number, coinbase, index) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+								ON CONFLICT (header_id, block_hash) DO UPDATE SET (parent_hash, cid, reward, mh_key, block_number, coinbase, index) = ($3, $4, $5, $6, $7, $8, $9)`, in.db.Schema),
+		uncle.BlockHash, headerID, uncle.ParentHash, uncle.CID, uncle.Reward, uncle.MhKey, uncle.BlockNumber, uncle.Coinbase, uncle.Index)
 	return err
 }
 
-func (in *CIDIndexer) indexTransactionAndReceiptCIDs(tx *sqlx.Tx, payload CIDPayload, headerID int64) error {
+func (in *CIDIndexer) indexTransactionAndReceiptCIDs(tx *sqlx.Tx, addresses addressIDs, payload CIDPayload, headerID int64) error {
 	for _, trxCidMeta := range payload.TransactionCIDs {
-		var txID int64
-		err := tx.QueryRowx(`INSERT INTO eth.transaction_cids (header_id, tx_hash, cid, dst, src, index, mh_key, tx_data, deployment) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-									ON CONFLICT (header_id, tx_hash) DO UPDATE SET (cid, dst, src, index, mh_key, tx_data, deployment) = ($3, $4, $5, $6, $7, $8, $9)
-									RETURNING id`,
-			headerID, trxCidMeta.TxHash, trxCidMeta.CID, trxCidMeta.Dst, trxCidMeta.Src, trxCidMeta.Index, trxCidMeta.MhKey, trxCidMeta.Data, trxCidMeta.Deployment).Scan(&txID)
+		txID, err := in.indexTransactionCID(tx, addresses, trxCidMeta, headerID)
 		if err != nil {
 			return err
 		}
 		receiptCidMeta, ok := payload.ReceiptCIDs[common.HexToHash(trxCidMeta.TxHash)]
 		if ok {
-			if err := in.indexReceiptCID(tx, receiptCidMeta, txID); err != nil {
+			if err := in.indexReceiptCID(tx, addresses, receiptCidMeta, txID); err != nil {
 				return err
 			}
 		}
@@ -124,22 +132,116 @@ func (in *CIDIndexer) indexTransactionAndReceiptCIDs(tx *sqlx.Tx, payload CIDPay
 	return nil
 }
 
-func (in *CIDIndexer) indexTransactionCID(tx *sqlx.Tx, transaction TxModel, headerID int64) (int64, error) {
+func (in *CIDIndexer) indexTransactionCID(tx *sqlx.Tx, addresses addressIDs, transaction TxModel, headerID int64) (int64, error) {
+	if err := in.resolveTxAddresses(tx, addresses, &transaction); err != nil {
+		return 0, err
+	}
 	var txID int64
-	err := tx.QueryRowx(`INSERT INTO eth.transaction_cids (header_id, tx_hash, cid, dst, src, index, mh_key, tx_data, deployment) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-									ON CONFLICT (header_id, tx_hash) DO UPDATE SET (cid, dst, src, index, mh_key, tx_data, deployment) = ($3, $4, $5, $6, $7, $8, $9)
-									RETURNING id`,
-		headerID, transaction.TxHash, transaction.CID, transaction.Dst, transaction.Src, transaction.Index, transaction.MhKey, transaction.Data, transaction.Deployment).Scan(&txID)
+	err := tx.QueryRowx(fmt.Sprintf(`INSERT INTO %s.transaction_cids (header_id, tx_hash, cid, dst_id, src_id, index, mh_key, tx_data, deployment, r, s, v, method_id, tx_rlp) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+									ON CONFLICT (header_id, tx_hash) DO UPDATE SET (cid, dst_id, src_id, index, mh_key, tx_data, deployment, r, s, v, method_id, tx_rlp) = ($3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+									RETURNING id`, in.db.Schema),
+		headerID, transaction.TxHash, transaction.CID, transaction.DstID, transaction.SrcID, transaction.Index, transaction.MhKey, transaction.Data, transaction.Deployment,
+		transaction.R, transaction.S, transaction.V, transaction.MethodID, transaction.RLP).Scan(&txID)
 	return txID, err
 }
 
-func (in *CIDIndexer) indexReceiptCID(tx *sqlx.Tx, rct ReceiptModel, txID int64) error {
-	_, err := tx.Exec(`INSERT INTO eth.receipt_cids (tx_id, cid, contract, contract_hash, topic0s, topic1s, topic2s, topic3s, log_contracts, mh_key) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-							  ON CONFLICT (tx_id) DO UPDATE SET (cid, contract, contract_hash, topic0s, topic1s, topic2s, topic3s, log_contracts, mh_key) = ($2, $3, $4, $5, $6, $7, $8, $9, $10)`,
-		txID, rct.CID, rct.Contract, rct.ContractHash, rct.Topic0s, rct.Topic1s, rct.Topic2s, rct.Topic3s, rct.LogContracts, rct.MhKey)
+func (in *CIDIndexer) indexReceiptCID(tx *sqlx.Tx, addresses addressIDs, rct ReceiptModel, txID int64) error {
+	if err := in.resolveReceiptAddresses(tx, addresses, &rct); err != nil {
+		return err
+	}
+	_, err := tx.Exec(fmt.Sprintf(`INSERT INTO %s.receipt_cids (tx_id, cid, contract_id, contract_hash, topic0s, topic1s, topic2s, topic3s, log_contract_ids, mh_key, log_count, gas_used) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+							  ON CONFLICT (tx_id) DO UPDATE SET (cid, contract_id, contract_hash, topic0s, topic1s, topic2s, topic3s, log_contract_ids, mh_key, log_count, gas_used) = ($2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`, in.db.Schema),
+		txID, rct.CID, rct.ContractID, rct.ContractHash, rct.Topic0s, rct.Topic1s, rct.Topic2s, rct.Topic3s, rct.LogContractIDs, rct.MhKey, rct.LogCount, rct.GasUsed)
 	return err
 }
 
+// copyTransactionAndReceiptCIDs bulk indexes the transactions and receipts of a block using COPY
+// rcts[i] must be the receipt for txs[i], or nil if that receipt should not be indexed, and each TxModel.Index must be its position in the block
+// COPY cannot upsert, so any rows already indexed for the header are replaced, this matches the
+// ON CONFLICT DO UPDATE behaviour of the row-by-row inserts
+// the address ids of the txs and receipts are set on them, and the ids of the addresses the tx inserts are collected in
+// addresses, to be cached once the tx has committed
+func (in *CIDIndexer) copyTransactionAndReceiptCIDs(tx *sqlx.Tx, addresses addressIDs, txs []TxModel, rcts []*ReceiptModel, headerID int64) error {
+	if len(txs) != len(rcts) {
+		return fmt.Errorf("eth indexer expected equal numbers of transactions and receipts, got %d and %d", len(txs), len(rcts))
+	}
+	if len(txs) == 0 {
+		return nil
+	}
+	// resolve every address id up front, pq rejects any other statement on the tx while a COPY is in progress
+	for i := range txs {
+		if err := in.resolveTxAddresses(tx, addresses, &txs[i]); err != nil {
+			return err
+		}
+	}
+	for _, rct := range rcts {
+		if rct == nil {
+			continue
+		}
+		if err := in.resolveReceiptAddresses(tx, addresses, rct); err != nil {
+			return err
+		}
+	}
+	// receipts are removed along with their transactions by the cascading FK
+	if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s.transaction_cids WHERE header_id = $1`, in.db.Schema), headerID); err != nil {
+		return err
+	}
+	// phase one: copy the transactions and collect their generated ids
+	txStmt, err := tx.Prepare(pq.CopyInSchema(in.db.Schema, "transaction_cids",
+		"header_id", "tx_hash", "cid", "dst_id", "src_id", "index", "mh_key", "tx_data", "deployment", "r", "s", "v", "method_id", "tx_rlp"))
+	if err != nil {
+		return err
+	}
+	for _, trx := range txs {
+		if _, err := txStmt.Exec(headerID, trx.TxHash, trx.CID, trx.DstID, trx.SrcID, trx.Index, trx.MhKey, trx.Data, trx.Deployment, trx.R, trx.S, trx.V, trx.MethodID, trx.RLP); err != nil {
+			txStmt.Close()
+			return err
+		}
+	}
+	if err := flushCopy(txStmt); err != nil {
+		return err
+	}
+	var ids []TxModel
+	pgStr := fmt.Sprintf(`SELECT id, index FROM %s.transaction_cids WHERE header_id = $1`, in.db.Schema)
+	if err := tx.Select(&ids, pgStr, headerID); err != nil {
+		return err
+	}
+	txIDs := make(map[int64]int64, len(ids))
+	for _, id := range ids {
+		txIDs[id.Index] = id.ID
+	}
+	// phase two: copy the receipts, referencing their transaction by its position in the block
+	rctStmt, err := tx.Prepare(pq.CopyInSchema(in.db.Schema, "receipt_cids",
+		"tx_id", "cid", "contract_id", "contract_hash", "topic0s", "topic1s", "topic2s", "topic3s", "log_contract_ids", "mh_key", "log_count", "gas_used"))
+	if err != nil {
+		return err
+	}
+	for i, rct := range rcts {
+		if rct == nil {
+			continue
+		}
+		txID, ok := txIDs[txs[i].Index]
+		if !ok {
+			rctStmt.Close()
+			return fmt.Errorf("eth indexer unable to find indexed transaction at index %d", txs[i].Index)
+		}
+		if _, err := rctStmt.Exec(txID, rct.CID, rct.ContractID, rct.ContractHash, rct.Topic0s, rct.Topic1s, rct.Topic2s, rct.Topic3s, rct.LogContractIDs, rct.MhKey, rct.LogCount, rct.GasUsed); err != nil {
+			rctStmt.Close()
+			return err
+		}
+	}
+	return flushCopy(rctStmt)
+}
+
+// flushCopy completes and closes a COPY statement
+func flushCopy(stmt *sql.Stmt) error {
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return err
+	}
+	return stmt.Close()
+}
+
 func (in *CIDIndexer) indexStateAndStorageCIDs(tx *sqlx.Tx, payload CIDPayload, headerID int64) error {
 	for _, stateCID := range payload.StateNodeCIDs {
 		var stateID int64
@@ -147,9 +249,9 @@ func (in *CIDIndexer) indexStateAndStorageCIDs(tx *sqlx.Tx, payload CIDPayload,
 		if stateCID.StateKey != nullHash.String() {
 			stateKey = stateCID.StateKey
 		}
-		err := tx.QueryRowx(`INSERT INTO eth.state_cids (header_id, state_leaf_key, cid, state_path, node_type, diff, mh_key) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		err := tx.QueryRowx(fmt.Sprintf(`INSERT INTO %s.state_cids (header_id, state_leaf_key, cid, state_path, node_type, diff, mh_key) VALUES ($1, $2, $3, $4, $5, $6, $7)
 									ON CONFLICT (header_id, state_path) DO UPDATE SET (state_leaf_key, cid, node_type, diff, mh_key) = ($2, $3, $5, $6, $7)
-									RETURNING id`,
+									RETURNING id`, in.db.Schema),
 			headerID, stateKey, stateCID.CID, stateCID.Path, stateCID.NodeType, true, stateCID.MhKey).Scan(&stateID)
 		if err != nil {
 			return err
@@ -178,27 +280,121 @@ func (in *CIDIndexer) indexStateCID(tx *sqlx.Tx, stateNode StateNodeModel, heade
 	if stateNode.StateKey != nullHash.String() {
 		stateKey = stateNode.StateKey
 	}
-	err := tx.QueryRowx(`INSERT INTO eth.state_cids (header_id, state_leaf_key, cid, state_path, node_type, diff, mh_key) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	err := tx.QueryRowx(fmt.Sprintf(`INSERT INTO %s.state_cids (header_id, state_leaf_key, cid, state_path, node_type, diff, mh_key) VALUES ($1, $2, $3, $4, $5, $6, $7)
 									ON CONFLICT (header_id, state_path) DO UPDATE SET (state_leaf_key, cid, node_type, diff, mh_key) = ($2, $3, $5, $6, $7)
-									RETURNING id`,
+									RETURNING id`, in.db.Schema),
 		headerID, stateKey, stateNode.CID, stateNode.Path, stateNode.NodeType, true, stateNode.MhKey).Scan(&stateID)
 	return stateID, err
 }
 
 func (in *CIDIndexer) indexStateAccount(tx *sqlx.Tx, stateAccount StateAccountModel, stateID int64) error {
-	_, err := tx.Exec(`INSERT INTO eth.state_accounts (state_id, balance, nonce, code_hash, storage_root) VALUES ($1, $2, $3, $4, $5)
-							  ON CONFLICT (state_id) DO UPDATE SET (balance, nonce, code_hash, storage_root) = ($2, $3, $4, $5)`,
+	_, err := tx.Exec(fmt.Sprintf(`INSERT INTO %s.state_accounts (state_id, balance, nonce, code_hash, storage_root) VALUES ($1, $2, $3, $4, $5)
+							  ON CONFLICT (state_id) DO UPDATE SET (balance, nonce, code_hash, storage_root) = ($2, $3, $4, $5)`, in.db.Schema),
 		stateID, stateAccount.Balance, stateAccount.Nonce, stateAccount.CodeHash, stateAccount.StorageRoot)
 	return err
 }
 
+func (in *CIDIndexer) indexIPLDSizes(tx *sqlx.Tx, sizes IPLDSizesModel, headerID int64) error {
+	_, err := tx.Exec(fmt.Sprintf(`INSERT INTO %s.ipld_sizes (header_id, header_bytes, uncle_bytes, tx_bytes, receipt_bytes, state_bytes, storage_bytes) VALUES ($1, $2, $3, $4, $5, $6, $7)
+							  ON CONFLICT (header_id) DO UPDATE SET (header_bytes, uncle_bytes, tx_bytes, receipt_bytes, state_bytes, storage_bytes) = ($2, $3, $4, $5, $6, $7)`, in.db.Schema),
+		headerID, sizes.HeaderBytes, sizes.UncleBytes, sizes.TxBytes, sizes.ReceiptBytes, sizes.StateBytes, sizes.StorageBytes)
+	return err
+}
+
+// indexGap records that only the provided phase has been indexed for the header, so the rest of its block is still missing
+func (in *CIDIndexer) indexGap(tx *sqlx.Tx, headerID int64, blockNumber uint64, phase string) error {
+	_, err := tx.Exec(fmt.Sprintf(`INSERT INTO %s.gaps (header_id, block_number, phase) VALUES ($1, $2, $3)
+							  ON CONFLICT (header_id) DO UPDATE SET (block_number, phase) = ($2, $3)`, in.db.Schema),
+		headerID, blockNumber, phase)
+	return err
+}
+
+// clearGap removes the gap recorded for the header, once the rest of its block has been indexed
+func (in *CIDIndexer) clearGap(tx *sqlx.Tx, headerID int64) error {
+	_, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s.gaps WHERE header_id = $1`, in.db.Schema), headerID)
+	return err
+}
+
+// indexPayload stores the raw statediff payload of a block, replacing the payload stored for any other block at its height
+func (in *CIDIndexer) indexPayload(tx *sqlx.Tx, blockNumber uint64, blockHash, td string, payload statediff.Payload) error {
+	_, err := tx.Exec(fmt.Sprintf(`INSERT INTO %s.payloads (block_number, block_hash, td, block_rlp, receipts_rlp, state_object_rlp) VALUES ($1, $2, $3, $4, $5, $6)
+							  ON CONFLICT (block_number) DO UPDATE SET (block_hash, td, block_rlp, receipts_rlp, state_object_rlp) = ($2, $3, $4, $5, $6)`, in.db.Schema),
+		blockNumber, blockHash, td, payload.BlockRlp, payload.ReceiptsRlp, payload.StateObjectRlp)
+	return err
+}
+
 func (in *CIDIndexer) indexStorageCID(tx *sqlx.Tx, storageCID StorageNodeModel, stateID int64) error {
 	var storageKey string
 	if storageCID.StorageKey != nullHash.String() {
 		storageKey = storageCID.StorageKey
 	}
-	_, err := tx.Exec(`INSERT INTO eth.storage_cids (state_id, storage_leaf_key, cid, storage_path, node_type, diff, mh_key) VALUES ($1, $2, $3, $4, $5, $6, $7) 
-							  ON CONFLICT (state_id, storage_path) DO UPDATE SET (storage_leaf_key, cid, node_type, diff, mh_key) = ($2, $3, $5, $6, $7)`,
+	_, err := tx.Exec(fmt.Sprintf(`INSERT INTO %s.storage_cids (state_id, storage_leaf_key, cid, storage_path, node_type, diff, mh_key) VALUES ($1, $2, $3, $4, $5, $6, $7) 
+							  ON CONFLICT (state_id, storage_path) DO UPDATE SET (storage_leaf_key, cid, node_type, diff, mh_key) = ($2, $3, $5, $6, $7)`, in.db.Schema),
 		stateID, storageKey, storageCID.CID, storageCID.Path, storageCID.NodeType, true, storageCID.MhKey)
 	return err
 }
+
+// addressID returns the id of the address in eth.addresses, inserting it within the block's tx if it has not been
+// indexed before; the ids the tx resolves are collected in addresses rather than cached, as an address the tx inserts
+// is gone again if it rolls back
+// concurrent txs inserting the same address wait on one another, a deadlock between them is retried as a serialization failure
+func (in *CIDIndexer) addressID(tx *sqlx.Tx, addresses addressIDs, address string) (int64, error) {
+	if id, ok := addresses[address]; ok {
+		return id, nil
+	}
+	if id, ok := in.addressCache.get(address); ok {
+		return id, nil
+	}
+	// the no-op update makes RETURNING return the id of an address that has already been inserted
+	pgStr := fmt.Sprintf(`INSERT INTO %[1]s.addresses (address) VALUES ($1)
+			ON CONFLICT (address) DO UPDATE SET address = %[1]s.addresses.address
+			RETURNING id`, in.db.Schema)
+	var id int64
+	if err := tx.Get(&id, pgStr, address); err != nil {
+		return 0, fmt.Errorf("eth indexer error indexing address %s: %v", address, err)
+	}
+	addresses[address] = id
+	return id, nil
+}
+
+// cacheAddressIDs caches the ids resolved by a tx, it must only be called once that tx has been committed
+func (in *CIDIndexer) cacheAddressIDs(addresses addressIDs) {
+	in.addressCache.add(addresses)
+}
+
+// optionalAddressID returns the id of the address, or nil if the address is empty
+func (in *CIDIndexer) optionalAddressID(tx *sqlx.Tx, addresses addressIDs, address string) (*int64, error) {
+	if address == "" {
+		return nil, nil
+	}
+	id, err := in.addressID(tx, addresses, address)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// resolveTxAddresses sets the ids of the transaction's sender and recipient
+func (in *CIDIndexer) resolveTxAddresses(tx *sqlx.Tx, addresses addressIDs, trx *TxModel) error {
+	var err error
+	if trx.SrcID, err = in.addressID(tx, addresses, trx.Src); err != nil {
+		return err
+	}
+	trx.DstID, err = in.optionalAddressID(tx, addresses, trx.Dst)
+	return err
+}
+
+// resolveReceiptAddresses sets the ids of the receipt's contract and of the contracts seen in its logs
+func (in *CIDIndexer) resolveReceiptAddresses(tx *sqlx.Tx, addresses addressIDs, rct *ReceiptModel) error {
+	var err error
+	if rct.ContractID, err = in.optionalAddressID(tx, addresses, rct.Contract); err != nil {
+		return err
+	}
+	rct.LogContractIDs = make(pq.Int64Array, len(rct.LogContracts))
+	for i, address := range rct.LogContracts {
+		if rct.LogContractIDs[i], err = in.addressID(tx, addresses, address); err != nil {
+			return err
+		}
+	}
+	return nil
+}
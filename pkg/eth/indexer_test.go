@@ -54,6 +54,68 @@ var _ = Describe("Indexer", func() {
 	})
 
 	Describe("Index", func() {
+		It("Indexes each address once and joins them back to hex in the views", func() {
+			err = repo.Index(mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			trxs := make([]eth.TxModel, 0)
+			pgStr := `SELECT transaction_cids_with_addresses.* FROM eth.transaction_cids_with_addresses
+				INNER JOIN eth.header_cids ON (transaction_cids_with_addresses.header_id = header_cids.id)
+				WHERE header_cids.block_number = $1
+				ORDER BY transaction_cids_with_addresses.index`
+			err = db.Select(&trxs, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(trxs)).To(Equal(3))
+			for i, trx := range trxs {
+				Expect(trx.Src).To(Equal(mocks.MockTrxMetaPostPublsh[i].Src))
+				Expect(trx.Dst).To(Equal(mocks.MockTrxMetaPostPublsh[i].Dst))
+				// each of the mock transactions has the same sender
+				Expect(trx.SrcID).To(Equal(trxs[0].SrcID))
+			}
+			Expect(trxs[0].DstID).ToNot(BeNil())
+			Expect(trxs[2].DstID).To(BeNil())
+
+			rcts := make([]eth.ReceiptModel, 0)
+			pgStr = `SELECT receipt_cids_with_addresses.contract, receipt_cids_with_addresses.contract_id,
+				receipt_cids_with_addresses.log_contracts, receipt_cids_with_addresses.log_contract_ids
+				FROM eth.receipt_cids_with_addresses
+				INNER JOIN eth.transaction_cids ON (receipt_cids_with_addresses.tx_id = transaction_cids.id)
+				INNER JOIN eth.header_cids ON (transaction_cids.header_id = header_cids.id)
+				WHERE header_cids.block_number = $1
+				ORDER BY transaction_cids.index`
+			err = db.Select(&rcts, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(rcts)).To(Equal(3))
+			for i, rct := range rcts {
+				Expect(rct.Contract).To(Equal(mocks.MockRctMetaPostPublish[i].Contract))
+				Expect(rct.LogContracts).To(ConsistOf(mocks.MockRctMetaPostPublish[i].LogContracts))
+				Expect(len(rct.LogContractIDs)).To(Equal(len(rct.LogContracts)))
+			}
+			Expect(rcts[0].ContractID).To(BeNil())
+			Expect(rcts[2].ContractID).ToNot(BeNil())
+			// the contract seen in the first receipt's logs is the recipient of the first transaction
+			Expect(rcts[0].LogContractIDs[0]).To(Equal(*trxs[0].DstID))
+		})
+
+		It("Rolls back the addresses of a block that fails to index and does not cache their ids", func() {
+			// the missing public.blocks entry fails the state node's FK once the addresses have been inserted
+			payload := mocks.MockCIDPayload
+			payload.StateNodeCIDs = append([]eth.StateNodeModel{}, payload.StateNodeCIDs...)
+			payload.StateNodeCIDs[0].MhKey = "/blocks/missing"
+			err = repo.Index(payload)
+			Expect(err).To(HaveOccurred())
+			var addressCount int
+			err = db.Get(&addressCount, `SELECT COUNT(*) FROM eth.addresses`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addressCount).To(BeZero())
+			// the ids of the rolled back addresses would fail the FKs of the transactions and receipts if they were cached
+			err = repo.Index(mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			var txCount int
+			err = db.Get(&txCount, `SELECT COUNT(*) FROM eth.transaction_cids_with_addresses WHERE src IS NOT NULL`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(txCount).To(Equal(len(mocks.MockCIDPayload.TransactionCIDs)))
+		})
+
 		It("Indexes CIDs and related metadata into vulcanizedb", func() {
 			err = repo.Index(mocks.MockCIDPayload)
 			Expect(err).ToNot(HaveOccurred())
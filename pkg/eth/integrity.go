@@ -0,0 +1,127 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+// orphanSampleSize is the number of orphaned row ids reported for each integrity check
+const orphanSampleSize = 10
+
+// integrityCheck describes a foreign key whose referenced row is checked for existence
+type integrityCheck struct {
+	name        string
+	table       string
+	column      string
+	parentTable string
+}
+
+// integrityChecks are the foreign keys checked for orphans
+// they are ordered parents first, so that deleting the orphans of one check cascades to their children before the next
+// check runs and the children aren't reported twice
+var integrityChecks = []integrityCheck{
+	{name: "transactions -> headers", table: "transaction_cids", column: "header_id", parentTable: "header_cids"},
+	{name: "receipts -> transactions", table: "receipt_cids", column: "tx_id", parentTable: "transaction_cids"},
+	{name: "state -> headers", table: "state_cids", column: "header_id", parentTable: "header_cids"},
+	{name: "storage -> state", table: "storage_cids", column: "state_id", parentTable: "state_cids"},
+}
+
+// Orphans describes the rows of a table whose foreign key does not reference an existing row
+type Orphans struct {
+	Check     string
+	Count     int64
+	SampleIDs []int64
+}
+
+// IntegrityChecker checks the eth tables for rows with dangling foreign key references
+// these can only be left behind if the foreign key constraints were bypassed, e.g. by a bulk load with triggers disabled
+type IntegrityChecker struct {
+	db *postgres.DB
+}
+
+// NewIntegrityChecker returns a new IntegrityChecker
+func NewIntegrityChecker(db *postgres.DB) *IntegrityChecker {
+	return &IntegrityChecker{
+		db: db,
+	}
+}
+
+// Verify returns the orphaned rows found by each of the integrity checks
+func (c *IntegrityChecker) Verify() ([]Orphans, error) {
+	orphans := make([]Orphans, 0, len(integrityChecks))
+	for _, check := range integrityChecks {
+		o, err := c.orphans(check)
+		if err != nil {
+			return nil, err
+		}
+		orphans = append(orphans, o)
+	}
+	return orphans, nil
+}
+
+// Fix deletes the orphaned rows found by each of the integrity checks in a single tx, along with their children
+// it returns the orphaned rows that were deleted by each check
+func (c *IntegrityChecker) Fix() ([]Orphans, error) {
+	tx, err := c.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	orphans := make([]Orphans, 0, len(integrityChecks))
+	for _, check := range integrityChecks {
+		o := Orphans{Check: check.name}
+		pgStr := fmt.Sprintf(`DELETE FROM %[1]s.%[2]s c
+				WHERE NOT EXISTS (SELECT 1 FROM %[1]s.%[4]s p WHERE p.id = c.%[3]s)
+				RETURNING c.id`, c.db.Schema, check.table, check.column, check.parentTable)
+		ids := make([]int64, 0)
+		if err := tx.Select(&ids, pgStr); err != nil {
+			shared.Rollback(tx)
+			return nil, err
+		}
+		o.Count = int64(len(ids))
+		if len(ids) > orphanSampleSize {
+			ids = ids[:orphanSampleSize]
+		}
+		o.SampleIDs = ids
+		if o.Count > 0 {
+			logrus.Infof("eth integrity checker deleting %d orphaned rows from %s", o.Count, check.table)
+		}
+		orphans = append(orphans, o)
+	}
+	return orphans, tx.Commit()
+}
+
+// orphans counts and samples the orphaned rows found by the provided check
+func (c *IntegrityChecker) orphans(check integrityCheck) (Orphans, error) {
+	o := Orphans{Check: check.name}
+	from := fmt.Sprintf(`FROM %[1]s.%[2]s c
+			LEFT JOIN %[1]s.%[4]s p ON (c.%[3]s = p.id)
+			WHERE p.id IS NULL`, c.db.Schema, check.table, check.column, check.parentTable)
+	if err := c.db.Get(&o.Count, `SELECT COUNT(*) `+from); err != nil {
+		return o, err
+	}
+	o.SampleIDs = make([]int64, 0)
+	if o.Count == 0 {
+		return o, nil
+	}
+	return o, c.db.Select(&o.SampleIDs, `SELECT c.id `+from+` ORDER BY c.id LIMIT $1`, orphanSampleSize)
+}
@@ -0,0 +1,102 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("IntegrityChecker", func() {
+	var (
+		db      *postgres.DB
+		err     error
+		checker *eth.IntegrityChecker
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		transformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+		_, err = transformer.Transform(1, mocks.MockStateDiffPayload)
+		Expect(err).ToNot(HaveOccurred())
+		checker = eth.NewIntegrityChecker(db)
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	It("Finds no orphans in a consistent database", func() {
+		orphans, err := checker.Verify()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(orphans)).To(Equal(4))
+		for _, o := range orphans {
+			Expect(o.Count).To(BeZero())
+			Expect(o.SampleIDs).To(BeEmpty())
+		}
+	})
+
+	Describe("with orphaned rows", func() {
+		var txCount, stateCount int64
+		BeforeEach(func() {
+			err = db.Get(&txCount, `SELECT COUNT(*) FROM eth.transaction_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			err = db.Get(&stateCount, `SELECT COUNT(*) FROM eth.state_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			// delete the header without cascading to its children
+			_, err = db.Exec(`ALTER TABLE eth.header_cids DISABLE TRIGGER ALL`)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = db.Exec(`DELETE FROM eth.header_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = db.Exec(`ALTER TABLE eth.header_cids ENABLE TRIGGER ALL`)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Reports the orphaned rows", func() {
+			orphans, err := checker.Verify()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(orphans[0].Check).To(Equal("transactions -> headers"))
+			Expect(orphans[0].Count).To(Equal(txCount))
+			Expect(len(orphans[0].SampleIDs)).To(BeEquivalentTo(txCount))
+			Expect(orphans[1].Count).To(BeZero())
+			Expect(orphans[2].Check).To(Equal("state -> headers"))
+			Expect(orphans[2].Count).To(Equal(stateCount))
+			Expect(orphans[3].Count).To(BeZero())
+		})
+
+		It("Deletes the orphaned rows and their children", func() {
+			deleted, err := checker.Fix()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deleted[0].Count).To(Equal(txCount))
+			Expect(deleted[2].Count).To(Equal(stateCount))
+			orphans, err := checker.Verify()
+			Expect(err).ToNot(HaveOccurred())
+			for _, o := range orphans {
+				Expect(o.Count).To(BeZero())
+			}
+			var rctCount int
+			err = db.Get(&rctCount, `SELECT COUNT(*) FROM eth.receipt_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rctCount).To(BeZero())
+		})
+	})
+})
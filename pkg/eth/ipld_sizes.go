@@ -0,0 +1,58 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	node "github.com/ipfs/go-ipld-format"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/ipfs/ipld"
+)
+
+// blockIPLDSizes returns the sizes of the header, uncle, transaction and receipt IPLDs of a block
+// transaction and receipt trie nodes are counted with the transactions and receipts
+func blockIPLDSizes(headerNode node.Node, uncleNodes []*ipld.EthHeader, txNodes []*ipld.EthTx, txTrieNodes []*ipld.EthTxTrie,
+	rctNodes []*ipld.EthReceipt, rctTrieNodes []*ipld.EthRctTrie) *IPLDSizesModel {
+	sizes := &IPLDSizesModel{
+		HeaderBytes: int64(len(headerNode.RawData())),
+	}
+	for _, n := range uncleNodes {
+		sizes.UncleBytes += int64(len(n.RawData()))
+	}
+	for _, n := range txNodes {
+		sizes.TxBytes += int64(len(n.RawData()))
+	}
+	for _, n := range txTrieNodes {
+		sizes.TxBytes += int64(len(n.RawData()))
+	}
+	for _, n := range rctNodes {
+		sizes.ReceiptBytes += int64(len(n.RawData()))
+	}
+	for _, n := range rctTrieNodes {
+		sizes.ReceiptBytes += int64(len(n.RawData()))
+	}
+	return sizes
+}
+
+// add adds the size of a state or storage IPLD to the sizes
+func (s *IPLDSizesModel) add(codec uint64, raw []byte) {
+	switch codec {
+	case ipld.MEthStateTrie:
+		s.StateBytes += int64(len(raw))
+	case ipld.MEthStorageTrie:
+		s.StorageBytes += int64(len(raw))
+	}
+}
@@ -0,0 +1,73 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/prom"
+)
+
+// IndexingLag describes how far the indexed data trails the head of the chain
+type IndexingLag struct {
+	ChainHead      uint64 `json:"chainHead"`
+	HighestIndexed uint64 `json:"highestIndexed"`
+	Lag            uint64 `json:"lag"`
+}
+
+// LagTracker measures the distance between the head of the chain and the highest block indexed in Postgres
+type LagTracker struct {
+	client    HeaderClient
+	retriever Retriever
+	timeout   time.Duration
+}
+
+// NewLagTracker returns a new LagTracker
+func NewLagTracker(client HeaderClient, retriever Retriever, timeout time.Duration) *LagTracker {
+	return &LagTracker{
+		client:    client,
+		retriever: retriever,
+		timeout:   timeout,
+	}
+}
+
+// Update fetches the head of the chain from the node and the highest indexed block from header_cids,
+// reports the lag between them to the metrics and returns it
+// the lag is 0 if the index is ahead of the node, e.g. while the node is still syncing
+func (lt *LagTracker) Update() (IndexingLag, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lt.timeout)
+	defer cancel()
+	head, err := lt.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return IndexingLag{}, fmt.Errorf("error fetching the head of the chain: %v", err)
+	}
+	last, err := lt.retriever.RetrieveLastBlockNumber()
+	if err != nil {
+		return IndexingLag{}, fmt.Errorf("error retrieving the highest indexed block: %v", err)
+	}
+	lag := IndexingLag{
+		ChainHead:      head.Number.Uint64(),
+		HighestIndexed: uint64(last),
+	}
+	if lag.ChainHead > lag.HighestIndexed {
+		lag.Lag = lag.ChainHead - lag.HighestIndexed
+	}
+	prom.SetIndexingLag(lag.ChainHead, lag.HighestIndexed, lag.Lag)
+	return lag, nil
+}
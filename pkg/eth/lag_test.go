@@ -0,0 +1,55 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+)
+
+var _ = Describe("LagTracker", func() {
+	Describe("Update", func() {
+		It("Returns the distance between the head of the chain and the highest indexed block", func() {
+			client := &mocks.HeaderClient{HeadToReturn: &types.Header{Number: big.NewInt(110)}}
+			retriever := &mocks.Retriever{LastBlockNumberToReturn: 100}
+			lag, err := eth.NewLagTracker(client, retriever, time.Second).Update()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lag).To(Equal(eth.IndexingLag{ChainHead: 110, HighestIndexed: 100, Lag: 10}))
+		})
+
+		It("Reports no lag when the index is ahead of the node", func() {
+			client := &mocks.HeaderClient{HeadToReturn: &types.Header{Number: big.NewInt(90)}}
+			retriever := &mocks.Retriever{LastBlockNumberToReturn: 100}
+			lag, err := eth.NewLagTracker(client, retriever, time.Second).Update()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lag.Lag).To(Equal(uint64(0)))
+		})
+
+		It("Returns an error if the head of the chain can't be fetched", func() {
+			retriever := &mocks.Retriever{LastBlockNumberToReturn: 100}
+			_, err := eth.NewLagTracker(&mocks.HeaderClient{}, retriever, time.Second).Update()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,36 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mocks
+
+import (
+	"sync"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+)
+
+// BlockEventSink is a mock sink that records the events emitted to it
+type BlockEventSink struct {
+	mu     sync.Mutex
+	Events []eth.BlockEvent
+}
+
+// Emit mock method
+func (s *BlockEventSink) Emit(event eth.BlockEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Events = append(s.Events, event)
+}
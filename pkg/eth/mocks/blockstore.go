@@ -0,0 +1,59 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mocks
+
+import (
+	"github.com/ipfs/go-cid"
+)
+
+// Blockstore is a mock external blockstore
+type Blockstore struct {
+	Blocks    map[string][]byte
+	ReturnErr error
+	// if greater than zero, ReturnErr is only returned by this many puts, after which they succeed
+	FailPuts int
+	// if not nil, this is called with the CID of each block before it is put
+	BeforePut func(c cid.Cid)
+	puts      int
+}
+
+// Put mock method
+func (bs *Blockstore) Put(c cid.Cid, raw []byte) error {
+	if bs.BeforePut != nil {
+		bs.BeforePut(c)
+	}
+	bs.puts++
+	if bs.ReturnErr != nil && (bs.FailPuts <= 0 || bs.puts <= bs.FailPuts) {
+		return bs.ReturnErr
+	}
+	if bs.Blocks == nil {
+		bs.Blocks = make(map[string][]byte)
+	}
+	bs.Blocks[c.String()] = raw
+	return nil
+}
+
+// DerivedCIDHandler is a mock handler of the IPLDs a dry run derives
+type DerivedCIDHandler struct {
+	CIDs []cid.Cid
+}
+
+// HandleDerivedCID mock method
+func (h *DerivedCIDHandler) HandleDerivedCID(c cid.Cid, raw []byte) error {
+	h.CIDs = append(h.CIDs, c)
+	return nil
+}
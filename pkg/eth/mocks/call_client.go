@@ -0,0 +1,43 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mocks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// CallClient is a mock client for making single rpc calls
+type CallClient struct {
+	ResultsToReturn map[string]interface{}
+	Called          []string
+}
+
+// CallContext mock method, the result for the method is round tripped through json into the provided result
+func (cc *CallClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	cc.Called = append(cc.Called, method)
+	res, ok := cc.ResultsToReturn[method]
+	if !ok {
+		return fmt.Errorf("mock call client has no result for method %s", method)
+	}
+	raw, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, result)
+}
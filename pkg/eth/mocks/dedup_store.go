@@ -0,0 +1,44 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mocks
+
+// DedupStore is a mock shared.DedupStore that records the keys looked up in it
+type DedupStore struct {
+	Keys    map[string]bool
+	Lookups []string
+}
+
+// Contains mock method
+func (s *DedupStore) Contains(key string) bool {
+	s.Lookups = append(s.Lookups, key)
+	return s.Keys[key]
+}
+
+// Add mock method
+func (s *DedupStore) Add(keys ...string) {
+	if s.Keys == nil {
+		s.Keys = make(map[string]bool)
+	}
+	for _, key := range keys {
+		s.Keys[key] = true
+	}
+}
+
+// Purge mock method
+func (s *DedupStore) Purge() {
+	s.Keys = nil
+}
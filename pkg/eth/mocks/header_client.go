@@ -0,0 +1,48 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// HeaderClient is a mock client for fetching canonical headers
+type HeaderClient struct {
+	HeadersToReturn map[uint64]*types.Header
+	HeadToReturn    *types.Header
+	CalledAt        []uint64
+}
+
+// HeaderByNumber mock method
+func (hc *HeaderClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		if hc.HeadToReturn == nil {
+			return nil, fmt.Errorf("mock header client has no head")
+		}
+		return hc.HeadToReturn, nil
+	}
+	hc.CalledAt = append(hc.CalledAt, number.Uint64())
+	header, ok := hc.HeadersToReturn[number.Uint64()]
+	if !ok {
+		return nil, fmt.Errorf("mock header client has no header at height %d", number.Uint64())
+	}
+	return header, nil
+}
@@ -17,6 +17,8 @@
 package mocks
 
 import (
+	"time"
+
 	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
 )
 
@@ -26,12 +28,14 @@ type Retriever struct {
 	GapsToRetrieveErr           error
 	CalledTimes                 int
 	FirstBlockNumberToReturn    int64
+	LastBlockNumberToReturn     int64
 	RetrieveFirstBlockNumberErr error
+	OldestValidationToReturn    *time.Time
 }
 
 // RetrieveLastBlockNumber mock method
-func (*Retriever) RetrieveLastBlockNumber() (int64, error) {
-	panic("implement me")
+func (mcr *Retriever) RetrieveLastBlockNumber() (int64, error) {
+	return mcr.LastBlockNumberToReturn, nil
 }
 
 // RetrieveFirstBlockNumber mock method
@@ -39,6 +43,11 @@ func (mcr *Retriever) RetrieveFirstBlockNumber() (int64, error) {
 	return mcr.FirstBlockNumberToReturn, mcr.RetrieveFirstBlockNumberErr
 }
 
+// RetrieveOldestValidation mock method
+func (mcr *Retriever) RetrieveOldestValidation() (*time.Time, error) {
+	return mcr.OldestValidationToReturn, nil
+}
+
 // RetrieveGapsInData mock method
 func (mcr *Retriever) RetrieveGapsInData(int) ([]eth.DBGap, error) {
 	mcr.CalledTimes++
@@ -0,0 +1,42 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mocks
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/statediff"
+)
+
+// TargetReconciler is a mock reconciler that records the payloads passed to it, keyed by target
+type TargetReconciler struct {
+	mu       sync.Mutex
+	Payloads map[string][]statediff.Payload
+	Errs     map[string][]error
+}
+
+// Reconcile mock method
+func (r *TargetReconciler) Reconcile(target string, payload statediff.Payload, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Payloads == nil {
+		r.Payloads = make(map[string][]statediff.Payload)
+		r.Errs = make(map[string][]error)
+	}
+	r.Payloads[target] = append(r.Payloads[target], payload)
+	r.Errs[target] = append(r.Errs[target], err)
+}
@@ -21,6 +21,7 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"math/big"
+	"time"
 
 	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
 
@@ -176,6 +177,8 @@ var (
 			LogContracts: []string{
 				Address.String(),
 			},
+			LogCount: 1,
+			GasUsed:  50,
 		},
 		{
 			CID:   "",
@@ -191,6 +194,8 @@ var (
 			LogContracts: []string{
 				AnotherAddress.String(),
 			},
+			LogCount: 1,
+			GasUsed:  100,
 		},
 		{
 			CID:          "",
@@ -198,6 +203,7 @@ var (
 			Contract:     ContractAddress.String(),
 			ContractHash: ContractHash,
 			LogContracts: []string{},
+			GasUsed:      75,
 		},
 	}
 	MockRctMetaPostPublish = []eth.ReceiptModel{
@@ -215,6 +221,8 @@ var (
 			LogContracts: []string{
 				Address.String(),
 			},
+			LogCount: 1,
+			GasUsed:  50,
 		},
 		{
 			CID:   Rct2CID.String(),
@@ -230,6 +238,8 @@ var (
 			LogContracts: []string{
 				AnotherAddress.String(),
 			},
+			LogCount: 1,
+			GasUsed:  100,
 		},
 		{
 			CID:          Rct3CID.String(),
@@ -237,6 +247,7 @@ var (
 			Contract:     ContractAddress.String(),
 			ContractHash: ContractHash,
 			LogContracts: []string{},
+			GasUsed:      75,
 		},
 	}
 
@@ -389,6 +400,7 @@ var (
 			UncleRoot:       MockBlock.UncleHash().String(),
 			Bloom:           MockBlock.Bloom().Bytes(),
 			Timestamp:       MockBlock.Time(),
+			BlockTime:       time.Unix(int64(MockBlock.Time()), 0).UTC(),
 		},
 		UncleCIDs:       []eth.UncleModel{},
 		TransactionCIDs: MockTrxMetaPostPublsh,
@@ -469,10 +481,10 @@ func createTransactionsAndReceipts() (types.Transactions, types.Receipts, common
 	mockReceipt1 := types.NewReceipt(common.HexToHash("0x0").Bytes(), false, 50)
 	mockReceipt1.Logs = []*types.Log{MockLog1}
 	mockReceipt1.TxHash = signedTrx1.Hash()
-	mockReceipt2 := types.NewReceipt(common.HexToHash("0x1").Bytes(), false, 100)
+	mockReceipt2 := types.NewReceipt(common.HexToHash("0x1").Bytes(), false, 150)
 	mockReceipt2.Logs = []*types.Log{MockLog2}
 	mockReceipt2.TxHash = signedTrx2.Hash()
-	mockReceipt3 := types.NewReceipt(common.HexToHash("0x2").Bytes(), false, 75)
+	mockReceipt3 := types.NewReceipt(common.HexToHash("0x2").Bytes(), false, 225)
 	mockReceipt3.Logs = []*types.Log{}
 	mockReceipt3.TxHash = signedTrx3.Hash()
 	return types.Transactions{signedTrx1, signedTrx2, signedTrx3}, types.Receipts{mockReceipt1, mockReceipt2, mockReceipt3}, SenderAddr
@@ -17,6 +17,9 @@
 package mocks
 
 import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/statediff"
 )
 
@@ -52,3 +55,39 @@ func (t *IterativeTransformer) Transform(workerID int, payload statediff.Payload
 	t.iteration++
 	return height, t.ReturnErr
 }
+
+// DecodedTransformer for testing
+type DecodedTransformer struct {
+	Transformer
+	PassedBlock       *types.Block
+	PassedReceipts    types.Receipts
+	PassedStateObject *statediff.StateObject
+	PassedTD          *big.Int
+}
+
+// TransformDecoded mock method
+func (t *DecodedTransformer) TransformDecoded(workerID int, block *types.Block, receipts types.Receipts, stateDiff *statediff.StateObject, td *big.Int) (uint64, error) {
+	t.PassedWorkerID = workerID
+	t.PassedBlock = block
+	t.PassedReceipts = receipts
+	t.PassedStateObject = stateDiff
+	t.PassedTD = td
+	return t.ReturnHeight, t.ReturnErr
+}
+
+// FlakyTransformer for testing, fails its first Failures calls with ReturnErr
+type FlakyTransformer struct {
+	Failures     int
+	Calls        int
+	ReturnHeight uint64
+	ReturnErr    error
+}
+
+// Transform mock method
+func (t *FlakyTransformer) Transform(workerID int, payload statediff.Payload) (uint64, error) {
+	t.Calls++
+	if t.Calls <= t.Failures {
+		return 0, t.ReturnErr
+	}
+	return t.ReturnHeight, nil
+}
@@ -16,7 +16,11 @@
 
 package eth
 
-import "github.com/lib/pq"
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
 
 // HeaderModel is the db model for eth.header_cids
 type HeaderModel struct {
@@ -35,7 +39,11 @@ type HeaderModel struct {
 	RctRoot         string `db:"receipt_root"`
 	Bloom           []byte `db:"bloom"`
 	Timestamp       uint64 `db:"timestamp"`
-	TimesValidated  int64  `db:"times_validated"`
+	// BlockTime is Timestamp as a time, the raw seconds are kept alongside it for exactness
+	BlockTime      time.Time `db:"block_time"`
+	TimesValidated int64     `db:"times_validated"`
+	// LastValidatedAt is nil for headers indexed before validation times were recorded
+	LastValidatedAt *time.Time `db:"last_validated_at"`
 }
 
 // UncleModel is the db model for eth.uncle_cids
@@ -47,23 +55,39 @@ type UncleModel struct {
 	CID        string `db:"cid"`
 	MhKey      string `db:"mh_key"`
 	Reward     string `db:"reward"`
+	// BlockNumber is the uncle's own number, not that of the block including it
+	BlockNumber string `db:"block_number"`
+	Coinbase    string `db:"coinbase"`
+	// Index is the position of the uncle in the uncles list of the block including it
+	Index int64 `db:"index"`
 }
 
 // TxModel is the db model for eth.transaction_cids
+// the table stores Src and Dst as ids in eth.addresses, they are read back as hex from eth.transaction_cids_with_addresses
 type TxModel struct {
-	ID         int64  `db:"id"`
-	HeaderID   int64  `db:"header_id"`
-	Index      int64  `db:"index"`
-	TxHash     string `db:"tx_hash"`
-	CID        string `db:"cid"`
-	MhKey      string `db:"mh_key"`
-	Dst        string `db:"dst"`
-	Src        string `db:"src"`
-	Data       []byte `db:"tx_data"`
-	Deployment bool   `db:"deployment"`
+	ID         int64   `db:"id"`
+	HeaderID   int64   `db:"header_id"`
+	Index      int64   `db:"index"`
+	TxHash     string  `db:"tx_hash"`
+	CID        string  `db:"cid"`
+	MhKey      string  `db:"mh_key"`
+	Dst        string  `db:"dst"`
+	Src        string  `db:"src"`
+	DstID      *int64  `db:"dst_id"` // nil for a contract deployment
+	SrcID      int64   `db:"src_id"`
+	Data       []byte  `db:"tx_data"`
+	MethodID   *string `db:"method_id"` // hex function selector, nil for deployments and txs with less than four bytes of input
+	Deployment bool    `db:"deployment"`
+	// signature values, only populated when signature indexing is enabled
+	R *string `db:"r"`
+	S *string `db:"s"`
+	V *string `db:"v"`
+	// the signed transaction's rlp encoding, only populated when raw transaction indexing is enabled
+	RLP []byte `db:"tx_rlp"`
 }
 
 // ReceiptModel is the db model for eth.receipt_cids
+// the table stores Contract and LogContracts as ids in eth.addresses, they are read back as hex from eth.receipt_cids_with_addresses
 type ReceiptModel struct {
 	ID           int64          `db:"id"`
 	TxID         int64          `db:"tx_id"`
@@ -76,6 +100,12 @@ type ReceiptModel struct {
 	Topic1s      pq.StringArray `db:"topic1s"`
 	Topic2s      pq.StringArray `db:"topic2s"`
 	Topic3s      pq.StringArray `db:"topic3s"`
+	LogCount     int64          `db:"log_count"`
+	// GasUsed is the gas used by this receipt's tx alone, not the cumulative gas used of the block up to it
+	GasUsed uint64 `db:"gas_used"`
+	// ids of Contract and LogContracts in eth.addresses, ContractID is nil if the receipt is not for a contract deployment
+	ContractID     *int64        `db:"contract_id"`
+	LogContractIDs pq.Int64Array `db:"log_contract_ids"`
 }
 
 // StateNodeModel is the db model for eth.state_cids
@@ -123,4 +153,17 @@ type StateAccountModel struct {
 	Nonce       uint64 `db:"nonce"`
 	CodeHash    []byte `db:"code_hash"`
 	StorageRoot string `db:"storage_root"`
+	// BlockNumber is not a column of state_accounts, it is only populated by queries that join the account to its header
+	BlockNumber string `db:"block_number"`
+}
+
+// IPLDSizesModel is the db model for eth.ipld_sizes, the total size in bytes of the IPLDs published for a block by node type
+type IPLDSizesModel struct {
+	HeaderID     int64 `db:"header_id"`
+	HeaderBytes  int64 `db:"header_bytes"`
+	UncleBytes   int64 `db:"uncle_bytes"`
+	TxBytes      int64 `db:"tx_bytes"`
+	ReceiptBytes int64 `db:"receipt_bytes"`
+	StateBytes   int64 `db:"state_bytes"`
+	StorageBytes int64 `db:"storage_bytes"`
 }
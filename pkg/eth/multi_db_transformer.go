@@ -0,0 +1,164 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/statediff"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultTargetRetries is the number of times a MultiDBTransformer retries a payload against a failed target by default
+const DefaultTargetRetries = 3
+
+// DefaultTargetRetryBackoff is the backoff before a MultiDBTransformer's first retry against a failed target by default
+const DefaultTargetRetryBackoff = 500 * time.Millisecond
+
+// MultiDBTarget is one of the databases a MultiDBTransformer writes to
+type MultiDBTarget struct {
+	// Name used to identify the target in logs, results and reconciliation, such as the database's host and name
+	Name string
+	// Transformer that writes to the target, usually a StateDiffTransformer created with the target's postgres.DB
+	Transformer Transformer
+}
+
+// TargetResult is the outcome of transforming a payload against a single MultiDBTarget
+type TargetResult struct {
+	Name string
+	// Number of times the payload was transformed against the target, including the retries
+	Attempts int
+	Height   uint64
+	// Error of the last attempt, nil if the target succeeded
+	Err error
+}
+
+// TargetReconciler is handed every payload a MultiDBTransformer could not write to one of its targets after exhausting
+// its retries, so that the target can be brought back in line with the others later, such as by re-queueing the block
+// or recording it as a gap for a backfill pointed at that target
+type TargetReconciler interface {
+	Reconcile(target string, payload statediff.Payload, err error)
+}
+
+// MultiDBTransformer satisfies the Transformer interface by writing each payload to several databases for high availability
+// the targets are written to concurrently and each failed target is retried independently of the others, a payload only
+// fails once it has failed against every target; until a failed target is reconciled it is behind the others
+type MultiDBTransformer struct {
+	targets []MultiDBTarget
+	// How many times to retry a payload against a target that failed it, defaults to DefaultTargetRetries
+	MaxRetries int
+	// The backoff before the first retry against a target, which doubles with each retry, defaults to DefaultTargetRetryBackoff
+	RetryBackoff time.Duration
+	// If not nil, each payload that a target still fails once its retries are exhausted is passed to this reconciler
+	Reconciler TargetReconciler
+}
+
+// NewMultiDBTransformer creates a pointer to a new MultiDBTransformer which writes to the provided targets
+func NewMultiDBTransformer(targets ...MultiDBTarget) *MultiDBTransformer {
+	return &MultiDBTransformer{
+		targets:      targets,
+		MaxRetries:   DefaultTargetRetries,
+		RetryBackoff: DefaultTargetRetryBackoff,
+	}
+}
+
+// Transform satisfies the Transformer interface
+// it returns the height of the payload if at least one target succeeded, the failed targets are logged and reconciled
+func (mt *MultiDBTransformer) Transform(workerID int, payload statediff.Payload) (uint64, error) {
+	height, _, err := mt.TransformTargets(workerID, payload)
+	return height, err
+}
+
+// TransformTargets writes the payload to every target and reports the result of each, in the order of the targets
+// an error is only returned if every target failed
+func (mt *MultiDBTransformer) TransformTargets(workerID int, payload statediff.Payload) (uint64, []TargetResult, error) {
+	if len(mt.targets) == 0 {
+		return 0, nil, errors.New("multi db transformer has no targets")
+	}
+	results := make([]TargetResult, len(mt.targets))
+	wg := new(sync.WaitGroup)
+	for i, target := range mt.targets {
+		wg.Add(1)
+		go func(i int, target MultiDBTarget) {
+			defer wg.Done()
+			results[i] = mt.transformTarget(workerID, target, payload)
+		}(i, target)
+	}
+	wg.Wait()
+
+	var height uint64
+	var failed []TargetResult
+	for _, result := range results {
+		if result.Err != nil {
+			logrus.Errorf("worker %d failed to write payload to target %s after %d attempts: %v", workerID, result.Name, result.Attempts, result.Err)
+			failed = append(failed, result)
+			continue
+		}
+		height = result.Height
+	}
+	if len(failed) == len(results) {
+		return 0, results, fmt.Errorf("payload failed on all %d targets: %w", len(failed), failed[0].Err)
+	}
+	// the payload is only reconciled when another target has it, if every target failed it is up to the caller to retry it
+	if mt.Reconciler != nil {
+		for _, result := range failed {
+			mt.Reconciler.Reconcile(result.Name, payload, result.Err)
+		}
+	}
+	return height, results, nil
+}
+
+// transformTarget transforms the payload against a single target, backing off exponentially and retrying while it fails
+// with an error that retrying could resolve
+func (mt *MultiDBTransformer) transformTarget(workerID int, target MultiDBTarget, payload statediff.Payload) TargetResult {
+	result := TargetResult{Name: target.Name}
+	backoff := mt.RetryBackoff
+	for {
+		result.Attempts++
+		result.Height, result.Err = target.Transformer.Transform(workerID, payload)
+		if result.Err == nil || isPayloadError(result.Err) || result.Attempts > mt.MaxRetries {
+			return result
+		}
+		logrus.Warnf("worker %d error writing payload to target %s, retrying in %s (%d/%d): %v", workerID, target.Name, backoff, result.Attempts, mt.MaxRetries, result.Err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// isPayloadError returns true if the error is due to the payload itself, and would fail it against every target and on every retry
+func isPayloadError(err error) bool {
+	for _, payloadErr := range []error{
+		ErrDecodeBlock,
+		ErrDecodeReceipts,
+		ErrDecodeStateObject,
+		ErrDecodeStateLeaf,
+		ErrNodeCountMismatch,
+		ErrReceiptCountMismatch,
+		ErrBloomMismatch,
+		ErrUnrecognizedStateObject,
+		ErrTooManyTopics,
+		ErrPayloadTooLarge,
+	} {
+		if errors.Is(err, payloadErr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("MultiDBTransformer", func() {
+	var (
+		primary    *mocks.FlakyTransformer
+		replica    *mocks.FlakyTransformer
+		reconciler *mocks.TargetReconciler
+		multi      *eth.MultiDBTransformer
+		targetErr  = errors.New("mock target error")
+	)
+	BeforeEach(func() {
+		primary = &mocks.FlakyTransformer{ReturnHeight: mocks.BlockNumber.Uint64(), ReturnErr: targetErr}
+		replica = &mocks.FlakyTransformer{ReturnHeight: mocks.BlockNumber.Uint64(), ReturnErr: targetErr}
+		reconciler = new(mocks.TargetReconciler)
+		multi = eth.NewMultiDBTransformer(
+			eth.MultiDBTarget{Name: "primary", Transformer: primary},
+			eth.MultiDBTarget{Name: "replica", Transformer: replica},
+		)
+		multi.RetryBackoff = 0
+		multi.Reconciler = reconciler
+	})
+
+	Describe("TransformTargets", func() {
+		It("Writes the payload to every target", func() {
+			height, results, err := multi.TransformTargets(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(height).To(Equal(mocks.BlockNumber.Uint64()))
+			Expect(results).To(Equal([]eth.TargetResult{
+				{Name: "primary", Attempts: 1, Height: mocks.BlockNumber.Uint64()},
+				{Name: "replica", Attempts: 1, Height: mocks.BlockNumber.Uint64()},
+			}))
+			Expect(reconciler.Payloads).To(BeEmpty())
+		})
+
+		It("Retries a failed target independently of the others", func() {
+			replica.Failures = 2
+			height, results, err := multi.TransformTargets(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(height).To(Equal(mocks.BlockNumber.Uint64()))
+			Expect(primary.Calls).To(Equal(1))
+			Expect(replica.Calls).To(Equal(3))
+			Expect(results[1].Attempts).To(Equal(3))
+			Expect(results[1].Err).ToNot(HaveOccurred())
+			Expect(reconciler.Payloads).To(BeEmpty())
+		})
+
+		It("Reconciles a target that still fails once its retries are exhausted, without failing the payload", func() {
+			replica.Failures = eth.DefaultTargetRetries + 1
+			height, results, err := multi.TransformTargets(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(height).To(Equal(mocks.BlockNumber.Uint64()))
+			Expect(results[0].Err).ToNot(HaveOccurred())
+			Expect(results[1].Attempts).To(Equal(eth.DefaultTargetRetries + 1))
+			Expect(results[1].Err).To(MatchError(targetErr))
+			Expect(reconciler.Payloads).To(HaveLen(1))
+			Expect(reconciler.Payloads["replica"]).To(HaveLen(1))
+			Expect(reconciler.Payloads["replica"][0]).To(Equal(mocks.MockStateDiffPayload))
+			Expect(reconciler.Errs["replica"][0]).To(MatchError(targetErr))
+		})
+
+		It("Fails the payload without reconciling it if every target fails", func() {
+			primary.Failures = eth.DefaultTargetRetries + 1
+			replica.Failures = eth.DefaultTargetRetries + 1
+			_, results, err := multi.TransformTargets(1, mocks.MockStateDiffPayload)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, targetErr)).To(BeTrue())
+			Expect(results[0].Err).To(MatchError(targetErr))
+			Expect(results[1].Err).To(MatchError(targetErr))
+			Expect(reconciler.Payloads).To(BeEmpty())
+		})
+
+		It("Does not retry errors caused by the payload itself", func() {
+			primary.Failures = 1
+			primary.ReturnErr = eth.ErrDecodeBlock
+			replica.Failures = 1
+			replica.ReturnErr = eth.ErrDecodeBlock
+			_, results, err := multi.TransformTargets(1, mocks.MockStateDiffPayload)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, eth.ErrDecodeBlock)).To(BeTrue())
+			Expect(results[0].Attempts).To(Equal(1))
+			Expect(results[1].Attempts).To(Equal(1))
+		})
+	})
+
+	Describe("TransformTargets against real databases", func() {
+		var db, replicaDB *postgres.DB
+		BeforeEach(func() {
+			var err error
+			db, err = shared.SetupDB()
+			Expect(err).ToNot(HaveOccurred())
+			replicaDB, err = shared.SetupReplicaDB()
+			Expect(err).ToNot(HaveOccurred())
+		})
+		AfterEach(func() {
+			eth.TearDownDB(db)
+			eth.TearDownDB(replicaDB)
+			replicaDB.Close()
+		})
+
+		It("Publishes the IPLDs to every target's public.blocks", func() {
+			// index the payload into the first database on its own, so that its keys are recorded as committed there
+			_, err := eth.NewStateDiffTransformer(params.MainnetChainConfig, db).Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = db.Exec(`DELETE FROM eth.header_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			multi := eth.NewMultiDBTransformer(
+				eth.MultiDBTarget{Name: "primary", Transformer: eth.NewStateDiffTransformer(params.MainnetChainConfig, db)},
+				eth.MultiDBTarget{Name: "replica", Transformer: eth.NewStateDiffTransformer(params.MainnetChainConfig, replicaDB)},
+			)
+			_, results, err := multi.TransformTargets(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			for _, result := range results {
+				Expect(result.Err).ToNot(HaveOccurred())
+			}
+			for _, targetDB := range []*postgres.DB{db, replicaDB} {
+				for _, mhKey := range []string{mocks.State1MhKey, mocks.State2MhKey, mocks.StorageMhKey} {
+					var count int
+					err = targetDB.Get(&count, `SELECT COUNT(*) FROM public.blocks WHERE key = $1`, mhKey)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(count).To(Equal(1))
+				}
+				var storageCount int
+				err = targetDB.Get(&storageCount, `SELECT COUNT(*) FROM eth.storage_cids WHERE mh_key = $1`, mocks.StorageMhKey)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(storageCount).To(Equal(1))
+			}
+		})
+
+		It("Does not skip the insert of a key marked as published in one database when publishing it to another", func() {
+			shared.MarkPublished(shared.DedupStoreFor(db.DB), mocks.StorageMhKey)
+			Expect(shared.RecentlyPublished(shared.DedupStoreFor(db.DB), mocks.StorageMhKey)).To(BeTrue())
+			Expect(shared.RecentlyPublished(shared.DedupStoreFor(replicaDB.DB), mocks.StorageMhKey)).To(BeFalse())
+			publish := func(targetDB *postgres.DB) (bool, int) {
+				tx, err := targetDB.Beginx()
+				Expect(err).ToNot(HaveOccurred())
+				inserted, err := shared.PublishDirectIfNew(tx, shared.DedupStoreFor(targetDB.DB), mocks.StorageMhKey, mocks.StorageLeafNode)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tx.Commit()).To(Succeed())
+				var count int
+				err = targetDB.Get(&count, `SELECT COUNT(*) FROM public.blocks WHERE key = $1`, mocks.StorageMhKey)
+				Expect(err).ToNot(HaveOccurred())
+				return inserted, count
+			}
+			// the key was never written to the first database, so skipping it there leaves it missing
+			inserted, count := publish(db)
+			Expect(inserted).To(BeFalse())
+			Expect(count).To(Equal(0))
+			inserted, count = publish(replicaDB)
+			Expect(inserted).To(BeTrue())
+			Expect(count).To(Equal(1))
+		})
+	})
+
+	Describe("Transform", func() {
+		It("Returns an error if there are no targets", func() {
+			_, err := eth.NewMultiDBTransformer().Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,129 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	node "github.com/ipfs/go-ipld-format"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/ipfs/ipld"
+)
+
+// supportedMultihashes are the hash functions the IPLDs can be keyed under
+var supportedMultihashes = map[uint64]bool{
+	multihash.KECCAK_256: true,
+	multihash.SHA2_256:   true,
+}
+
+// multihashNodeTypes are the names the IPLD node types are configured by, uncles are keyed as headers
+var multihashNodeTypes = map[string]uint64{
+	"header":           ipld.MEthHeader,
+	"transaction":      ipld.MEthTx,
+	"transaction-trie": ipld.MEthTxTrie,
+	"receipt":          ipld.MEthTxReceipt,
+	"receipt-trie":     ipld.MEthTxReceiptTrie,
+	"state":            ipld.MEthStateTrie,
+	"storage":          ipld.MEthStorageTrie,
+}
+
+// DefaultMultihashes returns the hash function each IPLD node type is keyed under by default, keyed by its multicodec
+// every node type is keyed under keccak256, the hash ethereum itself uses for them
+func DefaultMultihashes() map[uint64]uint64 {
+	return map[uint64]uint64{
+		ipld.MEthHeader:        multihash.KECCAK_256,
+		ipld.MEthTx:            multihash.KECCAK_256,
+		ipld.MEthTxTrie:        multihash.KECCAK_256,
+		ipld.MEthTxReceipt:     multihash.KECCAK_256,
+		ipld.MEthTxReceiptTrie: multihash.KECCAK_256,
+		ipld.MEthStateTrie:     multihash.KECCAK_256,
+		ipld.MEthStorageTrie:   multihash.KECCAK_256,
+	}
+}
+
+// ValidateMultihashes returns an error if the provided map is keyed by a multicodec the transformer does not publish
+// or maps one to a hash function that is not supported
+func ValidateMultihashes(multihashes map[uint64]uint64) error {
+	defaults := DefaultMultihashes()
+	for codec, mh := range multihashes {
+		if _, ok := defaults[codec]; !ok {
+			return fmt.Errorf("multicodec 0x%x is not an ethereum IPLD node type", codec)
+		}
+		if !supportedMultihashes[mh] {
+			return fmt.Errorf("multihash 0x%x for multicodec 0x%x is not supported", mh, codec)
+		}
+	}
+	return nil
+}
+
+// ParseMultihashes parses the hash function of each configured node type from entries of the form nodeType=hashName, e.g.
+// state=sha2-256; entries can hold several of these separated by commas or whitespace
+// the node types are header, transaction, transaction-trie, receipt, receipt-trie, state and storage, and the hash names
+// are multihash names, an error is returned for an unknown node type or hash name or for a hash function that is not supported
+func ParseMultihashes(entries []string) (map[uint64]uint64, error) {
+	multihashes := make(map[uint64]uint64)
+	for _, entry := range entries {
+		fields := strings.FieldsFunc(entry, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\r' || r == '\n'
+		})
+		for _, field := range fields {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("invalid multihash %q, expected nodeType=hashName", field)
+			}
+			codec, ok := multihashNodeTypes[parts[0]]
+			if !ok {
+				return nil, fmt.Errorf("unknown node type %q in multihash %q", parts[0], field)
+			}
+			mh, ok := multihash.Names[parts[1]]
+			if !ok {
+				return nil, fmt.Errorf("unknown hash function %q in multihash %q", parts[1], field)
+			}
+			multihashes[codec] = mh
+		}
+	}
+	if err := ValidateMultihashes(multihashes); err != nil {
+		return nil, err
+	}
+	return multihashes, nil
+}
+
+// SetMultihashes sets the hash function each IPLD node type is keyed under, keyed by its multicodec
+// node types that are left out keep their current hash function
+// changing the hash function of a node type changes the CIDs it is indexed under, so it should be set before indexing begins
+func (sdt *StateDiffTransformer) SetMultihashes(multihashes map[uint64]uint64) error {
+	if err := ValidateMultihashes(multihashes); err != nil {
+		return err
+	}
+	for codec, mh := range multihashes {
+		sdt.multihashes[codec] = mh
+	}
+	return nil
+}
+
+// cidFor returns the CID of the node under the hash function configured for the provided multicodec
+// the go-ethereum IPLD nodes derive their CIDs from keccak256, so they are only re-derived for other hash functions
+func (sdt *StateDiffTransformer) cidFor(codec uint64, n node.Node) (cid.Cid, error) {
+	mh := sdt.multihashes[codec]
+	if mh == multihash.KECCAK_256 {
+		return n.Cid(), nil
+	}
+	return ipld.RawdataToCid(codec, n.RawData(), mh)
+}
@@ -0,0 +1,57 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"github.com/multiformats/go-multihash"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/ipfs/ipld"
+)
+
+var _ = Describe("ParseMultihashes", func() {
+	It("Parses the hash function of each configured node type", func() {
+		multihashes, err := eth.ParseMultihashes([]string{"state=sha2-256, storage=sha2-256", "header=keccak-256"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(multihashes).To(Equal(map[uint64]uint64{
+			ipld.MEthStateTrie:   multihash.SHA2_256,
+			ipld.MEthStorageTrie: multihash.SHA2_256,
+			ipld.MEthHeader:      multihash.KECCAK_256,
+		}))
+	})
+
+	It("Returns no multihashes when none are configured", func() {
+		multihashes, err := eth.ParseMultihashes(nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(multihashes).To(BeEmpty())
+	})
+
+	It("Rejects unknown node types, unknown hash names and unsupported hash functions", func() {
+		_, err := eth.ParseMultihashes([]string{"account=sha2-256"})
+		Expect(err).To(HaveOccurred())
+		_, err = eth.ParseMultihashes([]string{"state=not-a-hash"})
+		Expect(err).To(HaveOccurred())
+		_, err = eth.ParseMultihashes([]string{"state"})
+		Expect(err).To(HaveOccurred())
+		// a valid multihash name, but not one the IPLDs can be keyed under
+		_, err = eth.ParseMultihashes([]string{"state=sha3-256"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("not supported"))
+	})
+})
@@ -19,6 +19,7 @@ package eth
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/rpc"
@@ -89,3 +90,22 @@ func (fetcher *PayloadFetcher) FetchAt(blockHeights []uint64) ([]statediff.Paylo
 	}
 	return results, nil
 }
+
+// rateLimitMessages are the fragments of the errors returned by nodes and rpc providers that are rate limiting us:
+// the status of an HTTP 429 response, and the message of the EIP-1474 "limit exceeded" (-32005) error
+var rateLimitMessages = []string{"429", "too many requests", "rate limit", "limit exceeded"}
+
+// IsRateLimitError returns whether the error returned by the node indicates that it is rate limiting our requests
+// the rpc client does not expose the HTTP status or error code of a failed batch, so the error message is matched
+func IsRateLimitError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range rateLimitMessages {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
@@ -17,6 +17,7 @@
 package eth_test
 
 import (
+	"errors"
 	"time"
 
 	"github.com/ethereum/go-ethereum/statediff"
@@ -25,6 +26,7 @@ import (
 
 	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
 )
 
 var _ = Describe("StateDiffFetcher", func() {
@@ -59,5 +61,34 @@ var _ = Describe("StateDiffFetcher", func() {
 			Expect(payload1).To(Equal(mocks.MockStateDiffPayload))
 			Expect(payload2).To(Equal(payload2))
 		})
+
+		It("Spaces out the requests of a rate limited client", func() {
+			limitedFetcher := eth.NewPayloadFetcher(eth.NewRateLimitedBatchClient(mc, shared.NewRateLimiter(20)), time.Second*60)
+			blockHeights := []uint64{
+				mocks.BlockNumber.Uint64(),
+				blockNumber2,
+			}
+			start := time.Now()
+			for i := 0; i < 3; i++ {
+				_, err := limitedFetcher.FetchAt(blockHeights)
+				Expect(err).ToNot(HaveOccurred())
+			}
+			// six requests at 20 per second, the first of which is let through immediately
+			Expect(time.Now().Sub(start)).To(BeNumerically(">=", 250*time.Millisecond))
+		})
+	})
+
+	Describe("IsRateLimitError", func() {
+		It("Recognizes the errors returned when the node is rate limiting requests", func() {
+			Expect(eth.IsRateLimitError(errors.New("429 Too Many Requests: "))).To(BeTrue())
+			Expect(eth.IsRateLimitError(errors.New("ethereum PayloadFetcher err at blockheight 1: limit exceeded"))).To(BeTrue())
+			Expect(eth.IsRateLimitError(errors.New("daily request count exceeded, request rate limited"))).To(BeTrue())
+		})
+
+		It("Does not match other errors", func() {
+			Expect(eth.IsRateLimitError(nil)).To(BeFalse())
+			Expect(eth.IsRateLimitError(errors.New("503 Service Unavailable"))).To(BeFalse())
+			Expect(eth.IsRateLimitError(errors.New("context deadline exceeded"))).To(BeFalse())
+		})
 	})
 })
@@ -0,0 +1,75 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/statediff"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+)
+
+// storedPayload is a row of eth.payloads
+type storedPayload struct {
+	BlockNumber    uint64 `db:"block_number"`
+	TD             string `db:"td"`
+	BlockRlp       []byte `db:"block_rlp"`
+	ReceiptsRlp    []byte `db:"receipts_rlp"`
+	StateObjectRlp []byte `db:"state_object_rlp"`
+}
+
+// DBPayloadFetcher satisfies the Fetcher interface by reading back the payloads stored in eth.payloads by
+// transformers with PersistPayloads set, so that they can be reprocessed without a node
+type DBPayloadFetcher struct {
+	db *postgres.DB
+}
+
+// NewDBPayloadFetcher returns a pointer to a new DBPayloadFetcher
+func NewDBPayloadFetcher(db *postgres.DB) *DBPayloadFetcher {
+	return &DBPayloadFetcher{
+		db: db,
+	}
+}
+
+// FetchAt returns the stored payloads at the provided heights, in the order of the heights
+// it returns an error if no payload has been stored at one of them
+func (f *DBPayloadFetcher) FetchAt(blockHeights []uint64) ([]statediff.Payload, error) {
+	pgStr := fmt.Sprintf(`SELECT block_number, td, block_rlp, receipts_rlp, state_object_rlp FROM %s.payloads WHERE block_number = $1`, f.db.Schema)
+	payloads := make([]statediff.Payload, 0, len(blockHeights))
+	for _, height := range blockHeights {
+		rows := make([]storedPayload, 0, 1)
+		if err := f.db.Select(&rows, pgStr, height); err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			return nil, fmt.Errorf("no payload is stored at height %d", height)
+		}
+		td, ok := new(big.Int).SetString(rows[0].TD, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid total difficulty %s stored at height %d", rows[0].TD, height)
+		}
+		payloads = append(payloads, statediff.Payload{
+			BlockRlp:        rows[0].BlockRlp,
+			ReceiptsRlp:     rows[0].ReceiptsRlp,
+			StateObjectRlp:  rows[0].StateObjectRlp,
+			TotalDifficulty: td,
+		})
+	}
+	return payloads, nil
+}
@@ -0,0 +1,82 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("DBPayloadFetcher", func() {
+	var (
+		db  *postgres.DB
+		err error
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	Describe("FetchAt", func() {
+		It("Returns the payloads stored by a transformer with payload persistence enabled", func() {
+			_, err = eth.NewStateDiffTransformer(params.MainnetChainConfig, db).Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			fetcher := eth.NewDBPayloadFetcher(db)
+			_, err = fetcher.FetchAt([]uint64{1})
+			Expect(err).To(HaveOccurred())
+
+			transformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			transformer.PersistPayloads = true
+			_, err = transformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			payloads, err := fetcher.FetchAt([]uint64{1})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(payloads)).To(Equal(1))
+			Expect(payloads[0].BlockRlp).To(Equal(mocks.MockStateDiffPayload.BlockRlp))
+			Expect(payloads[0].ReceiptsRlp).To(Equal(mocks.MockStateDiffPayload.ReceiptsRlp))
+			Expect(payloads[0].StateObjectRlp).To(Equal(mocks.MockStateDiffPayload.StateObjectRlp))
+			Expect(payloads[0].TotalDifficulty.String()).To(Equal(mocks.MockStateDiffPayload.TotalDifficulty.String()))
+		})
+
+		It("Reindexes a block from its stored payload", func() {
+			transformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			transformer.PersistPayloads = true
+			_, err = transformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			err = eth.NewDBCleaner(db).Clean([][2]uint64{{1, 1}}, shared.Full)
+			Expect(err).ToNot(HaveOccurred())
+
+			payloads, err := eth.NewDBPayloadFetcher(db).FetchAt([]uint64{1})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = eth.NewStateDiffTransformer(params.MainnetChainConfig, db).Transform(1, payloads[0])
+			Expect(err).ToNot(HaveOccurred())
+			var blockHash string
+			err = db.Get(&blockHash, `SELECT block_hash FROM eth.header_cids WHERE block_number = 1`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockHash).To(Equal(mocks.MockBlock.Hash().String()))
+		})
+	})
+})
@@ -18,6 +18,7 @@ package eth
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -63,6 +64,7 @@ func (pub *IPLDPublisher) Publish(payload ConvertedPayload) error {
 	if err != nil {
 		return err
 	}
+	addresses := make(addressIDs)
 	defer func() {
 		if p := recover(); p != nil {
 			shared.Rollback(tx)
@@ -71,6 +73,9 @@ func (pub *IPLDPublisher) Publish(payload ConvertedPayload) error {
 			shared.Rollback(tx)
 		} else {
 			err = tx.Commit()
+			if err == nil {
+				pub.indexer.cacheAddressIDs(addresses)
+			}
 		}
 	}()
 
@@ -105,6 +110,7 @@ func (pub *IPLDPublisher) Publish(payload ConvertedPayload) error {
 		TxRoot:          payload.Block.TxHash().String(),
 		UncleRoot:       payload.Block.UncleHash().String(),
 		Timestamp:       payload.Block.Time(),
+		BlockTime:       time.Unix(int64(payload.Block.Time()), 0).UTC(),
 	}
 	headerID, err := pub.indexer.indexHeaderCID(tx, header)
 	if err != nil {
@@ -112,17 +118,20 @@ func (pub *IPLDPublisher) Publish(payload ConvertedPayload) error {
 	}
 
 	// Publish and index uncles
-	for _, uncleNode := range uncleNodes {
+	for i, uncleNode := range uncleNodes {
 		if err := shared.PublishIPLD(tx, uncleNode); err != nil {
 			return err
 		}
 		uncleReward := CalcUncleMinerReward(payload.Block.Number().Uint64(), uncleNode.Number.Uint64())
 		uncle := UncleModel{
-			CID:        uncleNode.Cid().String(),
-			MhKey:      shared.MultihashKeyFromCID(uncleNode.Cid()),
-			ParentHash: uncleNode.ParentHash.String(),
-			BlockHash:  uncleNode.Hash().String(),
-			Reward:     uncleReward.String(),
+			CID:         uncleNode.Cid().String(),
+			MhKey:       shared.MultihashKeyFromCID(uncleNode.Cid()),
+			ParentHash:  uncleNode.ParentHash.String(),
+			BlockHash:   uncleNode.Hash().String(),
+			Reward:      uncleReward.String(),
+			BlockNumber: uncleNode.Number.String(),
+			Coinbase:    uncleNode.Coinbase.String(),
+			Index:       int64(i),
 		}
 		if err := pub.indexer.indexUncleCID(tx, uncle, headerID); err != nil {
 			return err
@@ -141,12 +150,12 @@ func (pub *IPLDPublisher) Publish(payload ConvertedPayload) error {
 		txModel := payload.TxMetaData[i]
 		txModel.CID = txNode.Cid().String()
 		txModel.MhKey = shared.MultihashKeyFromCID(txNode.Cid())
-		txID, err := pub.indexer.indexTransactionCID(tx, txModel, headerID)
+		txID, err := pub.indexer.indexTransactionCID(tx, addresses, txModel, headerID)
 		if err != nil {
 			return err
 		}
-		// If tx is a contract deployment, publish the data (code)
-		if txModel.Deployment { // codec doesn't matter in this case sine we are not interested in the cid and the db key is multihash-derived
+		// If tx is a successful contract deployment, publish the data (code)
+		if txModel.Deployment && receiptSucceeded(payload.Receipts[i]) { // codec doesn't matter in this case sine we are not interested in the cid and the db key is multihash-derived
 			if _, err = shared.PublishRaw(tx, ipld.MEthStorageTrie, multihash.KECCAK_256, txModel.Data); err != nil {
 				return err
 			}
@@ -154,7 +163,7 @@ func (pub *IPLDPublisher) Publish(payload ConvertedPayload) error {
 		rctModel := payload.ReceiptMetaData[i]
 		rctModel.CID = rctNode.Cid().String()
 		rctModel.MhKey = shared.MultihashKeyFromCID(rctNode.Cid())
-		if err := pub.indexer.indexReceiptCID(tx, rctModel, txID); err != nil {
+		if err := pub.indexer.indexReceiptCID(tx, addresses, rctModel, txID); err != nil {
 			return err
 		}
 	}
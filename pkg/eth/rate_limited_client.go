@@ -0,0 +1,74 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+// RateLimitedBatchClient is a BatchClient that waits on a rate limiter before each batch, counting every element of
+// the batch as a request
+type RateLimitedBatchClient struct {
+	client  BatchClient
+	limiter *shared.RateLimiter
+}
+
+// NewRateLimitedBatchClient returns a pointer to a new RateLimitedBatchClient
+// the client is not limited if the limiter is nil
+func NewRateLimitedBatchClient(client BatchClient, limiter *shared.RateLimiter) *RateLimitedBatchClient {
+	return &RateLimitedBatchClient{
+		client:  client,
+		limiter: limiter,
+	}
+}
+
+// BatchCallContext satisfies the BatchClient interface
+func (c *RateLimitedBatchClient) BatchCallContext(ctx context.Context, batch []rpc.BatchElem) error {
+	if err := c.limiter.Wait(ctx, len(batch)); err != nil {
+		return err
+	}
+	return c.client.BatchCallContext(ctx, batch)
+}
+
+// RateLimitedHeaderClient is a HeaderClient that waits on a rate limiter before each request
+type RateLimitedHeaderClient struct {
+	client  HeaderClient
+	limiter *shared.RateLimiter
+}
+
+// NewRateLimitedHeaderClient returns a pointer to a new RateLimitedHeaderClient
+// the client is not limited if the limiter is nil
+func NewRateLimitedHeaderClient(client HeaderClient, limiter *shared.RateLimiter) *RateLimitedHeaderClient {
+	return &RateLimitedHeaderClient{
+		client:  client,
+		limiter: limiter,
+	}
+}
+
+// HeaderByNumber satisfies the HeaderClient interface
+func (c *RateLimitedHeaderClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if err := c.limiter.Wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	return c.client.HeaderByNumber(ctx, number)
+}
@@ -19,6 +19,8 @@ package eth
 import (
 	"database/sql"
 	"fmt"
+	"sort"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -30,11 +32,15 @@ type Retriever interface {
 	RetrieveFirstBlockNumber() (int64, error)
 	RetrieveLastBlockNumber() (int64, error)
 	RetrieveGapsInData(validationLevel int) ([]DBGap, error)
+	RetrieveOldestValidation() (*time.Time, error)
 }
 
 // GapRetriever type for Ethereum
 type GapRetriever struct {
 	db *postgres.DB
+	// If true, blocks that have a gap recorded in eth.gaps are not returned by RetrieveGapsInData, this is set when the
+	// blocks are being indexed in the transformer's HeadersOnly mode, for which they are not missing anything
+	HeadersOnly bool
 }
 
 // NewGapRetriever returns a pointer to a new GapRetriever
@@ -47,14 +53,42 @@ func NewGapRetriever(db *postgres.DB) *GapRetriever {
 // RetrieveFirstBlockNumber is used to retrieve the first block number in the db
 func (ecr *GapRetriever) RetrieveFirstBlockNumber() (int64, error) {
 	var blockNumber int64
-	err := ecr.db.Get(&blockNumber, "SELECT block_number FROM eth.header_cids ORDER BY block_number ASC LIMIT 1")
+	err := ecr.db.Get(&blockNumber, fmt.Sprintf("SELECT block_number FROM %s.header_cids ORDER BY block_number ASC LIMIT 1", ecr.db.Schema))
 	return blockNumber, err
 }
 
 // RetrieveLastBlockNumber is used to retrieve the latest block number in the db
 func (ecr *GapRetriever) RetrieveLastBlockNumber() (int64, error) {
 	var blockNumber int64
-	err := ecr.db.Get(&blockNumber, "SELECT block_number FROM eth.header_cids ORDER BY block_number DESC LIMIT 1 ")
+	err := ecr.db.Get(&blockNumber, fmt.Sprintf("SELECT block_number FROM %s.header_cids ORDER BY block_number DESC LIMIT 1 ", ecr.db.Schema))
+	return blockNumber, err
+}
+
+// RetrieveOldestValidation returns the least recent time at which any indexed header was validated
+// it returns nil if no header has a recorded validation time
+func (ecr *GapRetriever) RetrieveOldestValidation() (*time.Time, error) {
+	var oldest *time.Time
+	err := ecr.db.Get(&oldest, fmt.Sprintf("SELECT min(last_validated_at) FROM %s.header_cids", ecr.db.Schema))
+	return oldest, err
+}
+
+// HighestContiguousBlock returns the highest block number below which no block is missing from the db, starting from genesis
+// it returns -1 if the genesis block has not been indexed yet
+// only missing blocks are considered, blocks below the validation level do not break the contiguous range
+func (ecr *GapRetriever) HighestContiguousBlock() (int64, error) {
+	first, err := ecr.RetrieveFirstBlockNumber()
+	if err == sql.ErrNoRows || (err == nil && first != 0) {
+		return -1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	// the lowest indexed block without a successor is the top of the contiguous range starting at genesis
+	pgStr := fmt.Sprintf(`SELECT min(header_cids.block_number) FROM %[1]s.header_cids
+			LEFT JOIN %[1]s.header_cids next ON (next.block_number = header_cids.block_number + 1)
+			WHERE next.block_number IS NULL`, ecr.db.Schema)
+	var blockNumber int64
+	err = ecr.db.Get(&blockNumber, pgStr)
 	return blockNumber, err
 }
 
@@ -65,7 +99,8 @@ type DBGap struct {
 }
 
 // RetrieveGapsInData is used to find the the block numbers at which we are missing data in the db
-// it finds the union of heights where no data exists and where the times_validated is lower than the validation level
+// it finds the union of heights where no data exists, where the times_validated is lower than the validation level
+// and, unless HeadersOnly is set, where only the header has been indexed so far
 func (ecr *GapRetriever) RetrieveGapsInData(validationLevel int) ([]DBGap, error) {
 	log.Info("searching for gaps in the eth ipfs watcher database")
 	startingBlock, err := ecr.RetrieveFirstBlockNumber()
@@ -82,26 +117,54 @@ func (ecr *GapRetriever) RetrieveGapsInData(validationLevel int) ([]DBGap, error
 		}}
 	}
 
-	pgStr := `SELECT header_cids.block_number + 1 AS start, min(fr.block_number) - 1 AS stop FROM eth.header_cids
-				LEFT JOIN eth.header_cids r on eth.header_cids.block_number = r.block_number - 1
-				LEFT JOIN eth.header_cids fr on eth.header_cids.block_number < fr.block_number
+	pgStr := fmt.Sprintf(`SELECT header_cids.block_number + 1 AS start, min(fr.block_number) - 1 AS stop FROM %[1]s.header_cids
+				LEFT JOIN %[1]s.header_cids r on %[1]s.header_cids.block_number = r.block_number - 1
+				LEFT JOIN %[1]s.header_cids fr on %[1]s.header_cids.block_number < fr.block_number
 				WHERE r.block_number is NULL and fr.block_number IS NOT NULL
-				GROUP BY header_cids.block_number, r.block_number`
+				GROUP BY header_cids.block_number, r.block_number`, ecr.db.Schema)
 	emptyGaps := make([]DBGap, 0)
 	if err := ecr.db.Select(&emptyGaps, pgStr); err != nil && err != sql.ErrNoRows {
 		return nil, err
 	}
 
-	// Find sections of blocks where we are below the validation level
+	// Find sections of blocks where we are below the validation level, or that only have their header indexed
 	// There will be no overlap between these "gaps" and the ones above
-	pgStr = `SELECT block_number FROM eth.header_cids
+	pgStr = fmt.Sprintf(`SELECT block_number FROM %[1]s.header_cids
 			WHERE times_validated < $1
-			ORDER BY block_number`
+			UNION SELECT block_number FROM %[1]s.gaps
+			WHERE NOT $2
+			ORDER BY block_number`, ecr.db.Schema)
 	var heights []uint64
-	if err := ecr.db.Select(&heights, pgStr, validationLevel); err != nil && err != sql.ErrNoRows {
+	if err := ecr.db.Select(&heights, pgStr, validationLevel, ecr.HeadersOnly); err != nil && err != sql.ErrNoRows {
 		return nil, err
 	}
-	return append(append(initialGap, emptyGaps...), MissingHeightsToGaps(heights)...), nil
+	// the missing data and validation level gaps can abut one another, merge them so that no range is split across workers
+	return MergeGaps(append(append(initialGap, emptyGaps...), MissingHeightsToGaps(heights)...)), nil
+}
+
+// MergeGaps returns the provided gaps sorted by their start height, with overlapping and adjacent gaps coalesced
+// into single non-overlapping gaps, so that no block height is covered more than once
+func MergeGaps(gaps []DBGap) []DBGap {
+	if len(gaps) == 0 {
+		return gaps
+	}
+	sorted := make([]DBGap, len(gaps))
+	copy(sorted, gaps)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+	merged := []DBGap{sorted[0]}
+	for _, gap := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if gap.Start <= last.Stop+1 {
+			if gap.Stop > last.Stop {
+				last.Stop = gap.Stop
+			}
+			continue
+		}
+		merged = append(merged, gap)
+	}
+	return merged
 }
 
 // MissingHeightsToGaps returns a slice of gaps from a slice of missing block heights
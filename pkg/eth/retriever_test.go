@@ -332,6 +332,80 @@ var _ = Describe("Retriever", func() {
 			Expect(ListContainsGap(gaps, eth.DBGap{Start: 1001, Stop: 1010100})).To(BeTrue())
 		})
 	})
+
+	Describe("HighestContiguousBlock", func() {
+		It("Returns -1 if there are no blocks in the database", func() {
+			blockNumber, err := retriever.HighestContiguousBlock()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockNumber).To(Equal(int64(-1)))
+		})
+
+		It("Returns -1 if the genesis block is missing", func() {
+			payload := mocks.MockConvertedPayload
+			payload.Block = mockBlock5
+			err := repo.Publish(payload)
+			Expect(err).ToNot(HaveOccurred())
+			blockNumber, err := retriever.HighestContiguousBlock()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockNumber).To(Equal(int64(-1)))
+		})
+
+		It("Returns the highest block below the first gap", func() {
+			payload0 := mocks.MockConvertedPayload
+			payload0.Block = mockBlock0
+			payload1 := mocks.MockConvertedPayload
+			payload3 := payload1
+			payload3.Block = mockBlock3
+			err := repo.Publish(payload0)
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Publish(payload1)
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Publish(payload3)
+			Expect(err).ToNot(HaveOccurred())
+			blockNumber, err := retriever.HighestContiguousBlock()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockNumber).To(Equal(int64(1)))
+		})
+
+		It("Returns the latest block if there are no gaps", func() {
+			payload0 := mocks.MockConvertedPayload
+			payload0.Block = mockBlock0
+			payload1 := mocks.MockConvertedPayload
+			payload2 := payload1
+			payload2.Block = mockBlock2
+			err := repo.Publish(payload0)
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Publish(payload1)
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Publish(payload2)
+			Expect(err).ToNot(HaveOccurred())
+			blockNumber, err := retriever.HighestContiguousBlock()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockNumber).To(Equal(int64(2)))
+		})
+	})
+})
+
+var _ = Describe("MergeGaps", func() {
+	It("Returns no gaps when given none", func() {
+		Expect(eth.MergeGaps(nil)).To(BeEmpty())
+	})
+
+	It("Sorts gaps and leaves disjoint gaps untouched", func() {
+		gaps := eth.MergeGaps([]eth.DBGap{{Start: 10, Stop: 20}, {Start: 0, Stop: 5}})
+		Expect(gaps).To(Equal([]eth.DBGap{{Start: 0, Stop: 5}, {Start: 10, Stop: 20}}))
+	})
+
+	It("Merges overlapping, contained and adjacent gaps", func() {
+		gaps := eth.MergeGaps([]eth.DBGap{
+			{Start: 5, Stop: 9},
+			{Start: 0, Stop: 4},
+			{Start: 7, Stop: 12},
+			{Start: 8, Stop: 10},
+			{Start: 20, Stop: 25},
+		})
+		Expect(gaps).To(Equal([]eth.DBGap{{Start: 0, Stop: 12}, {Start: 20, Stop: 25}}))
+	})
 })
 
 func newMockBlock(blockNumber uint64) *types.Block {
@@ -22,6 +22,39 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
+// RewardCalculator calculates the rewards indexed for blocks and their uncles, to support chains with other emission schedules
+type RewardCalculator interface {
+	BlockReward(header *types.Header, uncles []*types.Header, txs types.Transactions, receipts types.Receipts) *big.Int
+	UncleReward(blockNumber, uncleBlockNumber uint64) *big.Int
+}
+
+// EthRewardCalculator calculates the rewards of the ethereum mainnet emission schedule
+type EthRewardCalculator struct{}
+
+// BlockReward returns the static block reward, transaction fees and uncle inclusion rewards of the block
+func (EthRewardCalculator) BlockReward(header *types.Header, uncles []*types.Header, txs types.Transactions, receipts types.Receipts) *big.Int {
+	return CalcEthBlockReward(header, uncles, txs, receipts)
+}
+
+// UncleReward returns the reward of the miner of an uncle at uncleBlockNumber included in the block at blockNumber
+func (EthRewardCalculator) UncleReward(blockNumber, uncleBlockNumber uint64) *big.Int {
+	return CalcUncleMinerReward(blockNumber, uncleBlockNumber)
+}
+
+// FeeRewardCalculator calculates the rewards of a chain without block rewards, such as a proof-of-authority chain
+// the block reward is only the transaction fees, and uncles are not rewarded
+type FeeRewardCalculator struct{}
+
+// BlockReward returns the transaction fees of the block
+func (FeeRewardCalculator) BlockReward(header *types.Header, uncles []*types.Header, txs types.Transactions, receipts types.Receipts) *big.Int {
+	return calcEthTransactionFees(txs, receipts)
+}
+
+// UncleReward returns zero
+func (FeeRewardCalculator) UncleReward(blockNumber, uncleBlockNumber uint64) *big.Int {
+	return big.NewInt(0)
+}
+
 func CalcEthBlockReward(header *types.Header, uncles []*types.Header, txs types.Transactions, receipts types.Receipts) *big.Int {
 	staticBlockReward := staticRewardByBlockNumber(header.Number.Uint64())
 	transactionFees := calcEthTransactionFees(txs, receipts)
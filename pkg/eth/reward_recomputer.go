@@ -0,0 +1,190 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+// RewardRecomputer recalculates the rewards stored for already indexed blocks and their uncles,
+// so that rewards indexed with a wrong calculator can be corrected without reindexing
+type RewardRecomputer struct {
+	db         *postgres.DB
+	calculator RewardCalculator
+}
+
+// NewRewardRecomputer returns a pointer to a new RewardRecomputer
+// the EthRewardCalculator is used if the provided calculator is nil
+func NewRewardRecomputer(db *postgres.DB, calculator RewardCalculator) *RewardRecomputer {
+	if calculator == nil {
+		calculator = EthRewardCalculator{}
+	}
+	return &RewardRecomputer{
+		db:         db,
+		calculator: calculator,
+	}
+}
+
+// recomputeUncle holds the id of an indexed uncle and the IPLD of its header
+type recomputeUncle struct {
+	ID   int64  `db:"id"`
+	Data []byte `db:"data"`
+}
+
+// Recompute recalculates the reward of each header indexed in the provided range and of each of their uncles
+// from the header, uncle, transaction and receipt IPLDs, and updates the indexed rows in place
+// each header is updated in its own transaction, it returns the number of headers updated
+func (rr *RewardRecomputer) Recompute(start, stop uint64) (int, error) {
+	if stop < start {
+		return 0, fmt.Errorf("ethereum reward recomputation range ending block number needs to be greater than the starting block number")
+	}
+	headerIDs := make([]int64, 0)
+	pgStr := fmt.Sprintf(`SELECT id FROM %s.header_cids WHERE block_number BETWEEN $1 AND $2 ORDER BY block_number, id`, rr.db.Schema)
+	if err := rr.db.Select(&headerIDs, pgStr, start, stop); err != nil {
+		return 0, err
+	}
+	for i, headerID := range headerIDs {
+		if err := rr.recompute(headerID); err != nil {
+			return i, fmt.Errorf("ethereum reward recomputation error for header %d: %v", headerID, err)
+		}
+	}
+	return len(headerIDs), nil
+}
+
+// recompute recalculates and updates the rewards of a single header and its uncles
+func (rr *RewardRecomputer) recompute(headerID int64) (err error) {
+	tx, err := rr.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	var headerData []byte
+	pgStr := fmt.Sprintf(`SELECT data FROM %s.header_cids
+			INNER JOIN public.blocks ON (header_cids.mh_key = blocks.key)
+			WHERE header_cids.id = $1`, rr.db.Schema)
+	if err := tx.Get(&headerData, pgStr, headerID); err != nil {
+		return err
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(headerData, header); err != nil {
+		return err
+	}
+
+	uncleRows := make([]recomputeUncle, 0)
+	pgStr = fmt.Sprintf(`SELECT uncle_cids.id, data FROM %s.uncle_cids
+			INNER JOIN public.blocks ON (uncle_cids.mh_key = blocks.key)
+			WHERE uncle_cids.header_id = $1 ORDER BY uncle_cids.id`, rr.db.Schema)
+	if err := tx.Select(&uncleRows, pgStr, headerID); err != nil {
+		return err
+	}
+	uncles := make([]*types.Header, len(uncleRows))
+	for i, row := range uncleRows {
+		uncles[i] = new(types.Header)
+		if err := rlp.DecodeBytes(row.Data, uncles[i]); err != nil {
+			return err
+		}
+	}
+
+	txs, err := rr.transactions(tx, headerID)
+	if err != nil {
+		return err
+	}
+	receipts, err := rr.receipts(tx, headerID)
+	if err != nil {
+		return err
+	}
+	if len(txs) != len(receipts) {
+		return fmt.Errorf("expected number of transactions (%d) to be equal to the number of receipts (%d)", len(txs), len(receipts))
+	}
+
+	// the genesis block has no reward
+	reward := big.NewInt(0)
+	if header.Number.Uint64() != 0 {
+		reward = rr.calculator.BlockReward(header, uncles, txs, receipts)
+	}
+	pgStr = fmt.Sprintf(`UPDATE %s.header_cids SET reward = $1 WHERE id = $2`, rr.db.Schema)
+	if _, err := tx.Exec(pgStr, reward.String(), headerID); err != nil {
+		return err
+	}
+	pgStr = fmt.Sprintf(`UPDATE %s.uncle_cids SET reward = $1 WHERE id = $2`, rr.db.Schema)
+	for i, row := range uncleRows {
+		uncleReward := rr.calculator.UncleReward(header.Number.Uint64(), uncles[i].Number.Uint64())
+		if _, err := tx.Exec(pgStr, uncleReward.String(), row.ID); err != nil {
+			return err
+		}
+	}
+	logrus.Debugf("recomputed the rewards of block %d (%s) and its %d uncles", header.Number.Uint64(), header.Hash().Hex(), len(uncles))
+	return nil
+}
+
+// transactions returns the transactions of a header decoded from their IPLDs, in block order
+func (rr *RewardRecomputer) transactions(tx *sqlx.Tx, headerID int64) (types.Transactions, error) {
+	txData := make([][]byte, 0)
+	pgStr := fmt.Sprintf(`SELECT data FROM %s.transaction_cids
+			INNER JOIN public.blocks ON (transaction_cids.mh_key = blocks.key)
+			WHERE transaction_cids.header_id = $1 ORDER BY transaction_cids.index`, rr.db.Schema)
+	if err := tx.Select(&txData, pgStr, headerID); err != nil {
+		return nil, err
+	}
+	txs := make(types.Transactions, len(txData))
+	for i, data := range txData {
+		txs[i] = new(types.Transaction)
+		if err := rlp.DecodeBytes(data, txs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return txs, nil
+}
+
+// receipts returns the receipts of a header decoded from their IPLDs, in the order of their transactions
+func (rr *RewardRecomputer) receipts(tx *sqlx.Tx, headerID int64) (types.Receipts, error) {
+	rctData := make([][]byte, 0)
+	pgStr := fmt.Sprintf(`SELECT data FROM %[1]s.receipt_cids
+			INNER JOIN %[1]s.transaction_cids ON (receipt_cids.tx_id = transaction_cids.id)
+			INNER JOIN public.blocks ON (receipt_cids.mh_key = blocks.key)
+			WHERE transaction_cids.header_id = $1 ORDER BY transaction_cids.index`, rr.db.Schema)
+	if err := tx.Select(&rctData, pgStr, headerID); err != nil {
+		return nil, err
+	}
+	receipts := make(types.Receipts, len(rctData))
+	for i, data := range rctData {
+		receipts[i] = new(types.Receipt)
+		if err := rlp.DecodeBytes(data, receipts[i]); err != nil {
+			return nil, err
+		}
+	}
+	return receipts, nil
+}
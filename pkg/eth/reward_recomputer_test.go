@@ -0,0 +1,79 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("RewardRecomputer", func() {
+	var (
+		db  *postgres.DB
+		err error
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		transformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+		_, err = transformer.Transform(1, mocks.MockStateDiffPayload)
+		Expect(err).ToNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	It("Recomputes the rewards of the indexed headers in place", func() {
+		header := mocks.MockBlock.Header()
+		txs := mocks.MockBlock.Transactions()
+		updated, err := eth.NewRewardRecomputer(db, eth.FeeRewardCalculator{}).Recompute(0, 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(updated).To(Equal(1))
+		var reward string
+		err = db.Get(&reward, `SELECT reward FROM eth.header_cids WHERE block_number = $1`, mocks.BlockNumber.Uint64())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reward).To(Equal(eth.FeeRewardCalculator{}.BlockReward(header, nil, txs, mocks.MockReceipts).String()))
+
+		updated, err = eth.NewRewardRecomputer(db, nil).Recompute(0, 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(updated).To(Equal(1))
+		err = db.Get(&reward, `SELECT reward FROM eth.header_cids WHERE block_number = $1`, mocks.BlockNumber.Uint64())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reward).To(Equal(eth.CalcEthBlockReward(header, nil, txs, mocks.MockReceipts).String()))
+	})
+
+	It("Leaves headers outside of the range untouched", func() {
+		updated, err := eth.NewRewardRecomputer(db, eth.FeeRewardCalculator{}).Recompute(2, 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(updated).To(Equal(0))
+		var reward string
+		err = db.Get(&reward, `SELECT reward FROM eth.header_cids WHERE block_number = $1`, mocks.BlockNumber.Uint64())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reward).To(Equal(eth.CalcEthBlockReward(mocks.MockBlock.Header(), nil, mocks.MockBlock.Transactions(), mocks.MockReceipts).String()))
+	})
+
+	It("Returns an error if the range is inverted", func() {
+		_, err := eth.NewRewardRecomputer(db, nil).Recompute(10, 0)
+		Expect(err).To(HaveOccurred())
+	})
+})
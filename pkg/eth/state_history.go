@@ -0,0 +1,59 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+)
+
+// StateHistoryRetriever looks up how indexed accounts have changed over time
+type StateHistoryRetriever struct {
+	db *postgres.DB
+}
+
+// NewStateHistoryRetriever returns a pointer to a new StateHistoryRetriever
+func NewStateHistoryRetriever(db *postgres.DB) *StateHistoryRetriever {
+	return &StateHistoryRetriever{
+		db: db,
+	}
+}
+
+// StateDiffHistory returns the state of the account at each block in the inclusive range in which it was changed,
+// ordered by block number, with the BlockNumber of each record set
+// only blocks whose state diff includes the account have a record, and a height with more than one indexed header
+// (e.g. during a reorg) can have more than one record
+func (sr *StateHistoryRetriever) StateDiffHistory(addr common.Address, fromBlock, toBlock int64) ([]StateAccountModel, error) {
+	if toBlock < fromBlock {
+		return nil, fmt.Errorf("ethereum state history range ending block number needs to be greater than the starting block number")
+	}
+	leafKey := crypto.Keccak256Hash(addr.Bytes()).String()
+	pgStr := fmt.Sprintf(`SELECT state_accounts.id, state_accounts.state_id, state_accounts.balance, state_accounts.nonce,
+				state_accounts.code_hash, state_accounts.storage_root, header_cids.block_number
+				FROM %[1]s.state_accounts
+				INNER JOIN %[1]s.state_cids ON (state_accounts.state_id = state_cids.id)
+				INNER JOIN %[1]s.header_cids ON (state_cids.header_id = header_cids.id)
+				WHERE state_cids.state_leaf_key = $1
+				AND header_cids.block_number BETWEEN $2 AND $3
+				ORDER BY header_cids.block_number`, sr.db.Schema)
+	accounts := make([]StateAccountModel, 0)
+	return accounts, sr.db.Select(&accounts, pgStr, leafKey, fromBlock, toBlock)
+}
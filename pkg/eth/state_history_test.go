@@ -0,0 +1,74 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("StateHistoryRetriever", func() {
+	var (
+		db        *postgres.DB
+		err       error
+		retriever *eth.StateHistoryRetriever
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		transformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+		_, err = transformer.Transform(1, mocks.MockStateDiffPayload)
+		Expect(err).ToNot(HaveOccurred())
+		retriever = eth.NewStateHistoryRetriever(db)
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	Describe("StateDiffHistory", func() {
+		It("Returns the account at each block in the range it was changed in", func() {
+			history, err := retriever.StateDiffHistory(mocks.AccountAddresss, 0, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(history)).To(Equal(1))
+			Expect(history[0].BlockNumber).To(Equal(mocks.BlockNumber.String()))
+			history, err = retriever.StateDiffHistory(mocks.ContractAddress, 0, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(history)).To(Equal(1))
+		})
+
+		It("Returns no records outside of the range or for accounts that weren't changed", func() {
+			history, err := retriever.StateDiffHistory(mocks.AccountAddresss, 2, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(history).To(BeEmpty())
+			history, err = retriever.StateDiffHistory(common.HexToAddress("0x01"), 0, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(history).To(BeEmpty())
+		})
+
+		It("Rejects an inverted range", func() {
+			_, err := retriever.StateDiffHistory(mocks.AccountAddresss, 10, 0)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,102 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+// stateLeafFetchSize is the number of rows fetched from the cursor at a time
+const stateLeafFetchSize = 1000
+
+// leafNodeType is the node_type of a state leaf, see ResolveFromNodeType
+const leafNodeType = 2
+
+// StateLeafIterator streams the state leaves indexed at a block, e.g. for snapshot export
+type StateLeafIterator struct {
+	db        *postgres.DB
+	fetchSize int
+}
+
+// NewStateLeafIterator returns a pointer to a new StateLeafIterator
+func NewStateLeafIterator(db *postgres.DB) *StateLeafIterator {
+	return &StateLeafIterator{
+		db:        db,
+		fetchSize: stateLeafFetchSize,
+	}
+}
+
+// IterateStateLeaves calls fn with each leaf state node indexed at the block and its account, ordered by header and path
+// the rows are read through a server-side cursor in batches so the block's leaves are never all held in memory
+// iteration stops at the first error returned by fn, which is returned as is
+func (si *StateLeafIterator) IterateStateLeaves(blockNumber int64, fn func(StateNodeModel, StateAccountModel) error) error {
+	tx, err := si.db.Beginx()
+	if err != nil {
+		return err
+	}
+	// the cursor only reads, so the transaction is always rolled back
+	defer shared.Rollback(tx)
+
+	pgStr := fmt.Sprintf(`DECLARE state_leaves NO SCROLL CURSOR FOR
+				SELECT state_cids.id, state_cids.header_id, state_cids.state_path, state_cids.state_leaf_key,
+				state_cids.node_type, state_cids.cid, state_cids.mh_key, state_cids.diff,
+				state_accounts.id, state_accounts.balance, state_accounts.nonce, state_accounts.code_hash,
+				state_accounts.storage_root, header_cids.block_number
+				FROM %[1]s.state_cids
+				INNER JOIN %[1]s.state_accounts ON (state_cids.id = state_accounts.state_id)
+				INNER JOIN %[1]s.header_cids ON (state_cids.header_id = header_cids.id)
+				WHERE header_cids.block_number = $1
+				AND state_cids.node_type = $2
+				ORDER BY state_cids.header_id, state_cids.state_path`, si.db.Schema)
+	if _, err := tx.Exec(pgStr, blockNumber, leafNodeType); err != nil {
+		return fmt.Errorf("unable to declare state leaf cursor for block %d: %v", blockNumber, err)
+	}
+	fetchStr := fmt.Sprintf(`FETCH FORWARD %d FROM state_leaves`, si.fetchSize)
+	for {
+		rows, err := tx.Query(fetchStr)
+		if err != nil {
+			return fmt.Errorf("unable to fetch state leaves for block %d: %v", blockNumber, err)
+		}
+		fetched := 0
+		for rows.Next() {
+			var node StateNodeModel
+			var account StateAccountModel
+			if err := rows.Scan(&node.ID, &node.HeaderID, &node.Path, &node.StateKey, &node.NodeType, &node.CID,
+				&node.MhKey, &node.Diff, &account.ID, &account.Balance, &account.Nonce, &account.CodeHash,
+				&account.StorageRoot, &account.BlockNumber); err != nil {
+				rows.Close()
+				return err
+			}
+			account.StateID = node.ID
+			fetched++
+			if err := fn(node, account); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+		if fetched < si.fetchSize {
+			return nil
+		}
+	}
+}
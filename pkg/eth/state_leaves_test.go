@@ -0,0 +1,84 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("StateLeafIterator", func() {
+	var (
+		db       *postgres.DB
+		err      error
+		iterator *eth.StateLeafIterator
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		transformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+		_, err = transformer.Transform(1, mocks.MockStateDiffPayload)
+		Expect(err).ToNot(HaveOccurred())
+		iterator = eth.NewStateLeafIterator(db)
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	Describe("IterateStateLeaves", func() {
+		It("Streams each leaf indexed at the block with its account", func() {
+			leafKeys := make([]string, 0)
+			err := iterator.IterateStateLeaves(mocks.BlockNumber.Int64(), func(node eth.StateNodeModel, account eth.StateAccountModel) error {
+				Expect(node.NodeType).To(Equal(2))
+				Expect(account.StateID).To(Equal(node.ID))
+				Expect(account.BlockNumber).To(Equal(mocks.BlockNumber.String()))
+				leafKeys = append(leafKeys, node.StateKey)
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(leafKeys).To(ConsistOf(common.BytesToHash(mocks.AccountLeafKey).Hex(), common.BytesToHash(mocks.ContractLeafKey).Hex()))
+		})
+
+		It("Doesn't call fn for a block without indexed leaves", func() {
+			err := iterator.IterateStateLeaves(2, func(eth.StateNodeModel, eth.StateAccountModel) error {
+				Fail("unexpected state leaf")
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Stops at and returns the first error from fn", func() {
+			stop := errors.New("stop")
+			calls := 0
+			err := iterator.IterateStateLeaves(mocks.BlockNumber.Int64(), func(eth.StateNodeModel, eth.StateAccountModel) error {
+				calls++
+				return stop
+			})
+			Expect(err).To(Equal(stop))
+			Expect(calls).To(Equal(1))
+		})
+	})
+})
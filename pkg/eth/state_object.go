@@ -0,0 +1,109 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/statediff"
+)
+
+// StateObjectDecoder decodes the rlp encoded state object of a statediff payload
+type StateObjectDecoder func(stateObjectRlp []byte) (*statediff.StateObject, error)
+
+// statediff payloads carry no explicit version, so a state object layout is identified by its number of top-level rlp fields
+const (
+	// StateObjectFields is the number of fields in the current layout: block number, block hash and state nodes
+	StateObjectFields = 3
+	// StateObjectWithCodeFields is the number of fields in the layout that also carries contract code and code hashes
+	StateObjectWithCodeFields = 4
+)
+
+// defaultStateObjectDecoders returns the decoders for the state object layouts supported out of the box
+func defaultStateObjectDecoders() map[int]StateObjectDecoder {
+	return map[int]StateObjectDecoder{
+		StateObjectFields:         decodeStateObject,
+		StateObjectWithCodeFields: decodeStateObjectWithCode,
+	}
+}
+
+// RegisterStateObjectDecoder sets the decoder used for state objects with the provided number of top-level fields
+// it overrides any decoder already registered for that layout
+func (sdt *StateDiffTransformer) RegisterStateObjectDecoder(fields int, decoder StateObjectDecoder) {
+	sdt.stateObjectDecoders[fields] = decoder
+}
+
+// decodeStateObject detects the layout of the state object rlp and decodes it with the matching decoder
+func (sdt *StateDiffTransformer) decodeStateObject(stateObjectRlp []byte) (*statediff.StateObject, error) {
+	return decodeStateObjectWith(sdt.stateObjectDecoders, stateObjectRlp)
+}
+
+// decodeStateObjectWith detects the layout of the state object rlp and decodes it with the matching decoder from the provided set
+func decodeStateObjectWith(decoders map[int]StateObjectDecoder, stateObjectRlp []byte) (*statediff.StateObject, error) {
+	content, _, err := rlp.SplitList(stateObjectRlp)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecodeStateObject, err)
+	}
+	fields, err := rlp.CountValues(content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecodeStateObject, err)
+	}
+	decoder, ok := decoders[fields]
+	if !ok {
+		return nil, fmt.Errorf("%w: state object has %d fields", ErrUnrecognizedStateObject, fields)
+	}
+	stateDiff, err := decoder(stateObjectRlp)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecodeStateObject, err)
+	}
+	return stateDiff, nil
+}
+
+// decodeStateObject decodes the current state object layout
+func decodeStateObject(stateObjectRlp []byte) (*statediff.StateObject, error) {
+	stateDiff := new(statediff.StateObject)
+	return stateDiff, rlp.DecodeBytes(stateObjectRlp, stateDiff)
+}
+
+// stateObjectWithCode is the state object layout that also carries the code of the contracts in the diff
+type stateObjectWithCode struct {
+	BlockNumber       *big.Int
+	BlockHash         common.Hash
+	Nodes             []statediff.StateNode
+	CodeAndCodeHashes []codeAndCodeHash
+}
+
+type codeAndCodeHash struct {
+	Hash common.Hash
+	Code []byte
+}
+
+// decodeStateObjectWithCode decodes the layout carrying contract code, the code is not indexed and is dropped
+func decodeStateObjectWithCode(stateObjectRlp []byte) (*statediff.StateObject, error) {
+	withCode := new(stateObjectWithCode)
+	if err := rlp.DecodeBytes(stateObjectRlp, withCode); err != nil {
+		return nil, err
+	}
+	return &statediff.StateObject{
+		BlockNumber: withCode.BlockNumber,
+		BlockHash:   withCode.BlockHash,
+		Nodes:       withCode.Nodes,
+	}, nil
+}
@@ -0,0 +1,152 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/ipfs/ipld"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+// StateProofRetriever builds merkle proofs of accounts from the indexed state trie nodes
+type StateProofRetriever struct {
+	db *postgres.DB
+}
+
+// NewStateProofRetriever returns a pointer to a new StateProofRetriever
+func NewStateProofRetriever(db *postgres.DB) *StateProofRetriever {
+	return &StateProofRetriever{
+		db: db,
+	}
+}
+
+// GetStateProof returns the rlp encoded state trie nodes on the path from the state root of the block to the account's
+// leaf, in the same form as eth_getProof's accountProof, so that the account can be verified against the header's state root
+// if the account does not exist at the block the nodes prove its absence instead; the nodes are looked up by their hash,
+// so this relies on the state trie nodes being published under the default keccak256 multihash
+// ErrHeaderNotIndexed is returned if the block has not been indexed, and ErrProofNodeMissing if any node on the path has not
+func (pr *StateProofRetriever) GetStateProof(blockHash common.Hash, addr common.Address) ([][]byte, error) {
+	var stateRoot string
+	pgStr := fmt.Sprintf(`SELECT state_root FROM %s.header_cids WHERE block_hash = $1 LIMIT 1`, pr.db.Schema)
+	if err := pr.db.Get(&stateRoot, pgStr, blockHash.String()); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrHeaderNotIndexed, blockHash.String())
+		}
+		return nil, err
+	}
+	proof := make([][]byte, 0)
+	root := common.HexToHash(stateRoot)
+	if root == emptyStorageRoot {
+		return proof, nil
+	}
+	key := keyToNibbles(crypto.Keccak256(addr.Bytes()))
+	var path []byte
+	nodeRLP, err := pr.stateNode(root, path)
+	if err != nil {
+		return nil, err
+	}
+	proof = append(proof, nodeRLP)
+	var elements []interface{}
+	if err := rlp.DecodeBytes(nodeRLP, &elements); err != nil {
+		return nil, err
+	}
+	for {
+		var child interface{}
+		switch len(elements) {
+		case 17:
+			// the account keys are all the same length, so a branch never holds the value of one
+			if len(key) == 0 {
+				return proof, nil
+			}
+			child = elements[key[0]]
+			path, key = append(path, key[0]), key[1:]
+		case 2:
+			compactKey, ok := elements[0].([]byte)
+			if !ok || len(compactKey) == 0 {
+				return nil, fmt.Errorf("unable to decode state trie node partial path at path %x", path)
+			}
+			nibbles := compactToNibbles(compactKey)
+			// a leaf ends the path, whether it is the account's or proves its absence, as does an extension the key diverges from
+			if compactKey[0]>>4 >= 2 || !bytes.HasPrefix(key, nibbles) {
+				return proof, nil
+			}
+			child = elements[1]
+			path, key = append(path, nibbles...), key[len(nibbles):]
+		default:
+			return nil, fmt.Errorf("unexpected number of elements (%d) in state trie node at path %x", len(elements), path)
+		}
+		switch c := child.(type) {
+		case []byte:
+			// an empty child proves the account's absence
+			if len(c) == 0 {
+				return proof, nil
+			}
+			if len(c) != common.HashLength {
+				return nil, fmt.Errorf("unexpected state trie node reference of %d bytes at path %x", len(c), path)
+			}
+			if nodeRLP, err = pr.stateNode(common.BytesToHash(c), path); err != nil {
+				return nil, err
+			}
+			proof = append(proof, nodeRLP)
+			elements = nil
+			if err := rlp.DecodeBytes(nodeRLP, &elements); err != nil {
+				return nil, err
+			}
+		case []interface{}:
+			// nodes shorter than a hash are embedded in their parent, which is already part of the proof
+			elements = c
+		default:
+			return nil, fmt.Errorf("unable to decode state trie node child at path %x", path)
+		}
+	}
+}
+
+// stateNode returns the rlp encoded state trie node with the provided hash, found at the provided path
+func (pr *StateProofRetriever) stateNode(hash common.Hash, path []byte) ([]byte, error) {
+	mh, err := multihash.Encode(hash.Bytes(), multihash.KECCAK_256)
+	if err != nil {
+		return nil, err
+	}
+	var nodeRLP []byte
+	mhKey := shared.MultihashKeyFromCID(cid.NewCidV1(ipld.MEthStateTrie, mh))
+	if err := pr.db.Get(&nodeRLP, `SELECT data FROM public.blocks WHERE key = $1`, mhKey); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: node %s at path %x", ErrProofNodeMissing, hash.String(), path)
+		}
+		return nil, err
+	}
+	return nodeRLP, nil
+}
+
+// keyToNibbles unpacks key bytes into their nibbles, the inverse of nibblesToKey
+func keyToNibbles(key []byte) []byte {
+	nibbles := make([]byte, len(key)*2)
+	for i, b := range key {
+		nibbles[i*2], nibbles[i*2+1] = b>>4, b&0x0f
+	}
+	return nibbles
+}
@@ -0,0 +1,127 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+// proofList collects the nodes of a trie proof in the order they are written
+type proofList [][]byte
+
+func (l *proofList) Put(key []byte, value []byte) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func (l *proofList) Delete(key []byte) error {
+	return errors.New("proof list does not support deletes")
+}
+
+var _ = Describe("StateProofRetriever", func() {
+	var (
+		db          *postgres.DB
+		err         error
+		retriever   *eth.StateProofRetriever
+		addrs       []common.Address
+		genesisHash common.Hash
+		stateTrie   *trie.Trie
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		// enough accounts for the state trie to have branches several levels deep
+		alloc := core.GenesisAlloc{}
+		addrs = make([]common.Address, 0, 64)
+		for i := 1; i <= 64; i++ {
+			addr := common.BigToAddress(big.NewInt(int64(i)))
+			alloc[addr] = core.GenesisAccount{Balance: big.NewInt(int64(i))}
+			addrs = append(addrs, addr)
+		}
+		genesis := &core.Genesis{
+			Config:     params.MainnetChainConfig,
+			Difficulty: big.NewInt(1),
+			Alloc:      alloc,
+		}
+		_, err = eth.NewStateDiffTransformer(params.MainnetChainConfig, db).IndexGenesis(genesis)
+		Expect(err).ToNot(HaveOccurred())
+		memDB := rawdb.NewMemoryDatabase()
+		block := genesis.ToBlock(memDB)
+		genesisHash = block.Hash()
+		stateTrie, err = trie.New(block.Root(), trie.NewDatabase(memDB))
+		Expect(err).ToNot(HaveOccurred())
+		retriever = eth.NewStateProofRetriever(db)
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	expectedProof := func(addr common.Address) [][]byte {
+		proof := make(proofList, 0)
+		err := stateTrie.Prove(crypto.Keccak256(addr.Bytes()), 0, &proof)
+		Expect(err).ToNot(HaveOccurred())
+		return proof
+	}
+
+	It("Returns the same proof as the state trie for each account", func() {
+		for _, addr := range addrs {
+			proof, err := retriever.GetStateProof(genesisHash, addr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(proof)).To(BeNumerically(">", 1))
+			Expect(proof).To(Equal(expectedProof(addr)))
+		}
+	})
+
+	It("Returns the proof of absence of an account that is not in the state", func() {
+		addr := common.HexToAddress("0x0D3ab14BBaD3D99F4203bd7a11aCB94882050E7e")
+		proof, err := retriever.GetStateProof(genesisHash, addr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(proof).To(Equal(expectedProof(addr)))
+	})
+
+	It("Returns ErrProofNodeMissing if a node on the path has not been published", func() {
+		proof, err := retriever.GetStateProof(genesisHash, addrs[0])
+		Expect(err).ToNot(HaveOccurred())
+		leafKey, err := shared.MultihashKeyFromKeccak256(proof[len(proof)-1])
+		Expect(err).ToNot(HaveOccurred())
+		_, err = db.Exec(`DELETE FROM public.blocks WHERE key = $1`, leafKey)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = retriever.GetStateProof(genesisHash, addrs[0])
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, eth.ErrProofNodeMissing)).To(BeTrue())
+	})
+
+	It("Returns ErrHeaderNotIndexed for a block that has not been indexed", func() {
+		_, err := retriever.GetStateProof(common.HexToHash("0x01"), addrs[0])
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, eth.ErrHeaderNotIndexed)).To(BeTrue())
+	})
+})
@@ -0,0 +1,107 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+// StateReindexer re-indexes only the state and storage nodes of already indexed blocks, leaving their headers, uncles,
+// transactions and receipts untouched, e.g. to pick up a fix to how state or storage is processed without a full resync
+type StateReindexer struct {
+	transformer *StateDiffTransformer
+	fetcher     Fetcher
+}
+
+// NewStateReindexer returns a pointer to a new StateReindexer
+// the state diffs are refetched with the provided fetcher, as they are not retained once a block has been indexed,
+// and processed with the options of the provided transformer
+func NewStateReindexer(transformer *StateDiffTransformer, fetcher Fetcher) *StateReindexer {
+	return &StateReindexer{
+		transformer: transformer,
+		fetcher:     fetcher,
+	}
+}
+
+// ReindexState refetches the state diff of the block at the provided height and replaces the state and storage nodes
+// indexed for its header with those of the diff, in a single Postgres tx
+// the block's header must already be indexed, and match the block the state diff is fetched for
+// the IPLDs of the replaced nodes are left in public.blocks, as they can be referenced by other blocks
+func (sr *StateReindexer) ReindexState(blockNumber int64) (err error) {
+	sdt := sr.transformer
+	payloads, err := sr.fetcher.FetchAt([]uint64{uint64(blockNumber)})
+	if err != nil {
+		return fmt.Errorf("ethereum state reindex error fetching the state diff at height %d: %v", blockNumber, err)
+	}
+	if len(payloads) != 1 {
+		return fmt.Errorf("ethereum state reindex expected one payload at height %d, got %d", blockNumber, len(payloads))
+	}
+	decoded, err := decodePayload(sdt.chainConfig, sdt.stateObjectDecoders, sdt.MaxPayloadBytes, payloads[0])
+	if err != nil {
+		return err
+	}
+	blockHash := decoded.block.Hash().String()
+
+	tx, err := sdt.indexer.db.Beginx()
+	if err != nil {
+		return err
+	}
+	var publishedKeys []string
+	writes := sdt.newBlockstoreWrites()
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+			if err == nil {
+				err = sdt.flushPublished(publishedKeys, writes, uint64(blockNumber))
+			}
+		}
+	}()
+
+	var headerID int64
+	pgStr := fmt.Sprintf(`SELECT id FROM %s.header_cids WHERE block_number = $1 AND block_hash = $2`, sdt.indexer.db.Schema)
+	if err := tx.Get(&headerID, pgStr, blockNumber, blockHash); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("ethereum state reindex error: block %d with hash %s is not indexed", blockNumber, blockHash)
+		}
+		return err
+	}
+	// the storage nodes and accounts are removed along with their state nodes by the cascading FKs
+	pgStr = fmt.Sprintf(`DELETE FROM %s.state_cids WHERE header_id = $1`, sdt.indexer.db.Schema)
+	if _, err := tx.Exec(pgStr, headerID); err != nil {
+		return err
+	}
+	stateDiff := *decoded.stateDiff
+	stateDiff.Nodes = sdt.selectStateNodes(uint64(blockNumber), stateDiff.Nodes)
+	var deduped dedupedWrites
+	publishedKeys, deduped, err = sdt.processStateAndStorage(tx, headerID, &stateDiff, nil, nil, writes)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("reindexed the %d state nodes of block %d with hash %s, %d already published IPLDs (%d bytes) were skipped",
+		len(stateDiff.Nodes), blockNumber, blockHash, deduped.nodes, deduped.bytes)
+	return nil
+}
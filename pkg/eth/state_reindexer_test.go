@@ -0,0 +1,90 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/statediff"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("StateReindexer", func() {
+	var (
+		db      *postgres.DB
+		err     error
+		fetcher *mocks.PayloadFetcher
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		fetcher = &mocks.PayloadFetcher{
+			PayloadsToReturn: map[uint64]statediff.Payload{
+				1: mocks.MockStateDiffPayload,
+			},
+		}
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	Describe("ReindexState", func() {
+		It("Replaces the state and storage nodes of an indexed block without touching the rest of it", func() {
+			_, err = eth.NewStateDiffTransformer(params.MainnetChainConfig, db).Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			var txIDs []int64
+			err = db.Select(&txIDs, `SELECT id FROM eth.transaction_cids ORDER BY id`)
+			Expect(err).ToNot(HaveOccurred())
+			var stateCount, storageCount int
+			err = db.Get(&stateCount, `SELECT COUNT(*) FROM eth.state_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			err = db.Get(&storageCount, `SELECT COUNT(*) FROM eth.storage_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(storageCount).ToNot(BeZero())
+			_, err = db.Exec(`DELETE FROM eth.storage_cids`)
+			Expect(err).ToNot(HaveOccurred())
+
+			reindexer := eth.NewStateReindexer(eth.NewStateDiffTransformer(params.MainnetChainConfig, db), fetcher)
+			err = reindexer.ReindexState(1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fetcher.CalledAtBlockHeights).To(Equal([][]uint64{{1}}))
+			var reindexedStateCount, reindexedStorageCount int
+			err = db.Get(&reindexedStateCount, `SELECT COUNT(*) FROM eth.state_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reindexedStateCount).To(Equal(stateCount))
+			err = db.Get(&reindexedStorageCount, `SELECT COUNT(*) FROM eth.storage_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reindexedStorageCount).To(Equal(storageCount))
+			var reindexedTxIDs []int64
+			err = db.Select(&reindexedTxIDs, `SELECT id FROM eth.transaction_cids ORDER BY id`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reindexedTxIDs).To(Equal(txIDs))
+		})
+
+		It("Fails for a block whose header has not been indexed", func() {
+			reindexer := eth.NewStateReindexer(eth.NewStateDiffTransformer(params.MainnetChainConfig, db), fetcher)
+			err = reindexer.ReindexState(1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("is not indexed"))
+		})
+	})
+})
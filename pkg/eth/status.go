@@ -0,0 +1,94 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+)
+
+// IndexStatus is a snapshot of what has been indexed, the block numbers are -1 while nothing has been indexed
+type IndexStatus struct {
+	LowestBlock            int64        `json:"lowestBlock"`
+	HighestBlock           int64        `json:"highestBlock"`
+	HighestContiguousBlock int64        `json:"highestContiguousBlock"`
+	Headers                int64        `json:"headers"`
+	Gaps                   int          `json:"gaps"`
+	GapBlocks              uint64       `json:"gapBlocks"`
+	Lag                    *IndexingLag `json:"lag,omitempty"`
+}
+
+// StatusReporter collects an IndexStatus from the gap and lag queries used by the backfill process
+type StatusReporter struct {
+	db         *postgres.DB
+	retriever  *GapRetriever
+	lagTracker *LagTracker
+}
+
+// NewStatusReporter returns a new StatusReporter, the lag is only reported if a LagTracker is provided
+func NewStatusReporter(db *postgres.DB, lagTracker *LagTracker) *StatusReporter {
+	return &StatusReporter{
+		db:         db,
+		retriever:  NewGapRetriever(db),
+		lagTracker: lagTracker,
+	}
+}
+
+// Status returns the current IndexStatus, counting the blocks below the validation level as gaps as the backfill does
+func (sr *StatusReporter) Status(validationLevel int) (IndexStatus, error) {
+	status := IndexStatus{
+		LowestBlock:            -1,
+		HighestBlock:           -1,
+		HighestContiguousBlock: -1,
+	}
+	first, err := sr.retriever.RetrieveFirstBlockNumber()
+	if err == sql.ErrNoRows {
+		return status, nil
+	}
+	if err != nil {
+		return IndexStatus{}, err
+	}
+	status.LowestBlock = first
+	// the lag can only be measured once something has been indexed
+	if sr.lagTracker != nil {
+		lag, err := sr.lagTracker.Update()
+		if err != nil {
+			return IndexStatus{}, err
+		}
+		status.Lag = &lag
+	}
+	if status.HighestBlock, err = sr.retriever.RetrieveLastBlockNumber(); err != nil {
+		return IndexStatus{}, err
+	}
+	if status.HighestContiguousBlock, err = sr.retriever.HighestContiguousBlock(); err != nil {
+		return IndexStatus{}, err
+	}
+	if err := sr.db.Get(&status.Headers, fmt.Sprintf(`SELECT COUNT(*) FROM %s.header_cids`, sr.db.Schema)); err != nil {
+		return IndexStatus{}, err
+	}
+	gaps, err := sr.retriever.RetrieveGapsInData(validationLevel)
+	if err != nil {
+		return IndexStatus{}, err
+	}
+	status.Gaps = len(gaps)
+	for _, gap := range gaps {
+		status.GapBlocks += gap.Stop - gap.Start + 1
+	}
+	return status, nil
+}
@@ -0,0 +1,72 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("StatusReporter", func() {
+	var (
+		db  *postgres.DB
+		err error
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	Describe("Status", func() {
+		It("Reports no blocks when nothing has been indexed", func() {
+			status, err := eth.NewStatusReporter(db, nil).Status(1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status).To(Equal(eth.IndexStatus{LowestBlock: -1, HighestBlock: -1, HighestContiguousBlock: -1}))
+		})
+
+		It("Summarizes the indexed blocks, gaps and lag", func() {
+			transformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			_, err = transformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			client := &mocks.HeaderClient{HeadToReturn: &types.Header{Number: big.NewInt(5)}}
+			lagTracker := eth.NewLagTracker(client, eth.NewGapRetriever(db), time.Second)
+			status, err := eth.NewStatusReporter(db, lagTracker).Status(1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.LowestBlock).To(Equal(int64(1)))
+			Expect(status.HighestBlock).To(Equal(int64(1)))
+			// genesis is missing, so there is no contiguous range and it is the only gap
+			Expect(status.HighestContiguousBlock).To(Equal(int64(-1)))
+			Expect(status.Headers).To(Equal(int64(1)))
+			Expect(status.Gaps).To(Equal(1))
+			Expect(status.GapBlocks).To(Equal(uint64(1)))
+			Expect(status.Lag).To(Equal(&eth.IndexingLag{ChainHead: 5, HighestIndexed: 1, Lag: 4}))
+		})
+	})
+})
@@ -17,6 +17,9 @@
 package eth
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"math/big"
 	"time"
@@ -28,13 +31,16 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/statediff"
+	"github.com/ipfs/go-cid"
 	node "github.com/ipfs/go-ipld-format"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/multiformats/go-multihash"
 	"github.com/sirupsen/logrus"
 
 	"github.com/vulcanize/ipld-eth-indexer/pkg/ipfs/ipld"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/prom"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
 )
 
@@ -43,65 +49,260 @@ type Transformer interface {
 	Transform(workerID int, payload statediff.Payload) (uint64, error)
 }
 
+// BlockPutter is an external blockstore IPLDs are written to, such as an ipfs.IPFSBlockstore
+type BlockPutter interface {
+	Put(c cid.Cid, raw []byte) error
+}
+
+// DerivedCIDHandler is handed each IPLD a dry run derives, along with the CID it would have been published under
+type DerivedCIDHandler interface {
+	HandleDerivedCID(c cid.Cid, raw []byte) error
+}
+
 // StateDiffTransformer satisfies the Transformer interface for ethereum statediff objects
 type StateDiffTransformer struct {
 	chainConfig *params.ChainConfig
 	indexer     *CIDIndexer
+	// decoders for the supported state object layouts, keyed by their number of top-level fields
+	stateObjectDecoders map[int]StateObjectDecoder
+	// hash function each IPLD node type is keyed under, keyed by its multicodec
+	multihashes map[uint64]uint64
+	// If true, payloads are decoded and their IPLDs generated but nothing is written to Postgres
+	DryRun bool
+	// If true, payloads whose parent header has not been indexed yet are rejected with ErrParentNotIndexed
+	StrictParentCheck bool
+	// If true, payloads whose header bloom does not match their receipt logs are rejected with ErrBloomMismatch
+	VerifyBloom bool
+	// If true, the r, s and v signature values of each transaction are indexed alongside it
+	// these are off by default as they add three numeric columns to every transaction row
+	IndexSignatures bool
+	// If true, the rlp encoding of each signed transaction is indexed alongside it, so that its exact bytes can be read
+	// back without fetching the IPLD; each encoding is checked to decode back to the same transaction hash before it is indexed
+	// this is off by default as it roughly doubles the size of the transaction rows
+	IndexRawTxs bool
+	// If not empty, only the receipts whose logs were emitted by, or that deploy, one of these contracts are indexed
+	// every transaction is still indexed, and every receipt and receipt trie node is still published so that the receipt trie
+	// remains complete and provable, but receipt_cids is no longer a complete index: a receipt can only be found by its CID
+	// and the topic and contract filters of the receipt_cids table will not see the unwatched ones
+	ReceiptContracts []common.Address
+	// If true, payloads for a block that has already been indexed are skipped without opening a Postgres tx
+	// this guards against the statediff service re-sending payloads, e.g. after a reconnect, but must be left off to re-index blocks
+	SkipIndexed bool
+	// If not nil, only the storage nodes of these accounts are published and indexed, and for an account with a non-empty
+	// list of slots only the storage leaf nodes of those slots are, all other storage nodes are skipped
+	// slots are the raw storage keys, which are matched against the storage leaf keys by their keccak256 hash
+	WatchedStorageSlots map[common.Address][]common.Hash
+	// The maximum size in bytes of each of a payload's rlp fields, larger payloads are rejected with ErrPayloadTooLarge
+	// before they are decoded, defaults to DefaultMaxPayloadBytes
+	MaxPayloadBytes int
+	// Isolation level of the Postgres tx each payload is written in, defaults to the database's default level
+	IsolationLevel sql.IsolationLevel
+	// How many times to retry a payload that fails due to a serialization failure under a stricter isolation level
+	// defaults to DefaultSerializationRetries
+	MaxSerializationRetries int
+	// Calculates the block and uncle rewards that are indexed, defaults to the ethereum mainnet EthRewardCalculator
+	RewardCalculator RewardCalculator
+	// If true, the total size of the IPLDs published for each block is indexed in eth.ipld_sizes, by node type
+	// transaction and receipt trie nodes are counted with the transactions and receipts, and contract code is not counted
+	IndexIPLDSizes bool
+	// If greater than zero, the state and storage nodes of a payload are written in chunks of about this many nodes, each
+	// committed in a Postgres tx of its own, to bound the size of the tx for payloads with very large state diffs
+	// the header, uncles, txs and receipts are committed with the first chunk, and an account's storage nodes are always
+	// committed with its state node, so a chunk can exceed the limit for an account with more storage nodes than it
+	// the block is recorded in eth.gaps under the StatePhase until its last chunk commits, so a payload that fails part way
+	// through is left as a gap that is backfilled, rather than passing for a fully indexed block
+	MaxNodesPerTx int
+	// If not nil, each IPLD that is published is also written to this blockstore once the Postgres tx it was published in
+	// has committed, so that a slow blockstore does not hold the tx open; a block whose writes fail is still indexed
+	// the IPLDs are still published to public.blocks, which the CID index references by FK; a DryRun writes nothing to it
+	Blockstore BlockPutter
+	// If not nil, a DryRun hands it each IPLD it derives, other than contract code, such as to check the derived CIDs
+	DerivedCIDs DerivedCIDHandler
+	// If false, storage nodes are neither published nor indexed, only state nodes and accounts are, defaults to true
+	// this saves the space of the storage tries, but storage_cids is left empty and the storage root of an indexed account
+	// cannot be resolved to its storage nodes, so contract storage can't be read or proven from the indexed data
+	IndexStorage bool
+	// If true, only the header and uncles of each payload are published and indexed, and the block is recorded in eth.gaps
+	// under the HeadersOnlyPhase, so that a usable header chain is indexed quickly and the rest is filled in by a later
+	// pass with this turned off, which clears the gap once the block's txs, receipts, state and storage have been indexed
+	HeadersOnly bool
+	// If true, the raw payload of each block transformed by Transform is also stored in eth.payloads, keyed by block number,
+	// so that it can be reprocessed later without refetching it from a node; this roughly doubles the space used per block
+	// payloads passed to TransformDecoded, e.g. by a CompositeTransformer, have already been decoded and are not stored
+	PersistPayloads bool
+	// If not nil, a BlockEvent summarizing each block is emitted to this sink once the block has been committed
+	// in a DryRun the event is emitted once the block has been decoded, with the counts a real run would have indexed
+	EventSink BlockEventSink
+	// If true, the deferrable foreign key constraints of each payload's tx are only checked once it commits, which speeds up
+	// bulk loads such as an initial backfill; a violation still fails the payload, but at its commit rather than at the
+	// insert which caused it. If false, the constraints are checked immediately as each row is inserted
+	DeferConstraints bool
 }
 
+// HeadersOnlyPhase is the eth.gaps phase of a block for which only the header and uncles have been indexed
+const HeadersOnlyPhase = "headers"
+
+// StatePhase is the eth.gaps phase of a block whose state and storage nodes are committed in chunks, it is recorded with
+// the first chunk and cleared with the last, so it is only left behind by a block that failed part way through
+const StatePhase = "state"
+
+// DefaultSerializationRetries is the number of times a payload is retried after a serialization failure by default
+const DefaultSerializationRetries = 3
+
 // NewStateDiffTransformer creates a pointer to a new PayloadConverter which satisfies the PayloadConverter interface
 func NewStateDiffTransformer(chainConfig *params.ChainConfig, db *postgres.DB) *StateDiffTransformer {
 	return &StateDiffTransformer{
-		chainConfig: chainConfig,
-		indexer:     NewCIDIndexer(db),
+		chainConfig:         chainConfig,
+		indexer:             NewCIDIndexer(db),
+		stateObjectDecoders: defaultStateObjectDecoders(),
+		multihashes:         DefaultMultihashes(),
+		IndexStorage:        true,
 	}
 }
 
 // Transform method is used to process statediff.Payload objects
-// It performs the necessary data conversions and database persistence
+// It decodes the payload and processes the result as TransformDecoded does, storing the raw payload if PersistPayloads is set
 func (sdt *StateDiffTransformer) Transform(workerID int, payload statediff.Payload) (uint64, error) {
-	start, t := time.Now(), time.Now()
-	// Unpack block rlp to access fields
-	block := new(types.Block)
-	if err := rlp.DecodeBytes(payload.BlockRlp, block); err != nil {
-		return 0, fmt.Errorf("error decoding payload block rlp: %s", err.Error())
+	decoded, err := decodePayload(sdt.chainConfig, sdt.stateObjectDecoders, sdt.MaxPayloadBytes, payload)
+	if err != nil {
+		return 0, err
+	}
+	return sdt.transformDecoded(workerID, decoded.block, decoded.receipts, decoded.stateDiff, payload.TotalDifficulty, &payload)
+}
+
+// TransformDecoded processes a payload that has already been decoded, so that a driver running several transformers
+// over the same payload only has to decode it once
+// the receipts must have had their derived fields set, and none of the arguments are modified
+// It performs the necessary data conversions and database persistence
+// Under an isolation level stricter than the default, payloads that fail due to a serialization failure are retried
+func (sdt *StateDiffTransformer) TransformDecoded(workerID int, block *types.Block, receipts types.Receipts, stateDiff *statediff.StateObject, td *big.Int) (uint64, error) {
+	return sdt.transformDecoded(workerID, block, receipts, stateDiff, td, nil)
+}
+
+// transformDecoded processes a decoded payload, raw is the payload it was decoded from, if it is available to be persisted
+func (sdt *StateDiffTransformer) transformDecoded(workerID int, block *types.Block, receipts types.Receipts, stateDiff *statediff.StateObject, td *big.Int, raw *statediff.Payload) (uint64, error) {
+	// payloads decoded by the caller of TransformDecoded have not been through decodePayload's check
+	if err := checkReceiptCount(block.Transactions(), receipts); err != nil {
+		return 0, err
+	}
+	if sdt.SkipIndexed {
+		indexed, err := sdt.isIndexed(block)
+		if err != nil {
+			return 0, err
+		}
+		if indexed {
+			logrus.Infof("worker %d skipping payload at %d with hash %s, it has already been indexed", workerID, block.NumberU64(), block.Hash().String())
+			return block.NumberU64(), nil
+		}
+	}
+	start := time.Now()
+	defer func() { prom.ObserveTransform(time.Now().Sub(start)) }()
+	height, err := sdt.transform(workerID, block, receipts, stateDiff, td, raw)
+	if sdt.IsolationLevel == sql.LevelDefault {
+		return height, err
+	}
+	maxRetries := sdt.MaxSerializationRetries
+	if maxRetries <= 0 {
+		maxRetries = DefaultSerializationRetries
 	}
-	blockHash := block.Hash()
-	blockHashStr := blockHash.String()
+	for retry := 1; retry <= maxRetries && isSerializationFailure(err); retry++ {
+		logrus.Warnf("worker %d serialization failure transforming payload, retrying (%d/%d): %v", workerID, retry, maxRetries, err)
+		time.Sleep(time.Duration(retry) * 100 * time.Millisecond)
+		height, err = sdt.transform(workerID, block, receipts, stateDiff, td, raw)
+	}
+	return height, err
+}
+
+// beginTx begins a Postgres tx for a payload at the configured isolation level, with its foreign key constraints set to
+// be checked as each row is inserted or, if DeferConstraints is set, once when the tx commits
+func (sdt *StateDiffTransformer) beginTx() (*sqlx.Tx, error) {
+	tx, err := sdt.indexer.db.BeginTxx(context.Background(), &sql.TxOptions{Isolation: sdt.IsolationLevel})
+	if err != nil {
+		return nil, err
+	}
+	pgStr := `SET CONSTRAINTS ALL IMMEDIATE`
+	if sdt.DeferConstraints {
+		pgStr = `SET CONSTRAINTS ALL DEFERRED`
+	}
+	if _, err := tx.Exec(pgStr); err != nil {
+		shared.Rollback(tx)
+		return nil, err
+	}
+	return tx, nil
+}
+
+// isSerializationFailure returns whether the error is a Postgres serialization failure or deadlock, which can be retried
+func isSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
+
+// transform processes a single decoded payload in one Postgres tx
+// the error is a named result so that the deferred commit can report its failure
+func (sdt *StateDiffTransformer) transform(workerID int, block *types.Block, receipts types.Receipts, stateDiff *statediff.StateObject, td *big.Int, raw *statediff.Payload) (_ uint64, err error) {
+	start, t := time.Now(), time.Now()
+	blockHashStr := block.Hash().String()
 	height := block.NumberU64()
 	traceMsg := fmt.Sprintf("worker %d transformer stats for payload at %d with hash %s:\r\n", workerID, height, blockHashStr)
 	transactions := block.Transactions()
-	// Decode receipts for this block
-	receipts := make(types.Receipts, 0)
-	if err := rlp.DecodeBytes(payload.ReceiptsRlp, &receipts); err != nil {
-		return 0, fmt.Errorf("error decoding payload receipts rlp: %s", err.Error())
-	}
-	// Decode state diff rlp for this block
-	stateDiff := new(statediff.StateObject)
-	if err := rlp.DecodeBytes(payload.StateObjectRlp, stateDiff); err != nil {
-		return 0, fmt.Errorf("error decoding payload state object rlp: %s", err.Error())
-	}
-	// Derive any missing fields
-	if err := receipts.DeriveFields(sdt.chainConfig, blockHash, height, transactions); err != nil {
-		return 0, err
+	if sdt.VerifyBloom {
+		if err := verifyBloom(block.Header(), receipts); err != nil {
+			return 0, err
+		}
 	}
 	// Generate the block iplds
 	headerNode, uncleNodes, txNodes, txTrieNodes, rctNodes, rctTrieNodes, err := ipld.FromBlockAndReceipts(block, receipts)
 	if err != nil {
 		return 0, err
 	}
-	if len(txNodes) != len(txTrieNodes) && len(rctNodes) != len(rctTrieNodes) && len(txNodes) != len(rctNodes) {
-		return 0, fmt.Errorf("expected number of transactions (%d), transaction trie nodes (%d), receipts (%d), and receipt trie nodes (%d)to be equal", len(txNodes), len(txTrieNodes), len(rctNodes), len(rctTrieNodes))
+	// each tx and receipt is published along with the trie nodes at its index, so any one of the counts differing is an error
+	if len(txNodes) != len(txTrieNodes) || len(rctNodes) != len(rctTrieNodes) || len(txNodes) != len(rctNodes) {
+		return 0, fmt.Errorf("%w: transactions (%d), transaction trie nodes (%d), receipts (%d), receipt trie nodes (%d)", ErrNodeCountMismatch, len(txNodes), len(txTrieNodes), len(rctNodes), len(rctTrieNodes))
+	}
+	// Calculate reward, the genesis block has none
+	reward := big.NewInt(0)
+	if height != 0 {
+		reward = sdt.rewardCalculator().BlockReward(block.Header(), block.Uncles(), block.Transactions(), receipts)
+	}
+	var sizes *IPLDSizesModel
+	if sdt.IndexIPLDSizes {
+		sizes = blockIPLDSizes(headerNode, uncleNodes, txNodes, txTrieNodes, rctNodes, rctTrieNodes)
+	}
+	traceMsg += fmt.Sprintf("ipld generation time: %s\r\n", time.Now().Sub(t).String())
+	if sdt.StrictParentCheck && height != 0 {
+		if err := sdt.checkParent(block.ParentHash()); err != nil {
+			return 0, err
+		}
+	}
+	if sdt.DryRun {
+		return height, sdt.dryRun(workerID, block, receipts, stateDiff, headerNode, uncleNodes, txNodes, txTrieNodes, rctNodes, rctTrieNodes)
 	}
-	// Calculate reward
-	reward := CalcEthBlockReward(block.Header(), block.Uncles(), block.Transactions(), receipts)
-	traceMsg += fmt.Sprintf("payload decoding time: %s\r\n", time.Now().Sub(t).String())
 	t = time.Now()
 	// Begin new db tx for everything
-	tx, err := sdt.indexer.db.Beginx()
+	tx, err := sdt.beginTx()
 	if err != nil {
 		return 0, err
 	}
+	// keys of the state and storage IPLDs published in this tx, these are only cached once the tx has been committed
+	var publishedKeys []string
+	// ids of the addresses resolved in this tx, these are likewise only cached once the tx has been committed
+	addresses := make(addressIDs)
+	// IPLDs published in this tx, these are only written to the external blockstore once the tx has been committed
+	writes := sdt.newBlockstoreWrites()
+	// set once the state and storage nodes are split across several txs, so that their commits are reported per chunk
+	var chunked bool
+	// counts of the CIDs indexed for the block, only collected if there is a sink to emit them to
+	var event *BlockEvent
+	if sdt.EventSink != nil {
+		event = &BlockEvent{
+			BlockNumber: height,
+			BlockHash:   blockHashStr,
+			Uncles:      len(uncleNodes),
+		}
+	}
 	// defer to handle transaction commit or rollback for any return case
 	defer func() {
 		if p := recover(); p != nil {
@@ -110,8 +311,19 @@ func (sdt *StateDiffTransformer) Transform(workerID int, payload statediff.Paylo
 		} else if err != nil {
 			shared.Rollback(tx)
 		} else {
+			commitStart := time.Now()
 			err = tx.Commit()
+			prom.ObserveCommit(chunked, time.Now().Sub(commitStart))
+			if err == nil {
+				sdt.indexer.cacheAddressIDs(addresses)
+				if event != nil {
+					sdt.EventSink.Emit(*event)
+				}
+			}
 			traceMsg += fmt.Sprintf("postgres transaction commit duration: %s\r\n", time.Now().Sub(t).String())
+			if err == nil {
+				err = sdt.flushPublished(publishedKeys, writes, height)
+			}
 		}
 		traceMsg += fmt.Sprintf(" TOTAL PROCESSING TIME: %s\r\n", time.Now().Sub(start).String())
 		logrus.Info(traceMsg)
@@ -120,18 +332,27 @@ func (sdt *StateDiffTransformer) Transform(workerID int, payload statediff.Paylo
 	t = time.Now()
 
 	// Publish and index header, collect headerID
-	headerID, err := sdt.processHeader(tx, block.Header(), headerNode, reward, payload.TotalDifficulty)
+	headerID, err := sdt.processHeader(tx, block.Header(), headerNode, reward, td, writes)
 	if err != nil {
 		return 0, err
 	}
+	if sdt.PersistPayloads && raw != nil {
+		if err := sdt.indexer.indexPayload(tx, height, blockHashStr, bigIntToString(td, "total difficulty", height), *raw); err != nil {
+			return 0, err
+		}
+	}
 	traceMsg += fmt.Sprintf("header processing time: %s\r\n", time.Now().Sub(t).String())
 	t = time.Now()
 	// Publish and index uncles
-	if err := sdt.processUncles(tx, headerID, height, uncleNodes); err != nil {
+	if err := sdt.processUncles(tx, headerID, height, uncleNodes, writes); err != nil {
 		return 0, err
 	}
 	traceMsg += fmt.Sprintf("uncle processing time: %s\r\n", time.Now().Sub(t).String())
 	t = time.Now()
+	if sdt.HeadersOnly {
+		err = sdt.indexer.indexGap(tx, headerID, height, HeadersOnlyPhase)
+		return height, err // return error explicity so that the defer() assigns to it
+	}
 	// Publish and index receipts and txs
 	if err := sdt.processReceiptsAndTxs(tx, processArgs{
 		headerID:     headerID,
@@ -142,58 +363,276 @@ func (sdt *StateDiffTransformer) Transform(workerID int, payload statediff.Paylo
 		rctTrieNodes: rctTrieNodes,
 		txNodes:      txNodes,
 		txTrieNodes:  txTrieNodes,
+		event:        event,
+		writes:       writes,
+		addresses:    addresses,
 	}); err != nil {
 		return 0, err
 	}
 	traceMsg += fmt.Sprintf("tx and receipt processing time: %s\r\n", time.Now().Sub(t).String())
 	t = time.Now()
 	// Publish and index state and storage nodes
-	if err := sdt.processStateAndStorage(tx, headerID, stateDiff); err != nil {
+	var deduped dedupedWrites
+	chunks := chunkStateNodes(sdt.selectStateNodes(height, stateDiff.Nodes), sdt.MaxNodesPerTx)
+	chunked = len(chunks) > 1
+	if chunked {
+		// the header is committed with the first chunk, mark the block incomplete until the last chunk has been committed
+		if err := sdt.indexer.indexGap(tx, headerID, height, StatePhase); err != nil {
+			return 0, err
+		}
+	}
+	for i, nodes := range chunks {
+		if i > 0 {
+			// commit the chunks processed so far and continue in a new tx, the header they all reference has been committed
+			commitStart := time.Now()
+			err = tx.Commit()
+			prom.ObserveCommit(chunked, time.Now().Sub(commitStart))
+			if err != nil {
+				return 0, err
+			}
+			sdt.indexer.cacheAddressIDs(addresses)
+			if err = sdt.flushPublished(publishedKeys, writes, height); err != nil {
+				return 0, err
+			}
+			var next *sqlx.Tx
+			if next, err = sdt.beginTx(); err != nil {
+				return 0, err
+			}
+			tx = next
+		}
+		chunk := *stateDiff
+		chunk.Nodes = nodes
+		var chunkDeduped dedupedWrites
+		publishedKeys, chunkDeduped, err = sdt.processStateAndStorage(tx, headerID, &chunk, sizes, event, writes)
+		if err != nil {
+			return 0, err
+		}
+		deduped.nodes += chunkDeduped.nodes
+		deduped.bytes += chunkDeduped.bytes
+	}
+	if sizes != nil {
+		if err := sdt.indexer.indexIPLDSizes(tx, *sizes, headerID); err != nil {
+			return 0, err
+		}
+	}
+	if err := sdt.indexer.clearGap(tx, headerID); err != nil {
 		return 0, err
 	}
 	traceMsg += fmt.Sprintf("state and storage processing time: %s\r\n", time.Now().Sub(t).String())
+	if len(chunks) > 1 {
+		traceMsg += fmt.Sprintf("state and storage nodes committed in %d postgres transactions\r\n", len(chunks))
+	}
+	traceMsg += fmt.Sprintf("state and storage nodes already published by a recent block: %d (%d bytes not rewritten)\r\n",
+		deduped.nodes, deduped.bytes)
 	t = time.Now()
 	return height, err // return error explicity so that the defer() assigns to it
 }
 
-// processHeader publishes and indexes a header IPLD in Postgres
+// dedupStore returns the DedupStore of the database the transformer writes to
+func (sdt *StateDiffTransformer) dedupStore() shared.DedupStore {
+	return shared.DedupStoreFor(sdt.indexer.db.DB)
+}
+
+// rewardCalculator returns the configured RewardCalculator, or the EthRewardCalculator if none has been set
+func (sdt *StateDiffTransformer) rewardCalculator() RewardCalculator {
+	if sdt.RewardCalculator == nil {
+		return EthRewardCalculator{}
+	}
+	return sdt.RewardCalculator
+}
+
+// dedupStateNodes drops the state nodes whose path repeats that of an earlier state node in the diff, and the storage nodes
+// whose path repeats that of an earlier storage node of the same account, logging a warning for each node that is dropped
+// a well-formed diff has no duplicates, the first of each is kept so that a malformed one does not abort the whole block
+func dedupStateNodes(height uint64, nodes []statediff.StateNode) []statediff.StateNode {
+	deduped := make([]statediff.StateNode, 0, len(nodes))
+	seen := make(map[string]bool, len(nodes))
+	for _, stateNode := range nodes {
+		if seen[string(stateNode.Path)] {
+			logrus.Warnf("dropping duplicate state node at path %x in the diff at height %d", stateNode.Path, height)
+			continue
+		}
+		seen[string(stateNode.Path)] = true
+		storageNodes := make([]statediff.StorageNode, 0, len(stateNode.StorageNodes))
+		seenStorage := make(map[string]bool, len(stateNode.StorageNodes))
+		for _, storageNode := range stateNode.StorageNodes {
+			if seenStorage[string(storageNode.Path)] {
+				logrus.Warnf("dropping duplicate storage node at path %x for the state node at path %x in the diff at height %d",
+					storageNode.Path, stateNode.Path, height)
+				continue
+			}
+			seenStorage[string(storageNode.Path)] = true
+			storageNodes = append(storageNodes, storageNode)
+		}
+		stateNode.StorageNodes = storageNodes
+		deduped = append(deduped, stateNode)
+	}
+	return deduped
+}
+
+// selectStateNodes returns the deduplicated state nodes of a diff, each with only those of its storage nodes that are
+// indexed under the IndexStorage and WatchedStorageSlots settings
+// dry runs select the nodes they count with it too, so that they report the nodes a real run would index
+func (sdt *StateDiffTransformer) selectStateNodes(height uint64, nodes []statediff.StateNode) []statediff.StateNode {
+	selected := dedupStateNodes(height, nodes)
+	watchedStorage := sdt.watchedStorageLeafKeys()
+	for i, stateNode := range selected {
+		if !sdt.IndexStorage {
+			selected[i].StorageNodes = nil
+			continue
+		}
+		if watchedStorage == nil {
+			continue
+		}
+		watchedSlots, watched := watchedStorage[common.BytesToHash(stateNode.LeafKey)]
+		if !watched {
+			selected[i].StorageNodes = nil
+			continue
+		}
+		if watchedSlots == nil {
+			continue
+		}
+		storageNodes := make([]statediff.StorageNode, 0, len(stateNode.StorageNodes))
+		for _, storageNode := range stateNode.StorageNodes {
+			if watchedSlots[common.BytesToHash(storageNode.LeafKey)] {
+				storageNodes = append(storageNodes, storageNode)
+			}
+		}
+		selected[i].StorageNodes = storageNodes
+	}
+	return selected
+}
+
+// chunkStateNodes splits the state nodes into chunks of at most maxNodes state and storage nodes
+// a state node is never split from its storage nodes, so a state node with more storage nodes than maxNodes is a chunk of
+// its own; if maxNodes is not greater than zero the state nodes are returned as a single chunk
+func chunkStateNodes(nodes []statediff.StateNode, maxNodes int) [][]statediff.StateNode {
+	if maxNodes <= 0 {
+		return [][]statediff.StateNode{nodes}
+	}
+	chunks := make([][]statediff.StateNode, 0)
+	var start, count int
+	for i, stateNode := range nodes {
+		size := 1 + len(stateNode.StorageNodes)
+		if count > 0 && count+size > maxNodes {
+			chunks = append(chunks, nodes[start:i])
+			start, count = i, 0
+		}
+		count += size
+	}
+	return append(chunks, nodes[start:])
+}
+
+// checkParent returns ErrParentNotIndexed if the header with the provided hash has not been indexed
+// the genesis block has no parent and should not be checked
+func (sdt *StateDiffTransformer) checkParent(parentHash common.Hash) error {
+	var exists bool
+	pgStr := fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s.header_cids WHERE block_hash = $1)`, sdt.indexer.db.Schema)
+	if err := sdt.indexer.db.Get(&exists, pgStr, parentHash.String()); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: parent hash %s", ErrParentNotIndexed, parentHash.String())
+	}
+	return nil
+}
+
+// isIndexed returns whether the block has already been indexed
+// the header and the rest of a block are indexed in the same Postgres tx, so an indexed header means the block is fully indexed
+// unless it was indexed in HeadersOnly mode, in which case it has a gap that is only considered when HeadersOnly is off
+func (sdt *StateDiffTransformer) isIndexed(block *types.Block) (bool, error) {
+	var exists bool
+	pgStr := fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %[1]s.header_cids WHERE block_number = $1 AND block_hash = $2
+			AND ($3 OR NOT EXISTS(SELECT 1 FROM %[1]s.gaps WHERE gaps.header_id = header_cids.id)))`, sdt.indexer.db.Schema)
+	err := sdt.indexer.db.Get(&exists, pgStr, block.NumberU64(), block.Hash().String(), sdt.HeadersOnly)
+	return exists, err
+}
+
+// verifyBloom returns ErrBloomMismatch if the header's logs bloom is not the OR of the blooms of the receipts' logs
+func verifyBloom(header *types.Header, receipts types.Receipts) error {
+	if bloom := types.CreateBloom(receipts); bloom != header.Bloom {
+		return fmt.Errorf("%w: block %d with hash %s", ErrBloomMismatch, header.Number.Uint64(), header.Hash().String())
+	}
+	return nil
+}
+
+// processHeader publishes and indexes a header IPLD in Postgres, queueing it in writes for the external blockstore
 // it returns the headerID
-func (sdt *StateDiffTransformer) processHeader(tx *sqlx.Tx, header *types.Header, headerNode node.Node, reward, td *big.Int) (int64, error) {
+func (sdt *StateDiffTransformer) processHeader(tx *sqlx.Tx, header *types.Header, headerNode node.Node, reward, td *big.Int, writes *blockstoreWrites) (int64, error) {
 	// publish header
-	if err := shared.PublishIPLD(tx, headerNode); err != nil {
+	headerCID, err := sdt.cidFor(ipld.MEthHeader, headerNode)
+	if err != nil {
+		return 0, err
+	}
+	headerMhKey := shared.MultihashKeyFromCID(headerCID)
+	if err := shared.PublishDirect(tx, headerMhKey, headerNode.RawData()); err != nil {
 		return 0, err
 	}
+	writes.add(headerCID, headerNode.RawData())
+	// a malformed payload can be missing its total difficulty, don't let that take down the worker
+	height := header.Number.Uint64()
+	tdStr := bigIntToString(td, "total difficulty", height)
+	rewardStr := bigIntToString(reward, "reward", height)
 	// index header
 	return sdt.indexer.indexHeaderCID(tx, HeaderModel{
-		CID:             headerNode.Cid().String(),
-		MhKey:           shared.MultihashKeyFromCID(headerNode.Cid()),
+		CID:             headerCID.String(),
+		MhKey:           headerMhKey,
 		ParentHash:      header.ParentHash.String(),
 		BlockNumber:     header.Number.String(),
 		BlockHash:       header.Hash().String(),
-		TotalDifficulty: td.String(),
-		Reward:          reward.String(),
+		TotalDifficulty: tdStr,
+		Reward:          rewardStr,
 		Bloom:           header.Bloom.Bytes(),
 		StateRoot:       header.Root.String(),
 		RctRoot:         header.ReceiptHash.String(),
 		TxRoot:          header.TxHash.String(),
 		UncleRoot:       header.UncleHash.String(),
 		Timestamp:       header.Time,
+		BlockTime:       time.Unix(int64(header.Time), 0).UTC(),
 	})
 }
 
-func (sdt *StateDiffTransformer) processUncles(tx *sqlx.Tx, headerID int64, blockNumber uint64, uncleNodes []*ipld.EthHeader) error {
+// bigIntToString returns the decimal string for the provided big.Int
+// a nil value is logged and treated as 0
+func bigIntToString(i *big.Int, field string, height uint64) string {
+	if i == nil {
+		logrus.Warnf("payload at height %d has a nil %s, indexing it as 0", height, field)
+		return "0"
+	}
+	return i.String()
+}
+
+// bigIntString returns a pointer to the decimal string for the provided big.Int, or nil if it is nil
+func bigIntString(i *big.Int) *string {
+	if i == nil {
+		return nil
+	}
+	str := i.String()
+	return &str
+}
+
+func (sdt *StateDiffTransformer) processUncles(tx *sqlx.Tx, headerID int64, blockNumber uint64, uncleNodes []*ipld.EthHeader, writes *blockstoreWrites) error {
 	// publish and index uncles
-	for _, uncleNode := range uncleNodes {
-		if err := shared.PublishIPLD(tx, uncleNode); err != nil {
+	for i, uncleNode := range uncleNodes {
+		uncleCID, err := sdt.cidFor(ipld.MEthHeader, uncleNode)
+		if err != nil {
+			return err
+		}
+		uncleMhKey := shared.MultihashKeyFromCID(uncleCID)
+		if err := shared.PublishDirect(tx, uncleMhKey, uncleNode.RawData()); err != nil {
 			return err
 		}
-		uncleReward := CalcUncleMinerReward(blockNumber, uncleNode.Number.Uint64())
+		writes.add(uncleCID, uncleNode.RawData())
+		uncleReward := sdt.rewardCalculator().UncleReward(blockNumber, uncleNode.Number.Uint64())
 		uncle := UncleModel{
-			CID:        uncleNode.Cid().String(),
-			MhKey:      shared.MultihashKeyFromCID(uncleNode.Cid()),
-			ParentHash: uncleNode.ParentHash.String(),
-			BlockHash:  uncleNode.Hash().String(),
-			Reward:     uncleReward.String(),
+			CID:         uncleCID.String(),
+			MhKey:       uncleMhKey,
+			ParentHash:  uncleNode.ParentHash.String(),
+			BlockHash:   uncleNode.Hash().String(),
+			Reward:      uncleReward.String(),
+			BlockNumber: uncleNode.Number.String(),
+			Coinbase:    uncleNode.Coinbase.String(),
+			Index:       int64(i),
 		}
 		if err := sdt.indexer.indexUncleCID(tx, uncle, headerID); err != nil {
 			return err
@@ -212,12 +651,25 @@ type processArgs struct {
 	rctTrieNodes []*ipld.EthRctTrie
 	txNodes      []*ipld.EthTx
 	txTrieNodes  []*ipld.EthTxTrie
+	// if not nil, the txs and receipts that are indexed are counted in it
+	event *BlockEvent
+	// the published IPLDs are queued in it for the external blockstore
+	writes *blockstoreWrites
+	// the ids of the addresses the txs and receipts reference are collected in it
+	addresses addressIDs
 }
 
 // processReceiptsAndTxs publishes and indexes receipt and transaction IPLDs in Postgres
 func (sdt *StateDiffTransformer) processReceiptsAndTxs(tx *sqlx.Tx, args processArgs) error {
 	// Process receipts and txs
 	signer := types.MakeSigner(sdt.chainConfig, args.blockNumber)
+	txModels := make([]TxModel, 0, len(args.receipts))
+	rctModels := make([]*ReceiptModel, 0, len(args.receipts))
+	watched := sdt.watchedReceiptContracts()
+	// keys and raw data of the IPLDs to publish
+	cids := make([]cid.Cid, 0, len(args.receipts)*4)
+	mhKeys := make([]string, 0, len(args.receipts)*4)
+	iplds := make([][]byte, 0, len(args.receipts)*4)
 	for i, receipt := range args.receipts {
 		// tx that corresponds with this receipt
 		trx := args.txs[i]
@@ -227,68 +679,85 @@ func (sdt *StateDiffTransformer) processReceiptsAndTxs(tx *sqlx.Tx, args process
 		}
 
 		// Publishing
-		// publish trie nodes, these aren't indexed directly
-		if err := shared.PublishIPLD(tx, args.txTrieNodes[i]); err != nil {
+		// queue the trie nodes, which aren't indexed directly, and the txs and receipts to be published as one batch
+		txCID, err := sdt.cidFor(ipld.MEthTx, args.txNodes[i])
+		if err != nil {
 			return err
 		}
-		if err := shared.PublishIPLD(tx, args.rctTrieNodes[i]); err != nil {
+		rctCID, err := sdt.cidFor(ipld.MEthTxReceipt, args.rctNodes[i])
+		if err != nil {
 			return err
 		}
-		// publish the txs and receipts
-		txNode, rctNode := args.txNodes[i], args.rctNodes[i]
-		if err := shared.PublishIPLD(tx, txNode); err != nil {
+		txTrieCID, err := sdt.cidFor(ipld.MEthTxTrie, args.txTrieNodes[i])
+		if err != nil {
 			return err
 		}
-		if err := shared.PublishIPLD(tx, rctNode); err != nil {
+		rctTrieCID, err := sdt.cidFor(ipld.MEthTxReceiptTrie, args.rctTrieNodes[i])
+		if err != nil {
 			return err
 		}
+		txMhKey, rctMhKey := shared.MultihashKeyFromCID(txCID), shared.MultihashKeyFromCID(rctCID)
+		cids = append(cids, txTrieCID, rctTrieCID, txCID, rctCID)
+		mhKeys = append(mhKeys, shared.MultihashKeyFromCID(txTrieCID), shared.MultihashKeyFromCID(rctTrieCID), txMhKey, rctMhKey)
+		iplds = append(iplds, args.txTrieNodes[i].RawData(), args.rctTrieNodes[i].RawData(), args.txNodes[i].RawData(), args.rctNodes[i].RawData())
 
 		// Indexing
 		// extract topic and contract data from the receipt for indexing
-		topicSets := make([][]string, 4)
-		mappedContracts := make(map[string]bool) // use map to avoid duplicate addresses
-		for _, log := range receipt.Logs {
-			for i, topic := range log.Topics {
-				topicSets[i] = append(topicSets[i], topic.Hex())
-			}
-			mappedContracts[log.Address.String()] = true
-		}
-		// these are the contracts seen in the logs
-		logContracts := make([]string, 0, len(mappedContracts))
-		for addr := range mappedContracts {
-			logContracts = append(logContracts, addr)
+		topicSets, err := logTopicSets(receipt.Logs)
+		if err != nil {
+			return err
 		}
-		// this is the contract address if this receipt is for a contract creation tx
-		contract := shared.HandleZeroAddr(receipt.ContractAddress)
+		contract, logContracts := receiptContracts(receipt)
+		// a tx without a recipient is a contract deployment, whether or not the deployment succeeded
+		isDeployment := trx.To() == nil
 		var contractHash string
-		isDeployment := contract != ""
-		if isDeployment {
+		if contract != "" {
 			contractHash = crypto.Keccak256Hash(common.HexToAddress(contract).Bytes()).String()
-			// if tx is a contract deployment, publish the data (code)
+		}
+		if isDeployment && receiptSucceeded(receipt) {
+			// if tx is a successful contract deployment, publish the data (code)
 			// codec doesn't matter in this case sine we are not interested in the cid and the db key is multihash-derived
+			// the code is always keyed by its keccak256 hash, as code hashes are, whatever the storage tries are keyed under
 			// TODO: THE DATA IS NOT DIRECTLY THE CONTRACT CODE; THERE IS A MISSING PROCESSING STEP HERE
 			// the contractHash => contract code is not currently correct
-			if _, err := shared.PublishRaw(tx, ipld.MEthStorageTrie, multihash.KECCAK_256, trx.Data()); err != nil {
+			codeCID, err := shared.PublishRaw(tx, ipld.MEthStorageTrie, multihash.KECCAK_256, trx.Data())
+			if err != nil {
+				return err
+			}
+			if err := args.writes.addString(codeCID, trx.Data()); err != nil {
 				return err
 			}
 		}
-		// index tx first so that the receipt can reference it by FK
+		// collect the tx and receipt models, these are bulk indexed once the whole block has been published
 		txModel := TxModel{
 			Dst:        shared.HandleZeroAddrPointer(trx.To()),
 			Src:        shared.HandleZeroAddr(from),
 			TxHash:     trx.Hash().String(),
 			Index:      int64(i),
 			Data:       trx.Data(),
+			MethodID:   methodID(trx),
 			Deployment: isDeployment,
-			CID:        txNode.Cid().String(),
-			MhKey:      shared.MultihashKeyFromCID(txNode.Cid()),
+			CID:        txCID.String(),
+			MhKey:      txMhKey,
 		}
-		txID, err := sdt.indexer.indexTransactionCID(tx, txModel, args.headerID)
-		if err != nil {
-			return err
+		if sdt.IndexSignatures {
+			v, r, s := trx.RawSignatureValues()
+			txModel.R, txModel.S, txModel.V = bigIntString(r), bigIntString(s), bigIntString(v)
+		}
+		if sdt.IndexRawTxs {
+			raw, err := rawTx(trx)
+			if err != nil {
+				return err
+			}
+			txModel.RLP = raw
 		}
-		// index the receipt
-		rctModel := ReceiptModel{
+		txModels = append(txModels, txModel)
+		if watched != nil && !watchesReceipt(watched, contract, logContracts) {
+			// the receipt has been published above, it is only left out of the index
+			rctModels = append(rctModels, nil)
+			continue
+		}
+		rctModels = append(rctModels, &ReceiptModel{
 			Topic0s:      topicSets[0],
 			Topic1s:      topicSets[1],
 			Topic2s:      topicSets[2],
@@ -296,25 +765,265 @@ func (sdt *StateDiffTransformer) processReceiptsAndTxs(tx *sqlx.Tx, args process
 			Contract:     contract,
 			ContractHash: contractHash,
 			LogContracts: logContracts,
-			CID:          rctNode.Cid().String(),
-			MhKey:        shared.MultihashKeyFromCID(rctNode.Cid()),
+			LogCount:     int64(len(receipt.Logs)),
+			GasUsed:      receipt.GasUsed,
+			CID:          rctCID.String(),
+			MhKey:        rctMhKey,
+		})
+	}
+	if args.event != nil {
+		args.event.Transactions = len(txModels)
+		for i, rctModel := range rctModels {
+			if txModels[i].Deployment {
+				args.event.Deployments++
+			}
+			if rctModel != nil {
+				args.event.Receipts++
+			}
 		}
-		if err := sdt.indexer.indexReceiptCID(tx, rctModel, txID); err != nil {
+	}
+	// the raw inserts are independent of one another, so publish them in a single round trip
+	// this is used rather than publishing them concurrently, as a pq tx is not safe for concurrent use and spreading the
+	// inserts over several txs would give up the block's atomicity, see BenchmarkPublishFullBlockBatched
+	// they are not looked up in the DedupStore, a block's txs, receipts and the nodes of their tries rarely recur in later blocks
+	if err := shared.PublishDirectBatch(tx, mhKeys, iplds); err != nil {
+		return err
+	}
+	for i, c := range cids {
+		args.writes.add(c, iplds[i])
+	}
+	// index txs first so that the receipts can reference them by FK
+	return sdt.indexer.copyTransactionAndReceiptCIDs(tx, args.addresses, txModels, rctModels, args.headerID)
+}
+
+// blockstoreWrites queues the IPLDs published in a Postgres tx, which are written to the external blockstore once the tx
+// has committed, so that the tx and its pool connection are not held open across the blockstore's network round trips
+// a nil blockstoreWrites, used when there is no external blockstore, discards what is added to it
+type blockstoreWrites struct {
+	cids []cid.Cid
+	data [][]byte
+}
+
+// newBlockstoreWrites returns an empty queue of writes, or nil if no external blockstore is configured
+func (sdt *StateDiffTransformer) newBlockstoreWrites() *blockstoreWrites {
+	if sdt.Blockstore == nil {
+		return nil
+	}
+	return new(blockstoreWrites)
+}
+
+// add queues the IPLD to be written under the provided CID
+func (w *blockstoreWrites) add(c cid.Cid, raw []byte) {
+	if w == nil {
+		return
+	}
+	w.cids = append(w.cids, c)
+	w.data = append(w.data, raw)
+}
+
+// addString queues the IPLD to be written under the CID with the provided string
+func (w *blockstoreWrites) addString(c string, raw []byte) error {
+	if w == nil {
+		return nil
+	}
+	dc, err := cid.Decode(c)
+	if err != nil {
+		return err
+	}
+	w.add(dc, raw)
+	return nil
+}
+
+// flushBlockstoreWrites writes the queued IPLDs of a committed tx to the external blockstore and empties the queue
+// the block has already been indexed by then, so a failure is returned for it to be retried rather than rolled back
+func (sdt *StateDiffTransformer) flushBlockstoreWrites(writes *blockstoreWrites, height uint64) error {
+	if writes == nil {
+		return nil
+	}
+	defer func() { writes.cids, writes.data = writes.cids[:0], writes.data[:0] }()
+	for i, c := range writes.cids {
+		if err := sdt.Blockstore.Put(c, writes.data[i]); err != nil {
+			return fmt.Errorf("block %d was indexed, but writing its IPLD %s to the external blockstore failed: %w", height, c.String(), err)
+		}
+	}
+	return nil
+}
+
+// flushPublished writes the queued IPLDs of a committed tx to the external blockstore, and then records the keys of the
+// IPLDs the tx published in the dedup store
+// the keys are only recorded once the writes have succeeded, were they recorded before, a retry of a block whose writes
+// failed would skip publishing those IPLDs and so never queue them for the external blockstore again
+func (sdt *StateDiffTransformer) flushPublished(publishedKeys []string, writes *blockstoreWrites, height uint64) error {
+	if err := sdt.flushBlockstoreWrites(writes, height); err != nil {
+		return err
+	}
+	shared.MarkPublished(sdt.dedupStore(), publishedKeys...)
+	return nil
+}
+
+// handleDerivedCID hands the IPLD a dry run derived to the DerivedCIDHandler, if one is configured
+func (sdt *StateDiffTransformer) handleDerivedCID(c cid.Cid, raw []byte) error {
+	if sdt.DerivedCIDs == nil {
+		return nil
+	}
+	return sdt.DerivedCIDs.HandleDerivedCID(c, raw)
+}
+
+// handleDerivedBlockNodes hands the header, uncle, tx and receipt IPLDs a dry run derived for a block to the
+// DerivedCIDHandler, under the CIDs they would be indexed under
+func (sdt *StateDiffTransformer) handleDerivedBlockNodes(headerNode *ipld.EthHeader, uncleNodes []*ipld.EthHeader, txNodes []*ipld.EthTx,
+	txTrieNodes []*ipld.EthTxTrie, rctNodes []*ipld.EthReceipt, rctTrieNodes []*ipld.EthRctTrie) error {
+	put := func(codec uint64, n node.Node) error {
+		c, err := sdt.cidFor(codec, n)
+		if err != nil {
+			return err
+		}
+		return sdt.handleDerivedCID(c, n.RawData())
+	}
+	if err := put(ipld.MEthHeader, headerNode); err != nil {
+		return err
+	}
+	for _, uncleNode := range uncleNodes {
+		if err := put(ipld.MEthHeader, uncleNode); err != nil {
+			return err
+		}
+	}
+	for i := range txNodes {
+		if err := put(ipld.MEthTx, txNodes[i]); err != nil {
+			return err
+		}
+		if err := put(ipld.MEthTxTrie, txTrieNodes[i]); err != nil {
+			return err
+		}
+		if err := put(ipld.MEthTxReceipt, rctNodes[i]); err != nil {
+			return err
+		}
+		if err := put(ipld.MEthTxReceiptTrie, rctTrieNodes[i]); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// receiptSucceeded returns whether the tx of the receipt succeeded
+// pre-Byzantium receipts carry a post-state root instead of a status, their outcome is unknown so they are treated as successful
+func receiptSucceeded(receipt *types.Receipt) bool {
+	return len(receipt.PostState) > 0 || receipt.Status == types.ReceiptStatusSuccessful
+}
+
+// watchedReceiptContracts returns the set of ReceiptContracts, or nil if receipts are not being filtered
+func (sdt *StateDiffTransformer) watchedReceiptContracts() map[string]bool {
+	if len(sdt.ReceiptContracts) == 0 {
+		return nil
+	}
+	watched := make(map[string]bool, len(sdt.ReceiptContracts))
+	for _, addr := range sdt.ReceiptContracts {
+		watched[addr.String()] = true
+	}
+	return watched
+}
+
+// receiptContracts returns the address of the contract created by the receipt's tx, which is empty if it created none,
+// and the distinct addresses of the contracts that emitted its logs
+func receiptContracts(receipt *types.Receipt) (string, []string) {
+	mappedContracts := make(map[string]bool) // use map to avoid duplicate addresses
+	for _, log := range receipt.Logs {
+		mappedContracts[log.Address.String()] = true
+	}
+	logContracts := make([]string, 0, len(mappedContracts))
+	for addr := range mappedContracts {
+		logContracts = append(logContracts, addr)
+	}
+	return shared.HandleZeroAddr(receipt.ContractAddress), logContracts
+}
+
+// watchesReceipt returns whether the receipt for a tx deploying the provided contract and emitting logs from the provided
+// contracts touches any of the watched contracts
+func watchesReceipt(watched map[string]bool, contract string, logContracts []string) bool {
+	if watched[contract] {
+		return true
+	}
+	for _, addr := range logContracts {
+		if watched[addr] {
+			return true
+		}
+	}
+	return false
+}
+
+// watchedStorageLeafKeys returns the WatchedStorageSlots keyed by the state leaf key of each account, with each account's
+// slots converted to the set of their storage leaf keys
+// it returns nil if storage is not being filtered, and a nil set for an account whose storage is not filtered by slot
+func (sdt *StateDiffTransformer) watchedStorageLeafKeys() map[common.Hash]map[common.Hash]bool {
+	if sdt.WatchedStorageSlots == nil {
+		return nil
+	}
+	leafKeys := make(map[common.Hash]map[common.Hash]bool, len(sdt.WatchedStorageSlots))
+	for addr, slots := range sdt.WatchedStorageSlots {
+		var slotKeys map[common.Hash]bool
+		if len(slots) > 0 {
+			slotKeys = make(map[common.Hash]bool, len(slots))
+			for _, slot := range slots {
+				slotKeys[crypto.Keccak256Hash(slot.Bytes())] = true
+			}
+		}
+		leafKeys[crypto.Keccak256Hash(addr.Bytes())] = slotKeys
+	}
+	return leafKeys
+}
+
+// dedupedWrites counts the state and storage nodes whose IPLD had already been committed by a recent block, and their bytes
+// these are the writes to public.blocks, and to the external blockstore, that the DedupStore saved
+type dedupedWrites struct {
+	nodes int
+	bytes int
+}
+
 // processStateAndStorage publishes and indexes state and storage nodes in Postgres
-func (sdt *StateDiffTransformer) processStateAndStorage(tx *sqlx.Tx, headerID int64, stateDiff *statediff.StateObject) error {
+// it returns the keys of the IPLDs it published and the counts of the nodes whose IPLD had already been published by a recent block
+// the latter are still indexed for this block, their state_cids and storage_cids rows referencing the public.blocks row
+// committed by the earlier block by its key; only the redundant write of their data is skipped, no insert is issued for them
+// if sizes is not nil, the size of each of the nodes is added to it, and if event is not nil the indexed nodes are counted in it
+// the nodes must have been selected by selectStateNodes, every storage node left on them is published and indexed
+// the IPLDs that are published are queued in writes for the external blockstore
+func (sdt *StateDiffTransformer) processStateAndStorage(tx *sqlx.Tx, headerID int64, stateDiff *statediff.StateObject, sizes *IPLDSizesModel,
+	event *BlockEvent, writes *blockstoreWrites) ([]string, dedupedWrites, error) {
+	published := make([]string, 0, len(stateDiff.Nodes))
+	var deduped dedupedWrites
+	store := sdt.dedupStore()
+	publish := func(codec uint64, raw []byte) (string, string, error) {
+		c, err := ipld.RawdataToCid(codec, raw, sdt.multihashes[codec])
+		if err != nil {
+			return "", "", err
+		}
+		if sizes != nil {
+			sizes.add(codec, raw)
+		}
+		mhKey := shared.MultihashKeyFromCID(c)
+		inserted, err := shared.PublishDirectIfNew(tx, store, mhKey, raw)
+		if err != nil {
+			return "", "", err
+		}
+		if !inserted {
+			deduped.nodes++
+			deduped.bytes += len(raw)
+			prom.AddPublishCacheSkippedBytes(len(raw))
+			if event != nil {
+				event.DedupedNodes++
+				event.DedupedBytes += len(raw)
+			}
+			return c.String(), mhKey, nil
+		}
+		writes.add(c, raw)
+		published = append(published, mhKey)
+		return c.String(), mhKey, nil
+	}
 	for _, stateNode := range stateDiff.Nodes {
 		// publish the state node
-		stateCIDStr, err := shared.PublishRaw(tx, ipld.MEthStateTrie, multihash.KECCAK_256, stateNode.NodeValue)
+		stateCIDStr, mhKey, err := publish(ipld.MEthStateTrie, stateNode.NodeValue)
 		if err != nil {
-			return err
+			return nil, dedupedWrites{}, err
 		}
-		mhKey, _ := shared.MultihashKeyFromCIDString(stateCIDStr)
 		stateModel := StateNodeModel{
 			Path:     stateNode.Path,
 			StateKey: common.BytesToHash(stateNode.LeafKey).String(),
@@ -325,20 +1034,16 @@ func (sdt *StateDiffTransformer) processStateAndStorage(tx *sqlx.Tx, headerID in
 		// index the state node, collect the stateID to reference by FK
 		stateID, err := sdt.indexer.indexStateCID(tx, stateModel, headerID)
 		if err != nil {
-			return err
+			return nil, dedupedWrites{}, err
+		}
+		if event != nil {
+			event.StateNodes++
 		}
 		// if we have a leaf, decode and index the account data
 		if stateNode.NodeType == statediff.Leaf {
-			var i []interface{}
-			if err := rlp.DecodeBytes(stateNode.NodeValue, &i); err != nil {
-				return fmt.Errorf("error decoding state leaf node rlp: %s", err.Error())
-			}
-			if len(i) != 2 {
-				return fmt.Errorf("eth IPLDPublisher expected state leaf node rlp to decode into two elements")
-			}
-			var account state.Account
-			if err := rlp.DecodeBytes(i[1].([]byte), &account); err != nil {
-				return fmt.Errorf("error decoding state account rlp: %s", err.Error())
+			account, err := decodeStateAccount(stateNode.NodeValue)
+			if err != nil {
+				return nil, dedupedWrites{}, err
 			}
 			accountModel := StateAccountModel{
 				Balance:     account.Balance.String(),
@@ -347,16 +1052,15 @@ func (sdt *StateDiffTransformer) processStateAndStorage(tx *sqlx.Tx, headerID in
 				StorageRoot: account.Root.String(),
 			}
 			if err := sdt.indexer.indexStateAccount(tx, accountModel, stateID); err != nil {
-				return err
+				return nil, dedupedWrites{}, err
 			}
 		}
 		// if there are any storage nodes associated with this node, publish and index them
 		for _, storageNode := range stateNode.StorageNodes {
-			storageCIDStr, err := shared.PublishRaw(tx, ipld.MEthStorageTrie, multihash.KECCAK_256, storageNode.NodeValue)
+			storageCIDStr, mhKey, err := publish(ipld.MEthStorageTrie, storageNode.NodeValue)
 			if err != nil {
-				return err
+				return nil, dedupedWrites{}, err
 			}
-			mhKey, _ := shared.MultihashKeyFromCIDString(storageCIDStr)
 			storageModel := StorageNodeModel{
 				Path:       storageNode.Path,
 				StorageKey: common.BytesToHash(storageNode.LeafKey).String(),
@@ -365,9 +1069,105 @@ func (sdt *StateDiffTransformer) processStateAndStorage(tx *sqlx.Tx, headerID in
 				NodeType:   ResolveFromNodeType(storageNode.NodeType),
 			}
 			if err := sdt.indexer.indexStorageCID(tx, storageModel, stateID); err != nil {
+				return nil, dedupedWrites{}, err
+			}
+			if event != nil {
+				event.StorageNodes++
+			}
+		}
+	}
+	return published, deduped, nil
+}
+
+// decodeStateAccount decodes the account held by a state leaf node
+func decodeStateAccount(nodeValue []byte) (state.Account, error) {
+	var account state.Account
+	var i []interface{}
+	if err := rlp.DecodeBytes(nodeValue, &i); err != nil {
+		return account, fmt.Errorf("%w: %v", ErrDecodeStateLeaf, err)
+	}
+	if len(i) != 2 {
+		return account, fmt.Errorf("%w: expected state leaf node rlp to decode into two elements", ErrDecodeStateLeaf)
+	}
+	if err := rlp.DecodeBytes(i[1].([]byte), &account); err != nil {
+		return account, fmt.Errorf("%w: decoding state account rlp: %v", ErrDecodeStateLeaf, err)
+	}
+	return account, nil
+}
+
+// dryRun performs the remaining decoding and node generation for a payload without writing anything to Postgres
+// it logs the number of objects that would have been published and indexed, applying the same filters as a real run, and
+// emits them to the EventSink if there is one; nothing is written to the external blockstore either, the IPLDs that would
+// have been published are only handed to the DerivedCIDHandler if there is one
+func (sdt *StateDiffTransformer) dryRun(workerID int, block *types.Block, receipts types.Receipts, stateDiff *statediff.StateObject,
+	headerNode *ipld.EthHeader, uncleNodes []*ipld.EthHeader, txNodes []*ipld.EthTx, txTrieNodes []*ipld.EthTxTrie,
+	rctNodes []*ipld.EthReceipt, rctTrieNodes []*ipld.EthRctTrie) error {
+	event := BlockEvent{
+		BlockNumber: block.NumberU64(),
+		BlockHash:   block.Hash().String(),
+		Uncles:      len(uncleNodes),
+		DryRun:      true,
+	}
+	if sdt.HeadersOnly {
+		// only the header and uncles of the block would have been published
+		receipts, txNodes, txTrieNodes, rctNodes, rctTrieNodes = nil, nil, nil, nil, nil
+	}
+	if sdt.DerivedCIDs != nil {
+		if err := sdt.handleDerivedBlockNodes(headerNode, uncleNodes, txNodes, txTrieNodes, rctNodes, rctTrieNodes); err != nil {
+			return err
+		}
+	}
+	signer := types.MakeSigner(sdt.chainConfig, block.Number())
+	watched := sdt.watchedReceiptContracts()
+	for i, receipt := range receipts {
+		trx := block.Transactions()[i]
+		if _, err := types.Sender(signer, trx); err != nil {
+			return err
+		}
+		event.Transactions++
+		if trx.To() == nil {
+			event.Deployments++
+		}
+		contract, logContracts := receiptContracts(receipt)
+		if watched == nil || watchesReceipt(watched, contract, logContracts) {
+			event.Receipts++
+		}
+	}
+	var accounts int
+	if !sdt.HeadersOnly {
+		for _, stateNode := range sdt.selectStateNodes(block.NumberU64(), stateDiff.Nodes) {
+			stateCID, err := ipld.RawdataToCid(ipld.MEthStateTrie, stateNode.NodeValue, sdt.multihashes[ipld.MEthStateTrie])
+			if err != nil {
+				return err
+			}
+			if err := sdt.handleDerivedCID(stateCID, stateNode.NodeValue); err != nil {
 				return err
 			}
+			event.StateNodes++
+			if stateNode.NodeType == statediff.Leaf {
+				if _, err := decodeStateAccount(stateNode.NodeValue); err != nil {
+					return err
+				}
+				accounts++
+			}
+			for _, storageNode := range stateNode.StorageNodes {
+				storageCID, err := ipld.RawdataToCid(ipld.MEthStorageTrie, storageNode.NodeValue, sdt.multihashes[ipld.MEthStorageTrie])
+				if err != nil {
+					return err
+				}
+				if err := sdt.handleDerivedCID(storageCID, storageNode.NodeValue); err != nil {
+					return err
+				}
+				event.StorageNodes++
+			}
 		}
 	}
+	logrus.Infof("worker %d dry run for payload at %d with hash %s would index: 1 header, %d uncles, %d txs, %d receipts, "+
+		"%d tx trie nodes, %d receipt trie nodes, %d contract deployments, %d state nodes, %d state accounts, %d storage nodes",
+		workerID, event.BlockNumber, event.BlockHash, event.Uncles, event.Transactions, event.Receipts,
+		len(txTrieNodes), len(rctTrieNodes), event.Deployments, event.StateNodes, accounts, event.StorageNodes)
+	if sdt.EventSink != nil {
+		sdt.EventSink.Emit(event)
+	}
 	return nil
 }
@@ -0,0 +1,103 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/ipfs/ipld"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+// fullBlockTxCount is about the number of txs in a full mainnet block
+const fullBlockTxCount = 300
+
+// fullBlockIPLDs returns the keys and raw data of the tx, receipt and tx and receipt trie node IPLDs of a full block
+// these are the IPLDs processReceiptsAndTxs publishes
+func fullBlockIPLDs(b *testing.B) ([]string, [][]byte) {
+	txs := make(types.Transactions, fullBlockTxCount)
+	receipts := make(types.Receipts, fullBlockTxCount)
+	for i := range txs {
+		txs[i] = types.NewTransaction(uint64(i), mocks.Address, big.NewInt(1), 50000, big.NewInt(1), make([]byte, 128))
+		receipts[i] = types.NewReceipt(nil, false, uint64(i+1)*50000)
+		receipts[i].Logs = []*types.Log{{Address: mocks.Address, Topics: []common.Hash{common.HexToHash("0x04")}, Data: make([]byte, 64)}}
+	}
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1)}, txs, nil, receipts)
+	_, _, txNodes, txTrieNodes, rctNodes, rctTrieNodes, err := ipld.FromBlockAndReceipts(block, receipts)
+	if err != nil {
+		b.Fatal(err)
+	}
+	keys := make([]string, 0, len(txNodes)*4)
+	data := make([][]byte, 0, len(txNodes)*4)
+	for i := range txNodes {
+		keys = append(keys, shared.MultihashKeyFromCID(txTrieNodes[i].Cid()), shared.MultihashKeyFromCID(rctTrieNodes[i].Cid()),
+			shared.MultihashKeyFromCID(txNodes[i].Cid()), shared.MultihashKeyFromCID(rctNodes[i].Cid()))
+		data = append(data, txTrieNodes[i].RawData(), rctTrieNodes[i].RawData(), txNodes[i].RawData(), rctNodes[i].RawData())
+	}
+	return keys, data
+}
+
+// benchmarkPublish measures publishing the IPLDs of a full block in a Postgres tx with the provided function
+// each tx is rolled back so that every iteration inserts the same new rows
+// the benchmarks need the test database, and are run on their own with: go test ./pkg/eth -run '^$' -bench PublishFullBlock
+func benchmarkPublish(b *testing.B, publish func(tx *sqlx.Tx, keys []string, data [][]byte) error) {
+	db, err := shared.SetupDB()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+	keys, data := fullBlockIPLDs(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tx, err := db.Beginx()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := publish(tx, keys, data); err != nil {
+			tx.Rollback()
+			b.Fatal(err)
+		}
+		if err := tx.Rollback(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPublishFullBlockSerially publishes each IPLD with an insert of its own, as processReceiptsAndTxs used to
+func BenchmarkPublishFullBlockSerially(b *testing.B) {
+	benchmarkPublish(b, func(tx *sqlx.Tx, keys []string, data [][]byte) error {
+		for i, key := range keys {
+			if err := shared.PublishDirect(tx, key, data[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// BenchmarkPublishFullBlockBatched publishes the IPLDs in a single insert, as processReceiptsAndTxs does
+func BenchmarkPublishFullBlockBatched(b *testing.B) {
+	benchmarkPublish(b, func(tx *sqlx.Tx, keys []string, data [][]byte) error {
+		return shared.PublishDirectBatch(tx, keys, data)
+	})
+}
@@ -17,16 +17,26 @@
 package eth_test
 
 import (
+	"database/sql"
+	"errors"
+	"math/big"
+	"time"
+
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/statediff"
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-ipfs-blockstore"
 	"github.com/ipfs/go-ipfs-ds-help"
+	"github.com/multiformats/go-multihash"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/ipfs/ipld"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
 )
@@ -80,6 +90,18 @@ var _ = Describe("PublishAndIndexer", func() {
 			Expect(data).To(Equal(mocks.MockHeaderRlp))
 		})
 
+		It("Indexes the header timestamp as both raw seconds and a timestamptz", func() {
+			var header eth.HeaderModel
+			err = db.Get(&header, `SELECT * FROM eth.header_cids WHERE block_number = $1`, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(header.Timestamp).To(Equal(mocks.MockBlock.Time()))
+			Expect(header.BlockTime).To(BeTemporally("==", time.Unix(int64(mocks.MockBlock.Time()), 0)))
+			var count int
+			err = db.Get(&count, `SELECT COUNT(*) FROM eth.header_cids WHERE block_time = to_timestamp("timestamp")`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(1))
+		})
+
 		It("Publishes and indexes transaction IPLDs in a single tx", func() {
 			// check that txs were properly indexed
 			trxs := make([]string, 0)
@@ -144,6 +166,21 @@ var _ = Describe("PublishAndIndexer", func() {
 			}
 		})
 
+		It("Indexes the number of logs of each receipt", func() {
+			logCounts := make(map[string]int64)
+			rcts := make([]eth.ReceiptModel, 0)
+			err = db.Select(&rcts, `SELECT cid, log_count FROM eth.receipt_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			for _, rct := range rcts {
+				logCounts[rct.CID] = rct.LogCount
+			}
+			Expect(logCounts).To(Equal(map[string]int64{
+				mocks.Rct1CID.String(): 1,
+				mocks.Rct2CID.String(): 1,
+				mocks.Rct3CID.String(): 0,
+			}))
+		})
+
 		It("Publishes and indexes state IPLDs in a single tx", func() {
 			// check that state nodes were properly indexed and published
 			stateNodes := make([]eth.StateNodeModel, 0)
@@ -224,5 +261,949 @@ var _ = Describe("PublishAndIndexer", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(data).To(Equal(mocks.StorageLeafNode))
 		})
+
+		It("Indexes a block whose addresses have not been seen before", func() {
+			eth.TearDownDB(db)
+			_, err = db.Exec(`DELETE FROM eth.addresses`)
+			Expect(err).ToNot(HaveOccurred())
+			// a new transformer has no address ids cached, so every address is inserted along with the block
+			_, err = eth.NewStateDiffTransformer(params.MainnetChainConfig, db).Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			var addressCount int
+			err = db.Get(&addressCount, `SELECT COUNT(*) FROM eth.addresses`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addressCount).To(BeNumerically(">", 0))
+			trxs := make([]eth.TxModel, 0)
+			err = db.Select(&trxs, `SELECT * FROM eth.transaction_cids_with_addresses ORDER BY index`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(trxs)).To(Equal(len(mocks.MockTrxMetaPostPublsh)))
+			for i, trx := range trxs {
+				Expect(trx.Src).To(Equal(mocks.MockTrxMetaPostPublsh[i].Src))
+				Expect(trx.Dst).To(Equal(mocks.MockTrxMetaPostPublsh[i].Dst))
+			}
+			var rctCount int
+			err = db.Get(&rctCount, `SELECT COUNT(*) FROM eth.receipt_cids_with_addresses`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rctCount).To(Equal(len(trxs)))
+		})
+
+		It("Replaces the transactions and receipts of a block when it is re-indexed", func() {
+			_, err := transformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			indexes := make([]int64, 0)
+			pgStr := `SELECT transaction_cids.index FROM eth.transaction_cids
+				INNER JOIN eth.receipt_cids ON (receipt_cids.tx_id = transaction_cids.id)
+				INNER JOIN eth.header_cids ON (transaction_cids.header_id = header_cids.id)
+				WHERE header_cids.block_number = $1
+				ORDER BY transaction_cids.index`
+			err = db.Select(&indexes, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(indexes).To(Equal([]int64{0, 1, 2}))
+		})
+
+		It("Only indexes transaction signature values when signature indexing is enabled", func() {
+			_, err := transformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			txs := make([]eth.TxModel, 0)
+			pgStr := `SELECT transaction_cids.index, r, s, v FROM eth.transaction_cids
+				INNER JOIN eth.header_cids ON (transaction_cids.header_id = header_cids.id)
+				WHERE header_cids.block_number = $1
+				ORDER BY transaction_cids.index`
+			err = db.Select(&txs, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(txs)).To(Equal(3))
+			for _, trx := range txs {
+				Expect(trx.R).To(BeNil())
+				Expect(trx.S).To(BeNil())
+				Expect(trx.V).To(BeNil())
+			}
+
+			signatureTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			signatureTransformer.IndexSignatures = true
+			_, err = signatureTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			txs = make([]eth.TxModel, 0)
+			err = db.Select(&txs, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(txs)).To(Equal(3))
+			for i, trx := range txs {
+				v, r, s := mocks.MockTransactions[i].RawSignatureValues()
+				Expect(*trx.R).To(Equal(r.String()))
+				Expect(*trx.S).To(Equal(s.String()))
+				Expect(*trx.V).To(Equal(v.String()))
+			}
+		})
+
+		It("Only indexes the raw transaction rlp when raw transaction indexing is enabled", func() {
+			pgStr := `SELECT transaction_cids.index, tx_hash, tx_rlp FROM eth.transaction_cids
+				INNER JOIN eth.header_cids ON (transaction_cids.header_id = header_cids.id)
+				WHERE header_cids.block_number = $1
+				ORDER BY transaction_cids.index`
+			_, err := transformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			txs := make([]eth.TxModel, 0)
+			err = db.Select(&txs, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(txs)).To(Equal(3))
+			for _, trx := range txs {
+				Expect(trx.RLP).To(BeNil())
+			}
+
+			rawTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			rawTransformer.IndexRawTxs = true
+			_, err = rawTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			txs = make([]eth.TxModel, 0)
+			err = db.Select(&txs, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(txs)).To(Equal(3))
+			for _, trx := range txs {
+				decoded := new(types.Transaction)
+				err = rlp.DecodeBytes(trx.RLP, decoded)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(decoded.Hash().String()).To(Equal(trx.TxHash))
+			}
+		})
+
+		It("Indexes the gas used by each receipt's own transaction rather than the cumulative gas used", func() {
+			_, err = transformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			gasUsed := make([]uint64, 0)
+			pgStr := `SELECT receipt_cids.gas_used FROM eth.receipt_cids
+				INNER JOIN eth.transaction_cids ON (receipt_cids.tx_id = transaction_cids.id)
+				INNER JOIN eth.header_cids ON (transaction_cids.header_id = header_cids.id)
+				WHERE header_cids.block_number = $1
+				ORDER BY transaction_cids.index`
+			err = db.Select(&gasUsed, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gasUsed).To(Equal([]uint64{50, 100, 75}))
+		})
+
+		It("Emits an event with the counts of the CIDs indexed for each committed block", func() {
+			sink := new(mocks.BlockEventSink)
+			emittingTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			emittingTransformer.EventSink = sink
+			emittingTransformer.ReceiptContracts = []common.Address{mocks.Address}
+			_, err = emittingTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sink.Events).To(Equal([]eth.BlockEvent{{
+				BlockNumber:  mocks.BlockNumber.Uint64(),
+				BlockHash:    mocks.MockBlock.Hash().String(),
+				Uncles:       0,
+				Transactions: 3,
+				Receipts:     1,
+				Deployments:  1,
+				StateNodes:   2,
+				StorageNodes: 1,
+			}}))
+		})
+
+		It("Reports the counts a real run indexes in a dry run", func() {
+			configs := map[string]func(*eth.StateDiffTransformer){
+				"default": func(*eth.StateDiffTransformer) {},
+				"watched receipt contracts": func(t *eth.StateDiffTransformer) {
+					t.ReceiptContracts = []common.Address{mocks.Address}
+				},
+				"watched storage slots": func(t *eth.StateDiffTransformer) {
+					t.WatchedStorageSlots = map[common.Address][]common.Hash{mocks.ContractAddress: {common.HexToHash("1")}}
+				},
+				"storage indexing disabled": func(t *eth.StateDiffTransformer) {
+					t.IndexStorage = false
+				},
+				"headers only": func(t *eth.StateDiffTransformer) {
+					t.HeadersOnly = true
+				},
+			}
+			count := func(pgStr string) int {
+				var n int
+				Expect(db.Get(&n, pgStr)).To(Succeed())
+				return n
+			}
+			for name, configure := range configs {
+				eth.TearDownDB(db)
+				sink := new(mocks.BlockEventSink)
+				dryRunTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+				configure(dryRunTransformer)
+				dryRunTransformer.DryRun = true
+				dryRunTransformer.EventSink = sink
+				_, err = dryRunTransformer.Transform(1, mocks.MockStateDiffPayload)
+				Expect(err).ToNot(HaveOccurred(), name)
+				Expect(count(`SELECT COUNT(*) FROM eth.header_cids`)).To(BeZero(), name)
+				Expect(sink.Events).To(HaveLen(1), name)
+				event := sink.Events[0]
+				Expect(event.DryRun).To(BeTrue(), name)
+
+				realTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+				configure(realTransformer)
+				_, err = realTransformer.Transform(1, mocks.MockStateDiffPayload)
+				Expect(err).ToNot(HaveOccurred(), name)
+				Expect(event.Uncles).To(Equal(count(`SELECT COUNT(*) FROM eth.uncle_cids`)), name)
+				Expect(event.Transactions).To(Equal(count(`SELECT COUNT(*) FROM eth.transaction_cids`)), name)
+				Expect(event.Deployments).To(Equal(count(`SELECT COUNT(*) FROM eth.transaction_cids WHERE deployment`)), name)
+				Expect(event.Receipts).To(Equal(count(`SELECT COUNT(*) FROM eth.receipt_cids`)), name)
+				Expect(event.StateNodes).To(Equal(count(`SELECT COUNT(*) FROM eth.state_cids`)), name)
+				Expect(event.StorageNodes).To(Equal(count(`SELECT COUNT(*) FROM eth.storage_cids`)), name)
+			}
+		})
+
+		It("Only indexes the receipts that touch the watched contracts, while still publishing every receipt", func() {
+			filteringTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			filteringTransformer.ReceiptContracts = []common.Address{mocks.Address}
+			_, err = filteringTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			var txCount int
+			err = db.Get(&txCount, `SELECT COUNT(*) FROM eth.transaction_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(txCount).To(Equal(3))
+			rcts := make([]string, 0)
+			err = db.Select(&rcts, `SELECT cid FROM eth.receipt_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rcts).To(Equal([]string{mocks.Rct1CID.String()}))
+			for _, c := range []cid.Cid{mocks.Rct2CID, mocks.Rct3CID} {
+				prefixedKey := blockstore.BlockPrefix.String() + dshelp.MultihashToDsKey(c.Hash()).String()
+				var data []byte
+				err = db.Get(&data, ipfsPgGet, prefixedKey)
+				Expect(err).ToNot(HaveOccurred())
+			}
+		})
+
+		It("Indexes payloads decoded once by a CompositeTransformer", func() {
+			err = eth.NewDBCleaner(db).Clean([][2]uint64{{0, 1}}, shared.Full)
+			Expect(err).ToNot(HaveOccurred())
+			composite := eth.NewCompositeTransformer(params.MainnetChainConfig, eth.NewStateDiffTransformer(params.MainnetChainConfig, db))
+			height, err := composite.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(height).To(Equal(mocks.BlockNumber.Uint64()))
+			var blockHash string
+			err = db.Get(&blockHash, `SELECT block_hash FROM eth.header_cids WHERE block_number = $1`, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockHash).To(Equal(mocks.MockBlock.Hash().String()))
+			var rctCount int
+			err = db.Get(&rctCount, `SELECT COUNT(*) FROM eth.receipt_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rctCount).To(Equal(3))
+		})
+
+		It("Rejects payloads with an rlp field larger than the size limit before decoding them", func() {
+			limitedTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			limitedTransformer.MaxPayloadBytes = len(mocks.MockStateDiffPayload.BlockRlp) - 1
+			_, err = limitedTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, eth.ErrPayloadTooLarge)).To(BeTrue())
+		})
+
+		It("Returns typed errors for payloads that cannot be decoded", func() {
+			payload := mocks.MockStateDiffPayload
+			payload.BlockRlp = []byte{1, 2, 3}
+			_, err = eth.NewStateDiffTransformer(params.MainnetChainConfig, db).Transform(1, payload)
+			Expect(errors.Is(err, eth.ErrDecodeBlock)).To(BeTrue())
+			payload = mocks.MockStateDiffPayload
+			payload.ReceiptsRlp = []byte{1, 2, 3}
+			_, err = eth.NewStateDiffTransformer(params.MainnetChainConfig, db).Transform(1, payload)
+			Expect(errors.Is(err, eth.ErrDecodeReceipts)).To(BeTrue())
+			payload = mocks.MockStateDiffPayload
+			payload.StateObjectRlp = []byte{1, 2, 3}
+			_, err = eth.NewStateDiffTransformer(params.MainnetChainConfig, db).Transform(1, payload)
+			Expect(errors.Is(err, eth.ErrDecodeStateObject)).To(BeTrue())
+		})
+
+		It("Rejects payloads whose receipt and transaction counts do not match before indexing anything", func() {
+			eth.TearDownDB(db)
+			receiptsRlp, err := rlp.EncodeToBytes(mocks.MockReceipts[:2])
+			Expect(err).ToNot(HaveOccurred())
+			payload := mocks.MockStateDiffPayload
+			payload.ReceiptsRlp = receiptsRlp
+			_, err = transformer.Transform(1, payload)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, eth.ErrReceiptCountMismatch)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("transactions (3), receipts (2)"))
+
+			stateDiff := mocks.MockStateDiff
+			_, err = transformer.TransformDecoded(1, mocks.MockBlock, mocks.MockReceipts[:2], &stateDiff, mocks.MockStateDiffPayload.TotalDifficulty)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, eth.ErrReceiptCountMismatch)).To(BeTrue())
+
+			var headerCount int
+			err = db.Get(&headerCount, `SELECT COUNT(*) FROM eth.header_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(headerCount).To(Equal(0))
+		})
+
+		It("Indexes the number and coinbase of uncles", func() {
+			uncle := &types.Header{
+				Number:     big.NewInt(0),
+				Coinbase:   mocks.AnotherAddress,
+				Difficulty: big.NewInt(100),
+				Extra:      []byte{},
+			}
+			block := types.NewBlock(&mocks.MockHeader, mocks.MockTransactions, []*types.Header{uncle}, mocks.MockReceipts)
+			payload := mocks.MockStateDiffPayload
+			payload.BlockRlp, err = rlp.EncodeToBytes(block)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = transformer.Transform(1, payload)
+			Expect(err).ToNot(HaveOccurred())
+			uncles := make([]eth.UncleModel, 0)
+			err = db.Select(&uncles, `SELECT block_hash, block_number, coinbase FROM eth.uncle_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(uncles)).To(Equal(1))
+			Expect(uncles[0].BlockHash).To(Equal(uncle.Hash().String()))
+			Expect(uncles[0].BlockNumber).To(Equal("0"))
+			Expect(uncles[0].Coinbase).To(Equal(mocks.AnotherAddress.String()))
+		})
+
+		It("Indexes the position of each uncle in its block", func() {
+			uncles := []*types.Header{
+				{Number: big.NewInt(0), Coinbase: mocks.AnotherAddress, Difficulty: big.NewInt(100), Extra: []byte{}},
+				{Number: big.NewInt(0), Coinbase: mocks.Address, Difficulty: big.NewInt(200), Extra: []byte{}},
+			}
+			block := types.NewBlock(&mocks.MockHeader, mocks.MockTransactions, uncles, mocks.MockReceipts)
+			payload := mocks.MockStateDiffPayload
+			payload.BlockRlp, err = rlp.EncodeToBytes(block)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = transformer.Transform(1, payload)
+			Expect(err).ToNot(HaveOccurred())
+			indexed := make([]eth.UncleModel, 0)
+			err = db.Select(&indexed, `SELECT block_hash, index FROM eth.uncle_cids ORDER BY index`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(indexed)).To(Equal(2))
+			for i, uncle := range uncles {
+				Expect(indexed[i].Index).To(Equal(int64(i)))
+				Expect(indexed[i].BlockHash).To(Equal(uncle.Hash().String()))
+			}
+		})
+
+		It("Skips payloads for blocks that have already been indexed when enabled", func() {
+			_, err = db.Exec(`UPDATE eth.header_cids SET times_validated = 5 WHERE block_number = $1`, 1)
+			Expect(err).ToNot(HaveOccurred())
+			skippingTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			skippingTransformer.SkipIndexed = true
+			height, err := skippingTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(height).To(Equal(mocks.BlockNumber.Uint64()))
+			// re-indexing would have bumped the validation level of the header
+			var timesValidated int
+			err = db.Get(&timesValidated, `SELECT times_validated FROM eth.header_cids WHERE block_number = $1`, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(timesValidated).To(Equal(5))
+		})
+
+		Describe("Contract deployments", func() {
+			deploymentPayload := func(status uint64) statediff.Payload {
+				receipts := make(types.Receipts, len(mocks.MockReceipts))
+				for i, rct := range mocks.MockReceipts {
+					rctCopy := *rct
+					receipts[i] = &rctCopy
+				}
+				// post-Byzantium receipts carry a status in place of the post-state root
+				receipts[2].PostState = nil
+				receipts[2].Status = status
+				payload := mocks.MockStateDiffPayload
+				payload.ReceiptsRlp, err = rlp.EncodeToBytes(receipts)
+				Expect(err).ToNot(HaveOccurred())
+				return payload
+			}
+			codePublished := func() bool {
+				codeCID, err := ipld.RawdataToCid(ipld.MEthStorageTrie, mocks.MockContractByteCode, multihash.KECCAK_256)
+				Expect(err).ToNot(HaveOccurred())
+				var exists bool
+				err = db.Get(&exists, `SELECT EXISTS(SELECT 1 FROM public.blocks WHERE key = $1)`, shared.MultihashKeyFromCID(codeCID))
+				Expect(err).ToNot(HaveOccurred())
+				return exists
+			}
+			deployments := func() []bool {
+				flags := make([]bool, 0)
+				err := db.Select(&flags, `SELECT deployment FROM eth.transaction_cids ORDER BY index`)
+				Expect(err).ToNot(HaveOccurred())
+				return flags
+			}
+
+			It("Flags a successful deployment and publishes its code", func() {
+				// start from an empty db, with a new transformer that hasn't cached any of the IPLDs it published before
+				eth.TearDownDB(db)
+				_, err = eth.NewStateDiffTransformer(params.MainnetChainConfig, db).Transform(1, deploymentPayload(types.ReceiptStatusSuccessful))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deployments()).To(Equal([]bool{false, false, true}))
+				Expect(codePublished()).To(BeTrue())
+			})
+
+			It("Flags a reverted deployment without publishing its code", func() {
+				// start from an empty db, with a new transformer that hasn't cached any of the IPLDs it published before
+				eth.TearDownDB(db)
+				_, err = eth.NewStateDiffTransformer(params.MainnetChainConfig, db).Transform(1, deploymentPayload(types.ReceiptStatusFailed))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deployments()).To(Equal([]bool{false, false, true}))
+				Expect(codePublished()).To(BeFalse())
+			})
+		})
+
+		Describe("Watched storage slots", func() {
+			storageCount := func(slots map[common.Address][]common.Hash) int {
+				// start from an empty db, with a new transformer that hasn't cached any of the IPLDs it published before
+				eth.TearDownDB(db)
+				watchingTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+				watchingTransformer.WatchedStorageSlots = slots
+				_, err := watchingTransformer.Transform(1, mocks.MockStateDiffPayload)
+				Expect(err).ToNot(HaveOccurred())
+				var stateCount, storageCount int
+				err = db.Get(&stateCount, `SELECT COUNT(*) FROM eth.state_cids`)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(stateCount).To(Equal(len(mocks.MockStateNodes)))
+				err = db.Get(&storageCount, `SELECT COUNT(*) FROM eth.storage_cids`)
+				Expect(err).ToNot(HaveOccurred())
+				return storageCount
+			}
+
+			It("Indexes the storage nodes of watched slots", func() {
+				Expect(storageCount(map[common.Address][]common.Hash{
+					mocks.ContractAddress: {common.HexToHash("0")},
+				})).To(Equal(1))
+			})
+
+			It("Indexes all of the storage nodes of a watched account without slots", func() {
+				Expect(storageCount(map[common.Address][]common.Hash{
+					mocks.ContractAddress: nil,
+				})).To(Equal(1))
+			})
+
+			It("Skips the storage nodes of unwatched slots and accounts", func() {
+				Expect(storageCount(map[common.Address][]common.Hash{
+					mocks.ContractAddress: {common.HexToHash("1")},
+				})).To(Equal(0))
+				Expect(storageCount(map[common.Address][]common.Hash{
+					mocks.AccountAddresss: nil,
+				})).To(Equal(0))
+			})
+		})
+
+		It("Skips publishing and indexing storage nodes when storage indexing is disabled", func() {
+			eth.TearDownDB(db)
+			stateOnlyTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			stateOnlyTransformer.IndexStorage = false
+			_, err = stateOnlyTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			var stateCount, accountCount, storageCount int
+			err = db.Get(&stateCount, `SELECT COUNT(*) FROM eth.state_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateCount).To(Equal(len(mocks.MockStateNodes)))
+			err = db.Get(&accountCount, `SELECT COUNT(*) FROM eth.state_accounts`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(accountCount).To(Equal(len(mocks.MockStateNodes)))
+			err = db.Get(&storageCount, `SELECT COUNT(*) FROM eth.storage_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(storageCount).To(BeZero())
+			var storagePublished bool
+			err = db.Get(&storagePublished, `SELECT EXISTS(SELECT 1 FROM public.blocks WHERE key = $1)`, mocks.StorageMhKey)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(storagePublished).To(BeFalse())
+		})
+
+		It("Indexes state and storage nodes whose IPLDs were already published by a recent block", func() {
+			_, err := transformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			stateCIDs := make([]string, 0)
+			pgStr := `SELECT state_cids.cid FROM eth.state_cids INNER JOIN eth.header_cids ON (state_cids.header_id = header_cids.id)
+				WHERE header_cids.block_number = $1`
+			err = db.Select(&stateCIDs, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(stateCIDs)).To(Equal(2))
+			Expect(shared.ListContainsString(stateCIDs, mocks.State1CID.String())).To(BeTrue())
+			Expect(shared.ListContainsString(stateCIDs, mocks.State2CID.String())).To(BeTrue())
+			storageCIDs := make([]string, 0)
+			pgStr = `SELECT storage_cids.cid FROM eth.storage_cids INNER JOIN eth.state_cids ON (storage_cids.state_id = state_cids.id)
+				INNER JOIN eth.header_cids ON (state_cids.header_id = header_cids.id)
+				WHERE header_cids.block_number = $1`
+			err = db.Select(&storageCIDs, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(storageCIDs).To(Equal([]string{mocks.StorageCID.String()}))
+		})
+
+		It("Records the committed state and storage IPLD keys in the dedup store of the database", func() {
+			store := shared.NewLRUDedupStore(shared.DefaultPublishCacheSize)
+			shared.SetDedupStore(db.DB, store)
+			defer shared.SetDedupStore(db.DB, shared.NewLRUDedupStore(shared.DefaultPublishCacheSize))
+			_, err := transformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(store.Contains(mocks.State1MhKey)).To(BeTrue())
+			Expect(store.Contains(mocks.State2MhKey)).To(BeTrue())
+			Expect(store.Contains(mocks.StorageMhKey)).To(BeTrue())
+
+			// a second transformer skips the inserts of the IPLDs the first one committed
+			sink := new(mocks.BlockEventSink)
+			secondTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			secondTransformer.EventSink = sink
+			_, err = db.Exec(`DELETE FROM eth.header_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = secondTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			var storageCount int
+			err = db.Get(&storageCount, `SELECT COUNT(*) FROM eth.storage_cids WHERE mh_key = $1`, mocks.StorageMhKey)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(storageCount).To(Equal(1))
+			Expect(sink.Events).To(HaveLen(1))
+			Expect(sink.Events[0].DedupedNodes).To(Equal(3))
+			Expect(sink.Events[0].DedupedBytes).To(Equal(len(mocks.ContractLeafNode) + len(mocks.AccountLeafNode) + len(mocks.StorageLeafNode)))
+		})
+
+		It("Only looks up the keys of the state and storage IPLDs in the dedup store", func() {
+			store := new(mocks.DedupStore)
+			shared.SetDedupStore(db.DB, store)
+			defer shared.SetDedupStore(db.DB, shared.NewLRUDedupStore(shared.DefaultPublishCacheSize))
+			_, err := transformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(store.Lookups).To(ConsistOf(mocks.State1MhKey, mocks.State2MhKey, mocks.StorageMhKey))
+			Expect(store.Keys).To(Equal(map[string]bool{mocks.State1MhKey: true, mocks.State2MhKey: true, mocks.StorageMhKey: true}))
+		})
+
+		It("Evicts the least recently used keys from a full dedup store", func() {
+			store := shared.NewLRUDedupStore(2)
+			store.Add("a", "b")
+			Expect(store.Contains("a")).To(BeTrue())
+			store.Add("c")
+			Expect(store.Contains("a")).To(BeTrue())
+			Expect(store.Contains("b")).To(BeFalse())
+			Expect(store.Contains("c")).To(BeTrue())
+			store.Purge()
+			Expect(store.Contains("a")).To(BeFalse())
+		})
+
+		It("Rejects payloads whose parent has not been indexed in strict parent mode", func() {
+			strictTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			strictTransformer.StrictParentCheck = true
+			_, err := strictTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, eth.ErrParentNotIndexed)).To(BeTrue())
+		})
+
+		It("Rejects payloads whose header bloom does not match their receipt logs in bloom verification mode", func() {
+			verifyingTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			verifyingTransformer.VerifyBloom = true
+			_, err := verifyingTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+
+			droppedLogs := make(types.Receipts, len(mocks.MockReceipts))
+			for i, rct := range mocks.MockReceipts {
+				rctCopy := *rct
+				rctCopy.Logs = nil
+				droppedLogs[i] = &rctCopy
+			}
+			payload := mocks.MockStateDiffPayload
+			payload.ReceiptsRlp, err = rlp.EncodeToBytes(droppedLogs)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = verifyingTransformer.Transform(1, payload)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, eth.ErrBloomMismatch)).To(BeTrue())
+		})
+
+		It("Decodes state objects in the layout that carries contract code", func() {
+			withCode := []interface{}{
+				mocks.MockStateDiff.BlockNumber,
+				mocks.MockStateDiff.BlockHash,
+				mocks.MockStateDiff.Nodes,
+				[]interface{}{[]interface{}{common.Hash{}, mocks.MockContractByteCode}},
+			}
+			payload := mocks.MockStateDiffPayload
+			payload.StateObjectRlp, err = rlp.EncodeToBytes(withCode)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = transformer.Transform(1, payload)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Drops state and storage nodes whose path is duplicated within the diff", func() {
+			eth.TearDownDB(db)
+			contractNode := mocks.StateDiffs[0]
+			duplicateStorage := contractNode.StorageNodes[0]
+			duplicateStorage.NodeValue = mocks.AccountLeafNode
+			contractNode.StorageNodes = []statediff.StorageNode{contractNode.StorageNodes[0], duplicateStorage}
+			duplicateState := mocks.StateDiffs[1]
+			duplicateState.Path = contractNode.Path
+			stateDiff := mocks.MockStateDiff
+			stateDiff.Nodes = []statediff.StateNode{contractNode, mocks.StateDiffs[1], duplicateState}
+			payload := mocks.MockStateDiffPayload
+			payload.StateObjectRlp, err = rlp.EncodeToBytes(stateDiff)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = eth.NewStateDiffTransformer(params.MainnetChainConfig, db).Transform(1, payload)
+			Expect(err).ToNot(HaveOccurred())
+			// the first node at each path is the one that is kept
+			var stateCID string
+			err = db.Get(&stateCID, `SELECT cid FROM eth.state_cids WHERE state_path = $1`, contractNode.Path)
+			Expect(err).ToNot(HaveOccurred())
+			expectedState, err := ipld.RawdataToCid(ipld.MEthStateTrie, contractNode.NodeValue, multihash.KECCAK_256)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateCID).To(Equal(expectedState.String()))
+			storageCIDs := make([]string, 0)
+			err = db.Select(&storageCIDs, `SELECT cid FROM eth.storage_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			expectedStorage, err := ipld.RawdataToCid(ipld.MEthStorageTrie, mocks.StorageLeafNode, multihash.KECCAK_256)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(storageCIDs).To(Equal([]string{expectedStorage.String()}))
+			var stateCount int
+			err = db.Get(&stateCount, `SELECT COUNT(*) FROM eth.state_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateCount).To(Equal(2))
+		})
+
+		It("Rejects state objects in an unrecognized layout", func() {
+			unknown := []interface{}{mocks.MockStateDiff.BlockNumber, mocks.MockStateDiff.BlockHash}
+			payload := mocks.MockStateDiffPayload
+			payload.StateObjectRlp, err = rlp.EncodeToBytes(unknown)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = transformer.Transform(1, payload)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, eth.ErrUnrecognizedStateObject)).To(BeTrue())
+		})
+
+		It("Indexes payloads under a stricter isolation level", func() {
+			serializableTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			serializableTransformer.IsolationLevel = sql.LevelSerializable
+			blockNumber, err := serializableTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockNumber).To(Equal(mocks.BlockNumber.Uint64()))
+		})
+
+		It("Indexes payloads with their foreign key constraints deferred to commit", func() {
+			var expectedState int
+			err = db.Get(&expectedState, `SELECT COUNT(*) FROM eth.state_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			eth.TearDownDB(db)
+			deferringTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			deferringTransformer.DeferConstraints = true
+			deferringTransformer.MaxNodesPerTx = 1
+			blockNumber, err := deferringTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockNumber).To(Equal(mocks.BlockNumber.Uint64()))
+			var stateCount int
+			err = db.Get(&stateCount, `SELECT COUNT(*) FROM eth.state_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateCount).To(Equal(expectedState))
+		})
+
+		It("Decodes a payload without writing anything to Postgres in a dry run", func() {
+			eth.TearDownDB(db)
+			dryRunTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			dryRunTransformer.DryRun = true
+			height, err := dryRunTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(height).To(Equal(mocks.BlockNumber.Uint64()))
+			var headerCount, blockCount int
+			err = db.Get(&headerCount, `SELECT COUNT(*) FROM eth.header_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(headerCount).To(BeZero())
+			err = db.Get(&blockCount, `SELECT COUNT(*) FROM public.blocks`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockCount).To(BeZero())
+		})
+
+		It("Writes nothing to the external blockstore in a dry run, only handing the derived IPLDs to the handler", func() {
+			blockstore := new(mocks.Blockstore)
+			handler := new(mocks.DerivedCIDHandler)
+			dryRunTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			dryRunTransformer.DryRun = true
+			dryRunTransformer.Blockstore = blockstore
+			dryRunTransformer.DerivedCIDs = handler
+			_, err = dryRunTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockstore.Blocks).To(BeEmpty())
+			Expect(handler.CIDs).To(ContainElement(mocks.HeaderCID))
+			Expect(handler.CIDs).To(ContainElement(mocks.State1CID))
+			Expect(handler.CIDs).To(ContainElement(mocks.StorageCID))
+		})
+
+		It("Indexes a zero total difficulty when the payload is missing one", func() {
+			payload := mocks.MockStateDiffPayload
+			payload.TotalDifficulty = nil
+			blockNumber, err := transformer.Transform(1, payload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockNumber).To(Equal(mocks.BlockNumber.Uint64()))
+			var td string
+			err = db.Get(&td, `SELECT td FROM eth.header_cids WHERE block_number = $1`, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(td).To(Equal("0"))
+		})
+
+		It("Keys the IPLDs of each node type under its configured hash function", func() {
+			eth.TearDownDB(db)
+			sha256Transformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			err = sha256Transformer.SetMultihashes(map[uint64]uint64{ipld.MEthTx: multihash.SHA2_256})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = sha256Transformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			trx1CID, err := ipld.RawdataToCid(ipld.MEthTx, mocks.MockTransactions.GetRlp(0), multihash.SHA2_256)
+			Expect(err).ToNot(HaveOccurred())
+			var txCID string
+			err = db.Get(&txCID, `SELECT cid FROM eth.transaction_cids WHERE tx_hash = $1`, mocks.MockTransactions[0].Hash().String())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(txCID).To(Equal(trx1CID.String()))
+			var data []byte
+			err = db.Get(&data, ipfsPgGet, shared.MultihashKeyFromCID(trx1CID))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(mocks.MockTransactions.GetRlp(0)))
+			// the other node types are still keyed under keccak256
+			var headerCID string
+			err = db.Get(&headerCID, `SELECT cid FROM eth.header_cids WHERE block_number = $1`, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(headerCID).To(Equal(mocks.HeaderCID.String()))
+		})
+
+		It("Keys the deployed contract code under keccak256 whatever the storage tries are keyed under", func() {
+			eth.TearDownDB(db)
+			sha256Transformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			err = sha256Transformer.SetMultihashes(map[uint64]uint64{ipld.MEthStorageTrie: multihash.SHA2_256})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = sha256Transformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			codeMhKey, err := shared.MultihashKeyFromKeccak256(mocks.MockContractByteCode)
+			Expect(err).ToNot(HaveOccurred())
+			var code []byte
+			err = db.Get(&code, ipfsPgGet, codeMhKey)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(code).To(Equal(mocks.MockContractByteCode))
+			// the storage nodes are keyed under the configured sha256
+			storageCID, err := ipld.RawdataToCid(ipld.MEthStorageTrie, mocks.StorageLeafNode, multihash.SHA2_256)
+			Expect(err).ToNot(HaveOccurred())
+			var storageCount int
+			err = db.Get(&storageCount, `SELECT COUNT(*) FROM eth.storage_cids WHERE cid = $1`, storageCID.String())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(storageCount).To(Equal(1))
+		})
+
+		It("Indexes the rewards of the configured reward calculator", func() {
+			eth.TearDownDB(db)
+			feeTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			feeTransformer.RewardCalculator = eth.FeeRewardCalculator{}
+			_, err = feeTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			var reward string
+			err = db.Get(&reward, `SELECT reward FROM eth.header_cids WHERE block_number = $1`, 1)
+			Expect(err).ToNot(HaveOccurred())
+			expected := eth.FeeRewardCalculator{}.BlockReward(mocks.MockBlock.Header(), nil, mocks.MockBlock.Transactions(), mocks.MockReceipts)
+			Expect(reward).To(Equal(expected.String()))
+			Expect(reward).ToNot(Equal(eth.CalcEthBlockReward(mocks.MockBlock.Header(), nil, mocks.MockBlock.Transactions(), mocks.MockReceipts).String()))
+		})
+
+		It("Indexes the size of the published IPLDs when enabled", func() {
+			eth.TearDownDB(db)
+			sizeTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			sizeTransformer.IndexIPLDSizes = true
+			_, err = sizeTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			var sizes eth.IPLDSizesModel
+			err = db.Get(&sizes, `SELECT ipld_sizes.* FROM eth.ipld_sizes
+				INNER JOIN eth.header_cids ON (ipld_sizes.header_id = header_cids.id)
+				WHERE header_cids.block_number = $1`, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sizes.HeaderBytes).To(BeEquivalentTo(len(mocks.MockHeaderRlp)))
+			Expect(sizes.UncleBytes).To(BeZero())
+			var txBytes int
+			for i := range mocks.MockTransactions {
+				txBytes += len(mocks.MockTransactions.GetRlp(i))
+			}
+			// the tx trie nodes are counted alongside the txs
+			Expect(sizes.TxBytes).To(BeNumerically(">", txBytes))
+			Expect(sizes.ReceiptBytes).To(BeNumerically(">", 0))
+			var stateBytes int
+			for _, stateNode := range mocks.StateDiffs {
+				stateBytes += len(stateNode.NodeValue)
+			}
+			Expect(sizes.StateBytes).To(BeEquivalentTo(stateBytes))
+			Expect(sizes.StorageBytes).To(BeNumerically(">", 0))
+		})
+
+		It("Does not index the size of the published IPLDs by default", func() {
+			var count int
+			err = db.Get(&count, `SELECT COUNT(*) FROM eth.ipld_sizes`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(BeZero())
+		})
+
+		It("Commits the state and storage nodes in chunks when they are limited", func() {
+			countNodes := func() (int, int) {
+				var stateCount, storageCount int
+				err := db.Get(&stateCount, `SELECT COUNT(*) FROM eth.state_cids`)
+				Expect(err).ToNot(HaveOccurred())
+				err = db.Get(&storageCount, `SELECT COUNT(*) FROM eth.storage_cids`)
+				Expect(err).ToNot(HaveOccurred())
+				return stateCount, storageCount
+			}
+			expectedState, expectedStorage := countNodes()
+			Expect(expectedState).To(BeNumerically(">", 1))
+			eth.TearDownDB(db)
+			chunkingTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			chunkingTransformer.MaxNodesPerTx = 1
+			_, err = chunkingTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			stateCount, storageCount := countNodes()
+			Expect(stateCount).To(Equal(expectedState))
+			Expect(storageCount).To(Equal(expectedStorage))
+			var headerCount int
+			err = db.Get(&headerCount, `SELECT COUNT(*) FROM eth.header_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(headerCount).To(Equal(1))
+			var gapCount int
+			err = db.Get(&gapCount, `SELECT COUNT(*) FROM eth.gaps`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gapCount).To(BeZero())
+		})
+
+		It("Leaves a block whose later chunk fails recorded as a gap until it is retried", func() {
+			eth.TearDownDB(db)
+			// the contract node and its storage node make up the first chunk, the undecodable leaf the second
+			badLeaf := statediff.StateNode{
+				NodeType:  statediff.Leaf,
+				Path:      []byte{0x0f},
+				LeafKey:   mocks.AccountLeafKey,
+				NodeValue: []byte{1, 2, 3},
+			}
+			stateDiff := mocks.MockStateDiff
+			stateDiff.Nodes = []statediff.StateNode{mocks.StateDiffs[0], badLeaf}
+			payload := mocks.MockStateDiffPayload
+			payload.StateObjectRlp, err = rlp.EncodeToBytes(stateDiff)
+			Expect(err).ToNot(HaveOccurred())
+			chunkingTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			chunkingTransformer.MaxNodesPerTx = 2
+			chunkingTransformer.SkipIndexed = true
+			_, err = chunkingTransformer.Transform(1, payload)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, eth.ErrDecodeStateLeaf)).To(BeTrue())
+			// the first chunk was committed with the header
+			var headerCount, stateCount int
+			err = db.Get(&headerCount, `SELECT COUNT(*) FROM eth.header_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(headerCount).To(Equal(1))
+			err = db.Get(&stateCount, `SELECT COUNT(*) FROM eth.state_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateCount).To(Equal(1))
+			var phases []string
+			err = db.Select(&phases, `SELECT phase FROM eth.gaps WHERE block_number = $1`, mocks.BlockNumber.Uint64())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(phases).To(Equal([]string{eth.StatePhase}))
+			gaps, err := eth.NewGapRetriever(db).RetrieveGapsInData(1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gaps).To(ContainElement(eth.DBGap{Start: 1, Stop: 1}))
+			// the incomplete block is not skipped as already indexed, and the gap is cleared once it has been fully indexed
+			_, err = chunkingTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			err = db.Get(&stateCount, `SELECT COUNT(*) FROM eth.state_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateCount).To(Equal(len(mocks.MockStateNodes)))
+			err = db.Select(&phases, `SELECT phase FROM eth.gaps`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(phases).To(BeEmpty())
+		})
+
+		It("Writes the published IPLDs to the external blockstore", func() {
+			eth.TearDownDB(db)
+			blockstore := new(mocks.Blockstore)
+			mirroringTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			mirroringTransformer.Blockstore = blockstore
+			_, err = mirroringTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockstore.Blocks[mocks.HeaderCID.String()]).To(Equal(mocks.MockHeaderRlp))
+			Expect(blockstore.Blocks[mocks.Trx1CID.String()]).To(Equal(mocks.MockTransactions.GetRlp(0)))
+			// every block in public.blocks has been written to the blockstore
+			var published int
+			err = db.Get(&published, `SELECT COUNT(*) FROM public.blocks`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(blockstore.Blocks)).To(BeNumerically(">=", published))
+		})
+
+		It("Only writes to the external blockstore once the Postgres tx has committed", func() {
+			eth.TearDownDB(db)
+			var puts, uncommittedPuts int
+			blockstore := &mocks.Blockstore{BeforePut: func(cid.Cid) {
+				// the header is only visible to another connection once the block's tx has committed
+				var committed bool
+				err := db.Get(&committed, `SELECT EXISTS(SELECT 1 FROM eth.header_cids WHERE block_hash = $1)`, mocks.MockBlock.Hash().String())
+				Expect(err).ToNot(HaveOccurred())
+				puts++
+				if !committed {
+					uncommittedPuts++
+				}
+			}}
+			mirroringTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			mirroringTransformer.Blockstore = blockstore
+			_, err = mirroringTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(puts).ToNot(BeZero())
+			Expect(uncommittedPuts).To(BeZero())
+		})
+
+		It("Keeps a block indexed but returns an error when writing it to the external blockstore fails", func() {
+			eth.TearDownDB(db)
+			failingTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			failingTransformer.Blockstore = &mocks.Blockstore{ReturnErr: errors.New("blockstore unavailable")}
+			_, err = failingTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).To(HaveOccurred())
+			var headerCount int
+			err = db.Get(&headerCount, `SELECT COUNT(*) FROM eth.header_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(headerCount).To(Equal(1))
+		})
+
+		It("Writes the state and storage IPLDs to the external blockstore when a block is retried after its writes failed", func() {
+			eth.TearDownDB(db)
+			bs := &mocks.Blockstore{ReturnErr: errors.New("blockstore unavailable"), FailPuts: 1}
+			retryingTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			retryingTransformer.Blockstore = bs
+			_, err = retryingTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).To(HaveOccurred())
+			_, err = retryingTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			for _, c := range []string{mocks.State1CID.String(), mocks.State2CID.String(), mocks.StorageCID.String()} {
+				Expect(bs.Blocks).To(HaveKey(c))
+			}
+		})
+
+		It("Defers everything but the header and uncles in headers-only mode until a full pass", func() {
+			eth.TearDownDB(db)
+			headersTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			headersTransformer.HeadersOnly = true
+			_, err = headersTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			var headerCount, txCount, stateCount int
+			err = db.Get(&headerCount, `SELECT COUNT(*) FROM eth.header_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(headerCount).To(Equal(1))
+			err = db.Get(&txCount, `SELECT COUNT(*) FROM eth.transaction_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(txCount).To(BeZero())
+			err = db.Get(&stateCount, `SELECT COUNT(*) FROM eth.state_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateCount).To(BeZero())
+			var phases []string
+			err = db.Select(&phases, `SELECT phase FROM eth.gaps WHERE block_number = $1`, mocks.BlockNumber.Uint64())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(phases).To(Equal([]string{eth.HeadersOnlyPhase}))
+			// the block is a gap for a full backfill, but not for a headers-only one
+			retriever := eth.NewGapRetriever(db)
+			gaps, err := retriever.RetrieveGapsInData(1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gaps).To(ContainElement(eth.DBGap{Start: 1, Stop: 1}))
+			retriever.HeadersOnly = true
+			gaps, err = retriever.RetrieveGapsInData(1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gaps).ToNot(ContainElement(eth.DBGap{Start: 1, Stop: 1}))
+
+			fullTransformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+			fullTransformer.SkipIndexed = true
+			_, err = fullTransformer.Transform(1, mocks.MockStateDiffPayload)
+			Expect(err).ToNot(HaveOccurred())
+			err = db.Get(&txCount, `SELECT COUNT(*) FROM eth.transaction_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(txCount).To(Equal(len(mocks.MockTransactions)))
+			err = db.Get(&stateCount, `SELECT COUNT(*) FROM eth.state_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateCount).To(Equal(len(mocks.MockStateNodes)))
+			var gapCount int
+			err = db.Get(&gapCount, `SELECT COUNT(*) FROM eth.gaps`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gapCount).To(BeZero())
+		})
+
+		It("Rejects unsupported hash functions and node types", func() {
+			err = transformer.SetMultihashes(map[uint64]uint64{ipld.MEthTx: multihash.SHA1})
+			Expect(err).To(HaveOccurred())
+			err = transformer.SetMultihashes(map[uint64]uint64{cid.Raw: multihash.KECCAK_256})
+			Expect(err).To(HaveOccurred())
+		})
 	})
 })
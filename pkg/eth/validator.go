@@ -0,0 +1,117 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+)
+
+// HeaderClient is an interface for fetching canonical headers from an ethereum node; created to allow mock insertion
+type HeaderClient interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// Validator interface to allow substitution of mocks in tests
+type Validator interface {
+	Revalidate(start, stop uint64) ([]HashMismatch, error)
+}
+
+// HashMismatch describes a height at which the indexed headers do not match the canonical chain
+type HashMismatch struct {
+	BlockNumber   uint64
+	CanonicalHash string
+	IndexedHashes []string
+}
+
+// HeaderValidator satisfies the Validator interface for ethereum
+type HeaderValidator struct {
+	db      *postgres.DB
+	client  HeaderClient
+	cleaner Cleaner
+	timeout time.Duration
+}
+
+// NewHeaderValidator returns a new HeaderValidator
+func NewHeaderValidator(db *postgres.DB, client HeaderClient, timeout time.Duration) *HeaderValidator {
+	return &HeaderValidator{
+		db:      db,
+		client:  client,
+		cleaner: NewDBCleaner(db),
+		timeout: timeout,
+	}
+}
+
+// Revalidate resets the validation level of the headers in the provided range and re-checks each of their hashes
+// against the canonical chain, returning the heights at which the indexed headers don't match
+func (v *HeaderValidator) Revalidate(start, stop uint64) ([]HashMismatch, error) {
+	if stop < start {
+		return nil, fmt.Errorf("ethereum revalidation range ending block number needs to be greater than the starting block number")
+	}
+	if err := v.cleaner.ResetValidation([][2]uint64{{start, stop}}); err != nil {
+		return nil, fmt.Errorf("validation reset failed: %v", err)
+	}
+	mismatches := make([]HashMismatch, 0)
+	for height := start; height <= stop; height++ {
+		mismatch, err := v.validate(height)
+		if err != nil {
+			return nil, err
+		}
+		if mismatch != nil {
+			logrus.Warnf("ethereum revalidation mismatch at height %d: canonical hash %s, indexed hashes %v",
+				mismatch.BlockNumber, mismatch.CanonicalHash, mismatch.IndexedHashes)
+			mismatches = append(mismatches, *mismatch)
+		}
+	}
+	return mismatches, nil
+}
+
+// validate compares the headers indexed at the provided height against the canonical header at that height
+// it returns nil if the only header indexed at this height is the canonical one
+func (v *HeaderValidator) validate(height uint64) (*HashMismatch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), v.timeout)
+	defer cancel()
+	header, err := v.client.HeaderByNumber(ctx, new(big.Int).SetUint64(height))
+	if err != nil {
+		return nil, fmt.Errorf("ethereum revalidation error fetching header at height %d: %v", height, err)
+	}
+	indexedHashes := make([]string, 0)
+	pgStr := fmt.Sprintf(`SELECT block_hash FROM %s.header_cids WHERE block_number = $1`, v.db.Schema)
+	if err := v.db.Select(&indexedHashes, pgStr, height); err != nil {
+		return nil, err
+	}
+	canonicalHash := header.Hash().String()
+	if len(indexedHashes) == 1 && indexedHashes[0] == canonicalHash {
+		pgStr = fmt.Sprintf(`UPDATE %s.header_cids SET last_validated_at = now() WHERE block_number = $1 AND block_hash = $2`, v.db.Schema)
+		if _, err := v.db.Exec(pgStr, height, canonicalHash); err != nil {
+			return nil, fmt.Errorf("ethereum revalidation error recording validation time at height %d: %v", height, err)
+		}
+		return nil, nil
+	}
+	return &HashMismatch{
+		BlockNumber:   height,
+		CanonicalHash: canonicalHash,
+		IndexedHashes: indexedHashes,
+	}, nil
+}
@@ -0,0 +1,106 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("HeaderValidator", func() {
+	var (
+		db  *postgres.DB
+		err error
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		transformer := eth.NewStateDiffTransformer(params.MainnetChainConfig, db)
+		_, err = transformer.Transform(1, mocks.MockStateDiffPayload)
+		Expect(err).ToNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	Describe("Revalidate", func() {
+		It("Resets the validation level and reports no mismatches for canonical headers", func() {
+			client := &mocks.HeaderClient{
+				HeadersToReturn: map[uint64]*types.Header{
+					1: mocks.MockBlock.Header(),
+				},
+			}
+			validator := eth.NewHeaderValidator(db, client, time.Second)
+			mismatches, err := validator.Revalidate(1, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(mismatches)).To(Equal(0))
+			Expect(client.CalledAt).To(Equal([]uint64{1}))
+			var timesValidated int64
+			err = db.Get(&timesValidated, `SELECT times_validated FROM eth.header_cids WHERE block_number = 1`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(timesValidated).To(Equal(int64(0)))
+		})
+
+		It("Records when canonical headers were last validated", func() {
+			var indexedAt time.Time
+			err = db.Get(&indexedAt, `SELECT last_validated_at FROM eth.header_cids WHERE block_number = 1`)
+			Expect(err).ToNot(HaveOccurred())
+			client := &mocks.HeaderClient{
+				HeadersToReturn: map[uint64]*types.Header{
+					1: mocks.MockBlock.Header(),
+				},
+			}
+			validator := eth.NewHeaderValidator(db, client, time.Second)
+			_, err = validator.Revalidate(1, 1)
+			Expect(err).ToNot(HaveOccurred())
+			oldest, err := eth.NewGapRetriever(db).RetrieveOldestValidation()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(oldest).ToNot(BeNil())
+			Expect(oldest.After(indexedAt)).To(BeTrue())
+		})
+
+		It("Reports heights where the indexed header doesn't match the canonical one", func() {
+			otherHeader := types.CopyHeader(mocks.MockBlock.Header())
+			otherHeader.Extra = []byte{1}
+			client := &mocks.HeaderClient{
+				HeadersToReturn: map[uint64]*types.Header{
+					1: otherHeader,
+					2: {Number: big.NewInt(2)},
+				},
+			}
+			validator := eth.NewHeaderValidator(db, client, time.Second)
+			mismatches, err := validator.Revalidate(1, 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(mismatches)).To(Equal(2))
+			Expect(mismatches[0].BlockNumber).To(Equal(uint64(1)))
+			Expect(mismatches[0].CanonicalHash).To(Equal(otherHeader.Hash().String()))
+			Expect(mismatches[0].IndexedHashes).To(Equal([]string{mocks.MockBlock.Hash().String()}))
+			Expect(mismatches[1].BlockNumber).To(Equal(uint64(2)))
+			Expect(len(mismatches[1].IndexedHashes)).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,128 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gateway
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/node"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+	"github.com/vulcanize/ipld-eth-indexer/utils"
+)
+
+// Env variables
+const (
+	GATEWAY_HTTP_ADDR = "GATEWAY_HTTP_ADDR"
+
+	DATABASE_REPLICA_NAME     = "DATABASE_REPLICA_NAME"
+	DATABASE_REPLICA_HOSTNAME = "DATABASE_REPLICA_HOSTNAME"
+	DATABASE_REPLICA_PORT     = "DATABASE_REPLICA_PORT"
+	DATABASE_REPLICA_USER     = "DATABASE_REPLICA_USER"
+	DATABASE_REPLICA_PASSWORD = "DATABASE_REPLICA_PASSWORD"
+)
+
+// Config holds the parameters needed to serve the IPLD gateway
+type Config struct {
+	// DB info
+	DB       *postgres.DB
+	DBConfig postgres.Config
+
+	// Read replica info, ReplicaDB is nil if no replica has been configured
+	ReplicaDB       *postgres.DB
+	ReplicaDBConfig postgres.Config
+
+	HTTPAddr   string          // Address to serve the gateway on
+	LagTracker *eth.LagTracker // Tracks the indexing lag, nil if no ethereum node has been configured
+}
+
+// lagTimeout is the timeout for fetching the head of the chain when serving the indexing lag
+const lagTimeout = 5 * time.Second
+
+// NewConfig fills and returns a gateway config from toml parameters
+func NewConfig() (*Config, error) {
+	c := new(Config)
+
+	viper.BindEnv("gateway.httpAddr", GATEWAY_HTTP_ADDR)
+	viper.BindEnv("ethereum.httpPath", shared.ETH_HTTP_PATH)
+
+	c.HTTPAddr = viper.GetString("gateway.httpAddr")
+
+	c.DBConfig.Init()
+	db := utils.LoadPostgres(c.DBConfig, node.Info{})
+	c.DB = &db
+
+	// reads are only moved off the primary if a replica has been configured
+	if replicaDBConfig(c.DBConfig, &c.ReplicaDBConfig) {
+		replicaDB := utils.LoadPostgres(c.ReplicaDBConfig, node.Info{})
+		c.ReplicaDB = &replicaDB
+	}
+
+	// the indexing lag is only served if there is a node to fetch the head of the chain from
+	if ethHTTP := viper.GetString("ethereum.httpPath"); ethHTTP != "" {
+		client, err := rpc.Dial(shared.EthEndpoint(ethHTTP, "http"))
+		if err != nil {
+			return nil, err
+		}
+		c.LagTracker = eth.NewLagTracker(eth.NewRateLimitedHeaderClient(ethclient.NewClient(client), shared.RPCRateLimiter()), eth.NewGapRetriever(c.DB), lagTimeout)
+	}
+	return c, nil
+}
+
+// ReadDB returns the db the gateway serves queries from, the replica if one has been configured and the primary otherwise
+func (c *Config) ReadDB() *postgres.DB {
+	if c.ReplicaDB != nil {
+		return c.ReplicaDB
+	}
+	return c.DB
+}
+
+// replicaDBConfig fills the replica's config from the primary's, overriding its connection settings with the replica
+// specific ones; the schema and pool settings are shared with the primary
+// it returns false if no replica hostname has been set
+func replicaDBConfig(primary postgres.Config, replica *postgres.Config) bool {
+	viper.BindEnv("database.replica.name", DATABASE_REPLICA_NAME)
+	viper.BindEnv("database.replica.hostname", DATABASE_REPLICA_HOSTNAME)
+	viper.BindEnv("database.replica.port", DATABASE_REPLICA_PORT)
+	viper.BindEnv("database.replica.user", DATABASE_REPLICA_USER)
+	viper.BindEnv("database.replica.password", DATABASE_REPLICA_PASSWORD)
+
+	hostname := viper.GetString("database.replica.hostname")
+	if hostname == "" {
+		return false
+	}
+	*replica = primary
+	replica.Hostname = hostname
+	if name := viper.GetString("database.replica.name"); name != "" {
+		replica.Name = name
+	}
+	if port := viper.GetInt("database.replica.port"); port > 0 {
+		replica.Port = port
+	}
+	if user := viper.GetString("database.replica.user"); user != "" {
+		replica.User = user
+	}
+	if password := viper.GetString("database.replica.password"); password != "" {
+		replica.Password = password
+	}
+	return true
+}
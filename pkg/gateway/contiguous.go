@@ -0,0 +1,67 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+)
+
+// ContiguousPath is the path the contiguous block handler is served under
+const ContiguousPath = "/contiguous"
+
+// ContiguousResponse is the body returned by the contiguous block handler
+// HighestContiguousBlock is -1 if the genesis block has not been indexed yet
+type ContiguousResponse struct {
+	HighestContiguousBlock int64 `json:"highestContiguousBlock"`
+}
+
+// ContiguousHandler serves the highest block below which no block is missing from the index,
+// clients can safely read any block up to and including this height
+type ContiguousHandler struct {
+	retriever *eth.GapRetriever
+}
+
+// NewContiguousHandler returns a new ContiguousHandler
+func NewContiguousHandler(db *postgres.DB) *ContiguousHandler {
+	return &ContiguousHandler{
+		retriever: eth.NewGapRetriever(db),
+	}
+}
+
+// ServeHTTP handles GET /contiguous requests
+func (h *ContiguousHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	blockNumber, err := h.retriever.HighestContiguousBlock()
+	if err != nil {
+		logrus.Errorf("ipld gateway error fetching the highest contiguous block: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ContiguousResponse{HighestContiguousBlock: blockNumber}); err != nil {
+		logrus.Errorf("ipld gateway error writing the highest contiguous block response: %v", err)
+	}
+}
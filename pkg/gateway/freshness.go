@@ -0,0 +1,95 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gateway
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+)
+
+// FreshnessPath is the path the read freshness handler is served under
+const FreshnessPath = "/freshness"
+
+// FreshnessResponse is the body returned by the read freshness handler
+// the highest indexed blocks are -1 if nothing has been indexed yet
+type FreshnessResponse struct {
+	PrimaryHighestIndexedBlock int64 `json:"primaryHighestIndexedBlock"`
+	ReplicaHighestIndexedBlock int64 `json:"replicaHighestIndexedBlock"`
+	ReplicaLag                 int64 `json:"replicaLag"`
+}
+
+// FreshnessHandler serves how far the read replica the gateway queries trails the primary that is written to,
+// clients can only expect to read blocks up to the replica's highest indexed block
+type FreshnessHandler struct {
+	primary *eth.GapRetriever
+	replica *eth.GapRetriever
+}
+
+// NewFreshnessHandler returns a new FreshnessHandler
+func NewFreshnessHandler(primary, replica *postgres.DB) *FreshnessHandler {
+	return &FreshnessHandler{
+		primary: eth.NewGapRetriever(primary),
+		replica: eth.NewGapRetriever(replica),
+	}
+}
+
+// ServeHTTP handles GET /freshness requests
+func (h *FreshnessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	primaryHighest, err := highestIndexedBlock(h.primary)
+	if err != nil {
+		logrus.Errorf("ipld gateway error fetching the primary's highest indexed block: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	replicaHighest, err := highestIndexedBlock(h.replica)
+	if err != nil {
+		logrus.Errorf("ipld gateway error fetching the replica's highest indexed block: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	res := FreshnessResponse{
+		PrimaryHighestIndexedBlock: primaryHighest,
+		ReplicaHighestIndexedBlock: replicaHighest,
+	}
+	// the replica is read after the primary so it can briefly appear ahead of it
+	if primaryHighest > replicaHighest {
+		res.ReplicaLag = primaryHighest - replicaHighest
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logrus.Errorf("ipld gateway error writing the read freshness response: %v", err)
+	}
+}
+
+// highestIndexedBlock returns the highest block number in the db, or -1 if nothing has been indexed yet
+func highestIndexedBlock(retriever *eth.GapRetriever) (int64, error) {
+	blockNumber, err := retriever.RetrieveLastBlockNumber()
+	if err == sql.ErrNoRows {
+		return -1, nil
+	}
+	return blockNumber, err
+}
@@ -0,0 +1,83 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gateway_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/gateway"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("FreshnessHandler", func() {
+	var (
+		db      *postgres.DB
+		err     error
+		handler *gateway.FreshnessHandler
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		// the test db stands in for both the primary and a fully caught up replica
+		handler = gateway.NewFreshnessHandler(db, db)
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	It("Returns -1 for both when nothing has been indexed", func() {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, gateway.FreshnessPath, nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var res gateway.FreshnessResponse
+		err = json.Unmarshal(rec.Body.Bytes(), &res)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.PrimaryHighestIndexedBlock).To(Equal(int64(-1)))
+		Expect(res.ReplicaHighestIndexedBlock).To(Equal(int64(-1)))
+		Expect(res.ReplicaLag).To(Equal(int64(0)))
+	})
+
+	It("Returns the highest indexed block of the primary and the replica", func() {
+		_, err = eth.NewStateDiffTransformer(params.MainnetChainConfig, db).Transform(1, mocks.MockStateDiffPayload)
+		Expect(err).ToNot(HaveOccurred())
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, gateway.FreshnessPath, nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/json"))
+		var res gateway.FreshnessResponse
+		err = json.Unmarshal(rec.Body.Bytes(), &res)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.PrimaryHighestIndexedBlock).To(Equal(mocks.BlockNumber.Int64()))
+		Expect(res.ReplicaHighestIndexedBlock).To(Equal(mocks.BlockNumber.Int64()))
+		Expect(res.ReplicaLag).To(Equal(int64(0)))
+	})
+
+	It("Returns 405 for non-GET requests", func() {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, gateway.FreshnessPath, nil))
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
@@ -0,0 +1,82 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gateway
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+// IPLDPath is the path prefix the IPLD handler is served under
+const IPLDPath = "/ipld/"
+
+// IPLDHandler serves the raw bytes of indexed IPLD blocks by their CID
+type IPLDHandler struct {
+	db *postgres.DB
+}
+
+// NewIPLDHandler returns a new IPLDHandler
+func NewIPLDHandler(db *postgres.DB) *IPLDHandler {
+	return &IPLDHandler{
+		db: db,
+	}
+}
+
+// ServeHTTP handles GET /ipld/{cid} requests
+// It responds with 400 if the cid cannot be parsed and 404 if no block exists for it
+func (h *IPLDHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	cidStr := strings.TrimPrefix(r.URL.Path, IPLDPath)
+	mhKey, err := shared.MultihashKeyFromCIDString(cidStr)
+	if err != nil {
+		http.Error(w, "invalid cid: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	var data []byte
+	if err := h.db.Get(&data, `SELECT data FROM public.blocks WHERE key = $1`, mhKey); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		logrus.Errorf("ipld gateway error fetching block for cid %s: %v", cidStr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if _, err := w.Write(data); err != nil {
+		logrus.Errorf("ipld gateway error writing response for cid %s: %v", cidStr, err)
+	}
+}
+
+// NewServeMux returns a mux with the IPLD handler registered under IPLDPath, the contiguous block handler under ContiguousPath
+// and the state proof handler under ProofPath
+func NewServeMux(db *postgres.DB) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle(IPLDPath, NewIPLDHandler(db))
+	mux.Handle(ContiguousPath, NewContiguousHandler(db))
+	mux.Handle(ProofPath, NewProofHandler(db))
+	return mux
+}
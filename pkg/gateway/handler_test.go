@@ -0,0 +1,71 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/gateway"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("IPLDHandler", func() {
+	var (
+		db       *postgres.DB
+		err      error
+		mux      *http.ServeMux
+		mockData = []byte("mock ipld block")
+		mockCID  = shared.TestCID(mockData)
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		err = shared.PublishMockIPLD(db, shared.MultihashKeyFromCID(mockCID), mockData)
+		Expect(err).ToNot(HaveOccurred())
+		mux = gateway.NewServeMux(db)
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	It("Returns the raw block for an indexed cid", func() {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, gateway.IPLDPath+mockCID.String(), nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/octet-stream"))
+		Expect(rec.Body.Bytes()).To(Equal(mockData))
+	})
+
+	It("Returns 404 for a cid that isn't indexed", func() {
+		rec := httptest.NewRecorder()
+		missingCID := shared.TestCID([]byte("missing"))
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, gateway.IPLDPath+missingCID.String(), nil))
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("Returns 400 for an unparseable cid", func() {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, gateway.IPLDPath+"notacid", nil))
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	})
+})
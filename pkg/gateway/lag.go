@@ -0,0 +1,59 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+)
+
+// LagPath is the path the indexing lag handler is served under
+const LagPath = "/lag"
+
+// LagHandler serves how far the indexed data trails the head of the chain
+type LagHandler struct {
+	tracker *eth.LagTracker
+}
+
+// NewLagHandler returns a new LagHandler
+func NewLagHandler(tracker *eth.LagTracker) *LagHandler {
+	return &LagHandler{
+		tracker: tracker,
+	}
+}
+
+// ServeHTTP handles GET /lag requests, the lag is measured afresh for each request
+func (h *LagHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	lag, err := h.tracker.Update()
+	if err != nil {
+		logrus.Errorf("ipld gateway error measuring the indexing lag: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(lag); err != nil {
+		logrus.Errorf("ipld gateway error writing the indexing lag response: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+)
+
+// ProofPath is the path prefix the state proof handler is served under
+const ProofPath = "/proof/"
+
+// ProofResponse is the body returned by the state proof handler
+// AccountProof holds the rlp encoded state trie nodes from the state root to the account's leaf, as in eth_getProof
+type ProofResponse struct {
+	AccountProof []hexutil.Bytes `json:"accountProof"`
+}
+
+// ProofHandler serves the merkle proof of an account at a block, built from the indexed state trie nodes
+type ProofHandler struct {
+	retriever *eth.StateProofRetriever
+}
+
+// NewProofHandler returns a new ProofHandler
+func NewProofHandler(db *postgres.DB) *ProofHandler {
+	return &ProofHandler{
+		retriever: eth.NewStateProofRetriever(db),
+	}
+}
+
+// ServeHTTP handles GET /proof/{blockHash}/{address} requests
+// It responds with 400 if the hash or address cannot be parsed and 404 if the block or a node on the proof path is not indexed
+func (h *ProofHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, ProofPath), "/")
+	if len(parts) != 2 {
+		http.Error(w, "expected /proof/{blockHash}/{address}", http.StatusBadRequest)
+		return
+	}
+	hashBytes, err := hexutil.Decode(parts[0])
+	if err != nil || len(hashBytes) != common.HashLength {
+		http.Error(w, "invalid block hash", http.StatusBadRequest)
+		return
+	}
+	if !common.IsHexAddress(parts[1]) {
+		http.Error(w, "invalid address", http.StatusBadRequest)
+		return
+	}
+	proof, err := h.retriever.GetStateProof(common.BytesToHash(hashBytes), common.HexToAddress(parts[1]))
+	if err != nil {
+		if errors.Is(err, eth.ErrHeaderNotIndexed) || errors.Is(err, eth.ErrProofNodeMissing) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logrus.Errorf("ipld gateway error building the proof of %s at block %s: %v", parts[1], parts[0], err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	res := ProofResponse{AccountProof: make([]hexutil.Bytes, len(proof))}
+	for i, node := range proof {
+		res.AccountProof[i] = node
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logrus.Errorf("ipld gateway error writing the proof response: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gateway_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/gateway"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("ProofHandler", func() {
+	var (
+		db          *postgres.DB
+		err         error
+		mux         *http.ServeMux
+		allocAddr   = common.HexToAddress("0x0D3ab14BBaD3D99F4203bd7a11aCB94882050E7e")
+		genesisHash common.Hash
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		genesis := &core.Genesis{
+			Config:     params.MainnetChainConfig,
+			Difficulty: big.NewInt(1),
+			Alloc: core.GenesisAlloc{
+				allocAddr: {Balance: big.NewInt(1000)},
+			},
+		}
+		_, err = eth.NewStateDiffTransformer(params.MainnetChainConfig, db).IndexGenesis(genesis)
+		Expect(err).ToNot(HaveOccurred())
+		genesisHash = genesis.ToBlock(rawdb.NewMemoryDatabase()).Hash()
+		mux = gateway.NewServeMux(db)
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	It("Returns the proof of the account built from the indexed state trie nodes", func() {
+		expected, err := eth.NewStateProofRetriever(db).GetStateProof(genesisHash, allocAddr)
+		Expect(err).ToNot(HaveOccurred())
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, gateway.ProofPath+genesisHash.Hex()+"/"+allocAddr.Hex(), nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/json"))
+		var res gateway.ProofResponse
+		err = json.Unmarshal(rec.Body.Bytes(), &res)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(res.AccountProof)).To(Equal(len(expected)))
+		for i, node := range res.AccountProof {
+			Expect([]byte(node)).To(Equal(expected[i]))
+		}
+	})
+
+	It("Returns 404 for a block that has not been indexed", func() {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, gateway.ProofPath+common.HexToHash("0x01").Hex()+"/"+allocAddr.Hex(), nil))
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("Returns 400 for an invalid block hash or address", func() {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, gateway.ProofPath+"0x01/"+allocAddr.Hex(), nil))
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, gateway.ProofPath+genesisHash.Hex()+"/not-an-address", nil))
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	})
+})
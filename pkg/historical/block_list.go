@@ -0,0 +1,130 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package historical
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+)
+
+// ParseBlockList parses an explicit list of block numbers from the provided entries and, if it is not empty, the file
+// at the provided path; entries and lines can hold several numbers separated by commas or whitespace, and anything
+// after a # on a line of the file is a comment
+// the numbers are returned sorted and without duplicates, an error is returned for any that is not a valid block number
+func ParseBlockList(entries []string, path string) ([]uint64, error) {
+	fields := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		fields = append(fields, splitBlockList(entry)...)
+	}
+	if path != "" {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading block list file %s: %v", path, err)
+		}
+		for _, line := range strings.Split(string(contents), "\n") {
+			if i := strings.Index(line, "#"); i >= 0 {
+				line = line[:i]
+			}
+			fields = append(fields, splitBlockList(line)...)
+		}
+	}
+	seen := make(map[uint64]bool, len(fields))
+	blocks := make([]uint64, 0, len(fields))
+	for _, field := range fields {
+		number, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block number %q in block list", field)
+		}
+		if !seen[number] {
+			seen[number] = true
+			blocks = append(blocks, number)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i] < blocks[j] })
+	return blocks, nil
+}
+
+func splitBlockList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\r'
+	})
+}
+
+// blockGaps returns the sorted block numbers as gaps, one for each run of consecutive numbers
+func blockGaps(blocks []uint64) []eth.DBGap {
+	var gaps []eth.DBGap
+	for _, block := range blocks {
+		if len(gaps) > 0 && gaps[len(gaps)-1].Stop+1 == block {
+			gaps[len(gaps)-1].Stop = block
+			continue
+		}
+		gaps = append(gaps, eth.DBGap{Start: block, Stop: block})
+	}
+	return gaps
+}
+
+// checkBlocks splits the listed Blocks into those that are in the chain and those above the head of the chain
+func (bfs *Service) checkBlocks() ([]uint64, []uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), bfs.Timeout)
+	defer cancel()
+	head, err := bfs.HeadClient.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	headHeight := head.Number.Uint64()
+	// the blocks are sorted, so those above the head are all at the end
+	i := sort.Search(len(bfs.Blocks), func(i int) bool { return bfs.Blocks[i] > headHeight })
+	return bfs.Blocks[:i], bfs.Blocks[i:], nil
+}
+
+// syncBlocks backfills only the listed Blocks, in a single pass, instead of periodically checking for gaps
+// the blocks are checked against the head of the chain first, and those not in the chain are reported and skipped
+func (bfs *Service) syncBlocks(wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		found, missing, err := bfs.checkBlocks()
+		if err != nil {
+			log.Errorf("ethereum backfill error checking the listed blocks against the head of the chain: %v", err)
+			return
+		}
+		log.Infof("ethereum backfill found %d of the %d listed blocks in the chain", len(found), len(bfs.Blocks))
+		if len(missing) > 0 {
+			log.Warnf("ethereum backfill skipping %d listed blocks that are not in the chain: %v", len(missing), missing)
+		}
+		gaps := blockGaps(found)
+		prog := newProgress(gaps)
+		prog.run(bfs.progressFrequency())
+		quit := bfs.fillGaps(wg, gaps, prog)
+		prog.stop()
+		if quit {
+			log.Info("quiting ethereum backfill process")
+			return
+		}
+		log.Infof("ethereum backfill finished processing the %d listed blocks", len(found))
+	}()
+	log.Infof("ethereum backfill process successfully spun up to process %d listed blocks with %d workers", len(bfs.Blocks), bfs.Workers)
+}
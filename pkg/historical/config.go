@@ -24,6 +24,7 @@ import (
 
 	"github.com/spf13/viper"
 
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/node"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
@@ -32,10 +33,21 @@ import (
 
 // Env variables
 const (
-	BACKFILL_FREQUENCY        = "BACKFILL_FREQUENCY"
-	BACKFILL_BATCH_SIZE       = "BACKFILL_BATCH_SIZE"
-	BACKFILL_WORKERS          = "BACKFILL_WORKERS"
-	BACKFILL_VALIDATION_LEVEL = "BACKFILL_VALIDATION_LEVEL"
+	BACKFILL_FREQUENCY          = "BACKFILL_FREQUENCY"
+	BACKFILL_BATCH_SIZE         = "BACKFILL_BATCH_SIZE"
+	BACKFILL_WORKERS            = "BACKFILL_WORKERS"
+	BACKFILL_VALIDATION_LEVEL   = "BACKFILL_VALIDATION_LEVEL"
+	BACKFILL_PROGRESS_FREQUENCY = "BACKFILL_PROGRESS_FREQUENCY"
+	BACKFILL_TAIL_DISTANCE      = "BACKFILL_TAIL_DISTANCE"
+	BACKFILL_MODE_SWITCH_PASSES = "BACKFILL_MODE_SWITCH_PASSES"
+	BACKFILL_JITTER             = "BACKFILL_JITTER"
+	BACKFILL_HEADERS_ONLY       = "BACKFILL_HEADERS_ONLY"
+	BACKFILL_PARTITIONS         = "BACKFILL_PARTITIONS"
+	BACKFILL_PERSIST_PAYLOADS   = "BACKFILL_PERSIST_PAYLOADS"
+	BACKFILL_EVENT_SOCKET       = "BACKFILL_EVENT_SOCKET"
+	BACKFILL_BLOCKS             = "BACKFILL_BLOCKS"
+	BACKFILL_BLOCKS_FILE        = "BACKFILL_BLOCKS_FILE"
+	BACKFILL_DEFER_CONSTRAINTS  = "BACKFILL_DEFER_CONSTRAINTS"
 
 	BACKFILL_MAX_IDLE_CONNECTIONS = "BACKFILL_MAX_IDLE_CONNECTIONS"
 	BACKFILL_MAX_OPEN_CONNECTIONS = "BACKFILL_MAX_OPEN_CONNECTIONS"
@@ -46,14 +58,26 @@ const (
 type Config struct {
 	DBConfig postgres.Config
 
-	DB              *postgres.DB
-	HTTPClient      *rpc.Client
-	Frequency       time.Duration
-	BatchSize       uint64
-	Workers         uint64
-	ValidationLevel int
-	Timeout         time.Duration // HTTP connection timeout in seconds
-	NodeInfo        node.Info
+	DB                  *postgres.DB
+	HTTPClient          *rpc.Client
+	HTTPPath            string // The url HTTPClient was dialed at, each partition dials a client of its own
+	Frequency           time.Duration
+	ProgressFrequency   time.Duration
+	BatchSize           uint64
+	Workers             uint64
+	ValidationLevel     int
+	TailDistance        uint64            // How many blocks behind head to follow the chain once there are no gaps, 0 disables this
+	ModeSwitchThreshold int               // How many consecutive passes must agree before switching modes
+	Jitter              float64           // Percentage of the frequency by which each gap check is randomly offset
+	HeadersOnly         bool              // Only index headers and uncles, deferring the rest of each block to a later full pass
+	Partitions          int               // If greater than one, split each pass into this many contiguous ranges with their own workers
+	PersistPayloads     bool              // Also store the raw payload of each block in eth.payloads, so it can be reprocessed without a node
+	EventSocket         string            // If set, a JSON event is written to the consumers of this Unix socket for each committed block
+	Blocks              []uint64          // If not empty, only these blocks are backfilled, in a single pass, instead of the gaps
+	DeferConstraints    bool              // Check the foreign key constraints of each block's tx when it commits, for faster bulk loads
+	Multihashes         map[uint64]uint64 // The hash function each IPLD node type is keyed under, keyed by its multicodec
+	Timeout             time.Duration     // HTTP connection timeout in seconds
+	NodeInfo            node.Info
 }
 
 // NewConfig is used to initialize a historical config from a .toml file
@@ -66,6 +90,17 @@ func NewConfig() (*Config, error) {
 	viper.BindEnv("backfill.batchSize", BACKFILL_BATCH_SIZE)
 	viper.BindEnv("backfill.workers", BACKFILL_WORKERS)
 	viper.BindEnv("backfill.validationLevel", BACKFILL_VALIDATION_LEVEL)
+	viper.BindEnv("backfill.progressFrequency", BACKFILL_PROGRESS_FREQUENCY)
+	viper.BindEnv("backfill.tailDistance", BACKFILL_TAIL_DISTANCE)
+	viper.BindEnv("backfill.modeSwitchPasses", BACKFILL_MODE_SWITCH_PASSES)
+	viper.BindEnv("backfill.jitter", BACKFILL_JITTER)
+	viper.BindEnv("backfill.headersOnly", BACKFILL_HEADERS_ONLY)
+	viper.BindEnv("backfill.persistPayloads", BACKFILL_PERSIST_PAYLOADS)
+	viper.BindEnv("backfill.eventSocket", BACKFILL_EVENT_SOCKET)
+	viper.BindEnv("backfill.blocks", BACKFILL_BLOCKS)
+	viper.BindEnv("backfill.blocksFile", BACKFILL_BLOCKS_FILE)
+	viper.BindEnv("backfill.deferConstraints", BACKFILL_DEFER_CONSTRAINTS)
+	viper.BindEnv("backfill.partitions", BACKFILL_PARTITIONS)
 	viper.BindEnv("backfill.timeout", shared.HTTP_TIMEOUT)
 
 	timeout := viper.GetInt("backfill.timeout")
@@ -82,12 +117,31 @@ func NewConfig() (*Config, error) {
 		frequency = time.Second * time.Duration(freq)
 	}
 	c.Frequency = frequency
+	c.ProgressFrequency = time.Second * time.Duration(viper.GetInt("backfill.progressFrequency"))
 	c.BatchSize = uint64(viper.GetInt64("backfill.batchSize"))
 	c.Workers = uint64(viper.GetInt64("backfill.workers"))
 	c.ValidationLevel = viper.GetInt("backfill.validationLevel")
+	c.TailDistance = uint64(viper.GetInt64("backfill.tailDistance"))
+	c.ModeSwitchThreshold = viper.GetInt("backfill.modeSwitchPasses")
+	c.Jitter = viper.GetFloat64("backfill.jitter")
+	if c.Jitter < 0 || c.Jitter > 100 {
+		return nil, fmt.Errorf("backfill jitter must be a percentage between 0 and 100, got %v", c.Jitter)
+	}
+	c.HeadersOnly = viper.GetBool("backfill.headersOnly")
+	c.PersistPayloads = viper.GetBool("backfill.persistPayloads")
+	c.EventSocket = viper.GetString("backfill.eventSocket")
+	c.DeferConstraints = viper.GetBool("backfill.deferConstraints")
+	if c.Multihashes, err = eth.ParseMultihashes(shared.MultihashEntries()); err != nil {
+		return nil, err
+	}
+	c.Partitions = viper.GetInt("backfill.partitions")
+	if c.Blocks, err = ParseBlockList(viper.GetStringSlice("backfill.blocks"), viper.GetString("backfill.blocksFile")); err != nil {
+		return nil, err
+	}
 
 	ethHTTP := viper.GetString("ethereum.httpPath")
-	c.NodeInfo, c.HTTPClient, err = shared.GetEthNodeAndClient(fmt.Sprintf("http://%s", ethHTTP))
+	c.HTTPPath = shared.EthEndpoint(ethHTTP, "http")
+	c.NodeInfo, c.HTTPClient, err = shared.GetEthNodeAndClient(c.HTTPPath)
 	if err != nil {
 		return nil, err
 	}
@@ -103,7 +157,14 @@ func overrideDBConnConfig(con *postgres.Config) {
 	viper.BindEnv("database.backfill.maxIdle", BACKFILL_MAX_IDLE_CONNECTIONS)
 	viper.BindEnv("database.backfill.maxOpen", BACKFILL_MAX_OPEN_CONNECTIONS)
 	viper.BindEnv("database.backfill.maxLifetime", BACKFILL_MAX_CONN_LIFETIME)
-	con.MaxIdle = viper.GetInt("database.backfill.maxIdle")
-	con.MaxOpen = viper.GetInt("database.backfill.maxOpen")
-	con.MaxLifetime = viper.GetInt("database.backfill.maxLifetime")
+	// only override the general pool settings with command specific ones if they have been set
+	if maxIdle := viper.GetInt("database.backfill.maxIdle"); maxIdle > 0 {
+		con.MaxIdle = maxIdle
+	}
+	if maxOpen := viper.GetInt("database.backfill.maxOpen"); maxOpen > 0 {
+		con.MaxOpen = maxOpen
+	}
+	if maxLifetime := viper.GetInt("database.backfill.maxLifetime"); maxLifetime > 0 {
+		con.MaxLifetime = maxLifetime
+	}
 }
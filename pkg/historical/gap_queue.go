@@ -0,0 +1,65 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package historical
+
+import (
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+)
+
+// gapQueue hands out the gaps found in a pass as uniformly sized batches of heights, regardless of how the gaps are sized
+type gapQueue struct {
+	gaps []eth.DBGap
+}
+
+// newGapQueue returns a queue of the provided gaps, which are checked out in order
+func newGapQueue(gaps []eth.DBGap) *gapQueue {
+	queued := make([]eth.DBGap, len(gaps))
+	copy(queued, gaps)
+	return &gapQueue{gaps: queued}
+}
+
+// checkout removes and returns the next gap if it has no more than size heights, otherwise it returns its first size
+// heights and returns the remaining range to the front of the queue
+func (q *gapQueue) checkout(size uint64) (eth.DBGap, bool) {
+	if len(q.gaps) == 0 || size == 0 {
+		return eth.DBGap{}, false
+	}
+	gap := q.gaps[0]
+	if gap.Stop-gap.Start+1 <= size {
+		q.gaps = q.gaps[1:]
+		return gap, true
+	}
+	q.gaps[0].Start = gap.Start + size
+	return eth.DBGap{Start: gap.Start, Stop: gap.Start + size - 1}, true
+}
+
+// nextBatch returns the next batch of up to size heights, filled from as many gaps as it takes
+// so that a run of small gaps is fetched in full batches rather than one small batch per gap
+// it returns an empty batch once the queue is empty
+func (q *gapQueue) nextBatch(size uint64) []uint64 {
+	heights := make([]uint64, 0, size)
+	for remaining := size; remaining > 0; remaining = size - uint64(len(heights)) {
+		gap, ok := q.checkout(remaining)
+		if !ok {
+			break
+		}
+		for height := gap.Start; height <= gap.Stop; height++ {
+			heights = append(heights, height)
+		}
+	}
+	return heights
+}
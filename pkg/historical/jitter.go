@@ -0,0 +1,50 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package historical
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// bounds of the exponential backoff between retries of a fetch the node has rate limited
+	minRateLimitBackoff = time.Second
+	maxRateLimitBackoff = time.Minute
+	// how many times a rate limited fetch is retried before its error is returned
+	maxRateLimitRetries = 5
+)
+
+// the jitter source is seeded per process, so that indexers started at the same time draw different offsets
+var (
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	jitterLock sync.Mutex
+)
+
+// jitter returns the duration randomly offset by up to the provided percentage of itself, in either direction
+func jitter(d time.Duration, percent float64) time.Duration {
+	if percent <= 0 || d <= 0 {
+		return d
+	}
+	jitterLock.Lock()
+	r := jitterRand.Float64()
+	jitterLock.Unlock()
+	// r is in [0, 1), scale it to an offset in [-percent, percent) of d
+	offset := time.Duration(float64(d) * percent / 100 * (2*r - 1))
+	return d + offset
+}
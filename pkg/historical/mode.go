@@ -0,0 +1,86 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package historical
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// mode is the way in which the backfill process decides which heights to process each pass
+type mode int
+
+const (
+	// gapFilling processes the gaps found in the indexed data
+	gapFilling mode = iota
+	// tailFollowing processes the heights between the highest indexed block and a few blocks behind the head of the chain
+	tailFollowing
+)
+
+func (m mode) String() string {
+	switch m {
+	case gapFilling:
+		return "gap-filling"
+	case tailFollowing:
+		return "tail-following"
+	default:
+		return "unknown"
+	}
+}
+
+// coordinator transitions the backfill process between gap-filling and tail-following modes
+// it only switches modes once the result of the gap search has held for threshold consecutive passes,
+// so that a transient gap which is filled before the next pass doesn't cause it to flap between modes
+type coordinator struct {
+	mode        mode
+	threshold   int
+	emptyPasses int
+	gapPasses   int
+}
+
+// newCoordinator returns a coordinator that starts in gap-filling mode
+func newCoordinator(threshold int) *coordinator {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &coordinator{
+		mode:      gapFilling,
+		threshold: threshold,
+	}
+}
+
+// observe records the number of gaps found in a pass and returns the mode to use for it
+func (c *coordinator) observe(gapCount int) mode {
+	if gapCount == 0 {
+		c.gapPasses = 0
+		c.emptyPasses++
+		if c.mode == gapFilling && c.emptyPasses >= c.threshold {
+			c.transition(tailFollowing)
+		}
+		return c.mode
+	}
+	c.emptyPasses = 0
+	c.gapPasses++
+	if c.mode == tailFollowing && c.gapPasses >= c.threshold {
+		c.transition(gapFilling)
+	}
+	return c.mode
+}
+
+func (c *coordinator) transition(m mode) {
+	log.Infof("ethereum backfill switching from %s to %s mode", c.mode.String(), m.String())
+	c.mode = m
+}
@@ -0,0 +1,99 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package historical
+
+import (
+	"sync"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+)
+
+// PartitionFactory returns the fetcher and transformer a backfill partition is processed with
+type PartitionFactory func() (eth.Fetcher, eth.Transformer, error)
+
+// partitionGaps splits the heights covered by the gaps into at most n contiguous partitions, whose sizes differ by at
+// most one block, each partition holding the gaps, or the slices of gaps, that cover its range
+func partitionGaps(gaps []eth.DBGap, n int) [][]eth.DBGap {
+	_, total := gapStats(gaps)
+	if n <= 0 || total == 0 {
+		return nil
+	}
+	size, remainder := total/uint64(n), total%uint64(n)
+	queue := newGapQueue(gaps)
+	partitions := make([][]eth.DBGap, 0, n)
+	for i := 0; i < n; i++ {
+		want := size
+		if uint64(i) < remainder {
+			want++
+		}
+		if want == 0 {
+			break
+		}
+		partition := make([]eth.DBGap, 0)
+		for want > 0 {
+			gap, ok := queue.checkout(want)
+			if !ok {
+				break
+			}
+			partition = append(partition, gap)
+			want -= gapSize(gap)
+		}
+		partitions = append(partitions, partition)
+	}
+	return partitions
+}
+
+// backFillPartitions backfills each partition of the gaps with a worker of its own and waits for them all to finish
+// each block is reflected in the gaps as soon as it is committed, and a partition logs when it has been completed
+// it returns true if the backfill was stopped before the partitions were completed
+func (bfs *Service) backFillPartitions(gaps []eth.DBGap, prog *progress) bool {
+	var quit int32
+	wg := new(sync.WaitGroup)
+	for i, partition := range partitionGaps(gaps, bfs.Partitions) {
+		wg.Add(1)
+		go func(id int, partition []eth.DBGap) {
+			defer wg.Done()
+			fetcher, transformer := bfs.Fetcher, bfs.Transformer
+			if bfs.NewPartition != nil {
+				var err error
+				if fetcher, transformer, err = bfs.NewPartition(); err != nil {
+					log.Errorf("ethereum backfill partition %d error creating its fetcher and transformer: %v", id, err)
+					return
+				}
+			}
+			start, stop := partition[0].Start, partition[len(partition)-1].Stop
+			log.Infof("ethereum backfill partition %d backfilling from %d to %d", id, start, stop)
+			queue := newGapQueue(partition)
+			for heights := queue.nextBatch(bfs.BatchSize); len(heights) > 0; heights = queue.nextBatch(bfs.BatchSize) {
+				select {
+				case <-bfs.QuitChan:
+					atomic.StoreInt32(&quit, 1)
+					log.Infof("ethereum backfill partition %d shutting down", id)
+					return
+				default:
+					bfs.process(id, fetcher, transformer, heights, prog)
+				}
+			}
+			log.Infof("ethereum backfill partition %d finished from %d to %d", id, start, stop)
+		}(i+1, partition)
+	}
+	wg.Wait()
+	return atomic.LoadInt32(&quit) == 1
+}
@@ -0,0 +1,168 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package historical
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/prom"
+)
+
+// progress tracks how far a backfill pass has gotten through the gaps it found
+// it is safe for concurrent use by the backfill workers
+type progress struct {
+	total     uint64
+	processed uint64
+	// the number of blocks whose payload could not be transformed, these are not counted as processed
+	failed  uint64
+	current uint64
+	start   time.Time
+	quit    chan struct{}
+
+	// if true, the gaps gauges are updated as each of the gaps is completed
+	// this is only the case when the gaps are the ones found in the db, not when following the tail of the chain
+	reportGaps bool
+	gapsLock   sync.Mutex
+	gaps       []eth.DBGap
+	// the number of blocks left to process in each of the gaps
+	gapRemaining []uint64
+	openGaps     uint64
+	// the number of blocks left to process across all of the gaps
+	remaining uint64
+}
+
+// newProgress returns a progress tracker for a pass over the provided gaps
+func newProgress(gaps []eth.DBGap) *progress {
+	var total uint64
+	gapRemaining := make([]uint64, len(gaps))
+	for i, gap := range gaps {
+		gapRemaining[i] = gapSize(gap)
+		total += gapRemaining[i]
+	}
+	return &progress{
+		total:        total,
+		start:        time.Now(),
+		quit:         make(chan struct{}),
+		gaps:         gaps,
+		gapRemaining: gapRemaining,
+		openGaps:     uint64(len(gaps)),
+		remaining:    total,
+	}
+}
+
+// gapSize returns the number of blocks in the gap
+func gapSize(gap eth.DBGap) uint64 {
+	if gap.Stop < gap.Start {
+		return 0
+	}
+	return gap.Stop - gap.Start + 1
+}
+
+// gapStats returns the number of gaps and the total number of blocks missing from them
+func gapStats(gaps []eth.DBGap) (uint64, uint64) {
+	var blocks uint64
+	for _, gap := range gaps {
+		blocks += gapSize(gap)
+	}
+	return uint64(len(gaps)), blocks
+}
+
+// increment records that a block has been processed, tracking the highest processed height as the current block
+func (p *progress) increment(height uint64) {
+	atomic.AddUint64(&p.processed, 1)
+	if p.reportGaps {
+		p.completeGapBlock(height)
+	}
+	for {
+		current := atomic.LoadUint64(&p.current)
+		if height <= current || atomic.CompareAndSwapUint64(&p.current, current, height) {
+			return
+		}
+	}
+}
+
+// fail records that a block could not be transformed, it is neither processed nor left remaining in this pass
+func (p *progress) fail() {
+	atomic.AddUint64(&p.failed, 1)
+}
+
+// completeGapBlock records that a block in one of the gaps has been processed, updating the gaps gauges once it completes the gap
+func (p *progress) completeGapBlock(height uint64) {
+	p.gapsLock.Lock()
+	defer p.gapsLock.Unlock()
+	for i, gap := range p.gaps {
+		if height < gap.Start || height > gap.Stop || p.gapRemaining[i] == 0 {
+			continue
+		}
+		p.gapRemaining[i]--
+		p.remaining--
+		if p.gapRemaining[i] == 0 {
+			p.openGaps--
+			prom.SetGaps(p.openGaps, p.remaining)
+		}
+		return
+	}
+}
+
+// report logs the current progress and updates the backfill gauges
+func (p *progress) report() {
+	processed := atomic.LoadUint64(&p.processed)
+	failed := atomic.LoadUint64(&p.failed)
+	current := atomic.LoadUint64(&p.current)
+	var remaining uint64
+	if p.total > processed+failed {
+		remaining = p.total - processed - failed
+	}
+	var rate float64
+	if elapsed := time.Since(p.start).Seconds(); elapsed > 0 {
+		rate = float64(processed) / elapsed
+	}
+	var eta time.Duration
+	if rate > 0 {
+		eta = time.Duration(float64(remaining)/rate) * time.Second
+	}
+	log.Infof("ethereum backfill progress: %d of %d blocks processed, %d failed, current block %d, %.2f blocks/sec, estimated time remaining %s",
+		processed, p.total, failed, current, rate, eta.String())
+	prom.SetBackfillProgress(processed, failed, current, remaining, rate, eta)
+}
+
+// run periodically reports progress until stop is called
+func (p *progress) run(frequency time.Duration) {
+	ticker := time.NewTicker(frequency)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.report()
+			case <-p.quit:
+				p.report()
+				return
+			}
+		}
+	}()
+}
+
+// stop ends periodic reporting, logging the final progress
+func (p *progress) stop() {
+	close(p.quit)
+}
@@ -17,15 +17,20 @@
 package historical
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/statediff"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/prom"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
-	"github.com/vulcanize/ipld-eth-indexer/utils"
 )
 
 // Backfill for filling in gaps in the ipld-eth-indexer db
@@ -45,6 +50,8 @@ type Service struct {
 	Retriever eth.Retriever
 	// Check frequency
 	GapCheckFrequency time.Duration
+	// Progress reporting frequency
+	ProgressFrequency time.Duration
 	// Size of batch fetches
 	BatchSize uint64
 	// Number of goroutines
@@ -55,19 +62,62 @@ type Service struct {
 	ChainConfig *params.ChainConfig
 	// Headers with times_validated lower than this will be resynced
 	validationLevel int
+	// Client for fetching the head of the chain, used in tail-following mode
+	HeadClient eth.HeaderClient
+	// How many blocks behind the head to stay in tail-following mode, 0 disables tail-following
+	TailDistance uint64
+	// How many consecutive passes must agree before switching between gap-filling and tail-following modes
+	ModeSwitchThreshold int
+	// Timeout for fetching the head of the chain
+	Timeout time.Duration
+	// Tracks how far the indexed data trails the head of the chain, updated on each gap check
+	LagTracker *eth.LagTracker
+	// Percentage of GapCheckFrequency by which each gap check is randomly offset, so that a cluster of indexers
+	// started together does not poll the node in lockstep, 0 disables the jitter
+	Jitter float64
+	// If greater than one, the gaps found in each pass are split into this many contiguous partitions of about the same
+	// number of blocks, each backfilled in BatchSize batches by a worker of its own instead of by Workers sharing one queue
+	Partitions int
+	// Returns the fetcher and transformer of a partition, so that each can have its own connection to the node
+	// if nil, the partitions share the Fetcher and Transformer
+	NewPartition PartitionFactory
+	// If not empty, only these blocks are backfilled, in a single pass, whether or not they have already been indexed
+	// they must be sorted and without duplicates, as returned by ParseBlockList
+	Blocks []uint64
+	// Number of workers or partitions currently fetching and transforming a batch
+	busyWorkers int64
 }
 
 // NewBackfillService returns a new BackfillInterface
 func NewBackfillService(settings *Config) (Backfill, error) {
 	bs := new(Service)
 	var err error
-	bs.Fetcher = eth.NewPayloadFetcher(settings.HTTPClient, settings.Timeout)
-	bs.ChainConfig, err = eth.ChainConfig(settings.NodeInfo.ChainID)
+	bs.Fetcher = eth.NewPayloadFetcher(eth.NewRateLimitedBatchClient(settings.HTTPClient, shared.RPCRateLimiter()), settings.Timeout)
+	bs.ChainConfig, err = eth.ResolveChainConfig(settings.HTTPClient, settings.NodeInfo.ChainID, shared.ChainConfigFromNode())
 	if err != nil {
 		return nil, err
 	}
-	bs.Transformer = eth.NewStateDiffTransformer(bs.ChainConfig, settings.DB)
-	bs.Retriever = eth.NewGapRetriever(settings.DB)
+	// the partitions share the one socket
+	var eventSink eth.BlockEventSink
+	if settings.EventSocket != "" {
+		emitter, err := eth.NewUnixSocketEmitter(settings.EventSocket)
+		if err != nil {
+			return nil, err
+		}
+		eventSink = emitter
+	}
+	transformer := eth.NewStateDiffTransformer(bs.ChainConfig, settings.DB)
+	if err := transformer.SetMultihashes(settings.Multihashes); err != nil {
+		return nil, err
+	}
+	transformer.HeadersOnly = settings.HeadersOnly
+	transformer.PersistPayloads = settings.PersistPayloads
+	transformer.EventSink = eventSink
+	transformer.DeferConstraints = settings.DeferConstraints
+	bs.Transformer = transformer
+	retriever := eth.NewGapRetriever(settings.DB)
+	retriever.HeadersOnly = settings.HeadersOnly
+	bs.Retriever = retriever
 	bs.BatchSize = settings.BatchSize
 	if bs.BatchSize == 0 {
 		bs.BatchSize = shared.DefaultMaxBatchSize
@@ -79,12 +129,43 @@ func NewBackfillService(settings *Config) (Backfill, error) {
 	bs.QuitChan = make(chan bool)
 	bs.validationLevel = settings.ValidationLevel
 	bs.GapCheckFrequency = settings.Frequency
+	bs.ProgressFrequency = settings.ProgressFrequency
+	bs.HeadClient = eth.NewRateLimitedHeaderClient(ethclient.NewClient(settings.HTTPClient), shared.RPCRateLimiter())
+	bs.TailDistance = settings.TailDistance
+	bs.ModeSwitchThreshold = settings.ModeSwitchThreshold
+	bs.Timeout = settings.Timeout
+	bs.LagTracker = eth.NewLagTracker(bs.HeadClient, bs.Retriever, bs.Timeout)
+	bs.Jitter = settings.Jitter
+	bs.Partitions = settings.Partitions
+	bs.Blocks = settings.Blocks
+	bs.NewPartition = func() (eth.Fetcher, eth.Transformer, error) {
+		client, err := rpc.Dial(settings.HTTPPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		partitionTransformer := eth.NewStateDiffTransformer(bs.ChainConfig, settings.DB)
+		if err := partitionTransformer.SetMultihashes(settings.Multihashes); err != nil {
+			return nil, nil, err
+		}
+		partitionTransformer.HeadersOnly = settings.HeadersOnly
+		partitionTransformer.PersistPayloads = settings.PersistPayloads
+		partitionTransformer.EventSink = eventSink
+		partitionTransformer.DeferConstraints = settings.DeferConstraints
+		return eth.NewPayloadFetcher(eth.NewRateLimitedBatchClient(client, shared.RPCRateLimiter()), settings.Timeout), partitionTransformer, nil
+	}
 	return bs, nil
 }
 
 // Sync periodically checks for and fills in gaps in the watcher db
+// if Blocks is set, only those blocks are backfilled instead
 func (bfs *Service) Sync(wg *sync.WaitGroup) {
-	ticker := time.NewTicker(bfs.GapCheckFrequency)
+	if len(bfs.Blocks) > 0 {
+		bfs.syncBlocks(wg)
+		return
+	}
+	// a timer is reset to a newly jittered interval on each check, rather than using a ticker with a fixed interval
+	timer := time.NewTimer(jitter(bfs.GapCheckFrequency, bfs.Jitter))
+	coord := newCoordinator(bfs.ModeSwitchThreshold)
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -93,66 +174,89 @@ func (bfs *Service) Sync(wg *sync.WaitGroup) {
 			case <-bfs.QuitChan:
 				log.Info("quiting ethereum backfill process")
 				return
-			case <-ticker.C:
+			case <-timer.C:
+				timer.Reset(jitter(bfs.GapCheckFrequency, bfs.Jitter))
+				if bfs.LagTracker != nil {
+					if _, err := bfs.LagTracker.Update(); err != nil {
+						log.Errorf("ethereum backfill error updating the indexing lag: %v", err)
+					}
+				}
+				if oldest, err := bfs.Retriever.RetrieveOldestValidation(); err != nil {
+					log.Errorf("ethereum backfill error retrieving the oldest validation time: %v", err)
+				} else if oldest != nil {
+					prom.SetOldestValidation(*oldest)
+				}
 				gaps, err := bfs.Retriever.RetrieveGapsInData(bfs.validationLevel)
 				if err != nil {
 					log.Errorf("ethereum backfill error finding missing data: %v", err)
 					continue
 				}
-				// spin up worker goroutines for this search pass
-				// we start and kill a new batch of workers for each pass
-				// so that we know each of the previous workers is done before we search for new gaps
-				heightsChan := make(chan []uint64)
-				for i := 1; i <= int(bfs.Workers); i++ {
-					go bfs.backFill(wg, i, heightsChan)
-				}
-				for _, gap := range gaps {
-					log.Infof("backfilling historical ethereum data from %d to %d", gap.Start, gap.Stop)
-					blockRangeBins, err := utils.GetBlockHeightBins(gap.Start, gap.Stop, bfs.BatchSize)
-					if err != nil {
-						log.Errorf("ethereum backfill gap binning error: %v", err)
+				prom.SetGaps(gapStats(gaps))
+				dbGaps := true
+				if bfs.TailDistance > 0 && coord.observe(len(gaps)) == tailFollowing {
+					if gaps, err = bfs.tailGaps(); err != nil {
+						log.Errorf("ethereum backfill error finding tail: %v", err)
 						continue
 					}
-					for _, heights := range blockRangeBins {
-						select {
-						case <-bfs.QuitChan:
-							log.Info("quiting ethereum backfill process")
-							return
-						default:
-							heightsChan <- heights
-						}
-					}
+					dbGaps = false
 				}
-				// send a quit signal to each worker
-				// this blocks until each worker has finished its current task and is free to receive from the quit channel
-				for i := 1; i <= int(bfs.Workers); i++ {
-					bfs.QuitChan <- true
+				// track and periodically report our progress through the gaps found in this pass
+				prog := newProgress(gaps)
+				prog.reportGaps = dbGaps
+				prog.run(bfs.progressFrequency())
+				quit := bfs.fillGaps(wg, gaps, prog)
+				prog.stop()
+				if quit {
+					log.Info("quiting ethereum backfill process")
+					return
 				}
 			}
 		}
 	}()
-	log.Info("ethereum backfill process successfully spun up")
+	log.Infof("ethereum backfill process successfully spun up with %d workers per pass", bfs.Workers)
 }
 
-func (bfs *Service) backFill(wg *sync.WaitGroup, id int, heightChan chan []uint64) {
+// fillGaps backfills the gaps found in a single pass, returning true if the service was told to quit part way through
+func (bfs *Service) fillGaps(wg *sync.WaitGroup, gaps []eth.DBGap, prog *progress) bool {
+	if bfs.Partitions > 1 {
+		return bfs.backFillPartitions(gaps, prog)
+	}
+	// spin up worker goroutines for this search pass
+	// we start and kill a new batch of workers for each pass
+	// so that we know each of the previous workers is done before we search for new gaps
+	heightsChan := make(chan []uint64)
+	for i := 1; i <= int(bfs.Workers); i++ {
+		go bfs.backFill(wg, i, heightsChan, prog)
+	}
+	for _, gap := range gaps {
+		log.Infof("backfilling historical ethereum data from %d to %d", gap.Start, gap.Stop)
+	}
+	// the gaps are handed out in batches of BatchSize heights, small gaps are batched together and large ones split
+	queue := newGapQueue(gaps)
+	for heights := queue.nextBatch(bfs.BatchSize); len(heights) > 0; heights = queue.nextBatch(bfs.BatchSize) {
+		select {
+		case <-bfs.QuitChan:
+			return true
+		default:
+			heightsChan <- heights
+			prom.SetBackfillBusyWorkers(atomic.LoadInt64(&bfs.busyWorkers))
+		}
+	}
+	// send a quit signal to each worker
+	// this blocks until each worker has finished its current task and is free to receive from the quit channel
+	for i := 1; i <= int(bfs.Workers); i++ {
+		bfs.QuitChan <- true
+	}
+	return false
+}
+
+func (bfs *Service) backFill(wg *sync.WaitGroup, id int, heightChan chan []uint64, prog *progress) {
 	wg.Add(1)
 	defer wg.Done()
 	for {
 		select {
 		case heights := <-heightChan:
-			log.Debugf("ethereum backfill worker %d processing section from %d to %d", id, heights[0], heights[len(heights)-1])
-			payloads, err := bfs.Fetcher.FetchAt(heights)
-			if err != nil {
-				log.Errorf("ethereum backfill worker %d fetcher error: %s", id, err.Error())
-			}
-			for _, payload := range payloads {
-				blockNumber, err := bfs.Transformer.Transform(id, payload)
-				if err != nil {
-					log.Errorf("ethereum backfill worker %d transformer error: %s", id, err.Error())
-				}
-				log.Infof("ethereum backfill worker %d transformed data at height %d", id, blockNumber)
-			}
-			log.Infof("ethereum backfill worker %d finished section from %d to %d", id, heights[0], heights[len(heights)-1])
+			bfs.process(id, bfs.Fetcher, bfs.Transformer, heights, prog)
 		case <-bfs.QuitChan:
 			log.Infof("ethereum backfill worker %d shutting down", id)
 			return
@@ -160,6 +264,79 @@ func (bfs *Service) backFill(wg *sync.WaitGroup, id int, heightChan chan []uint6
 	}
 }
 
+// process fetches and transforms the payloads of a batch of heights
+func (bfs *Service) process(id int, fetcher eth.Fetcher, transformer eth.Transformer, heights []uint64, prog *progress) {
+	prom.SetBackfillBusyWorkers(atomic.AddInt64(&bfs.busyWorkers, 1))
+	defer func() {
+		prom.SetBackfillBusyWorkers(atomic.AddInt64(&bfs.busyWorkers, -1))
+	}()
+	log.Debugf("ethereum backfill worker %d processing section from %d to %d", id, heights[0], heights[len(heights)-1])
+	payloads, err := bfs.fetchAt(id, fetcher, heights)
+	if err != nil {
+		log.Errorf("ethereum backfill worker %d fetcher error: %s", id, err.Error())
+	}
+	for _, payload := range payloads {
+		blockNumber, err := transformer.Transform(id, payload)
+		if err != nil {
+			// the block is left missing, to be found as a gap again by a later pass
+			log.Errorf("ethereum backfill worker %d transformer error: %s", id, err.Error())
+			prog.fail()
+			continue
+		}
+		log.Infof("ethereum backfill worker %d transformed data at height %d", id, blockNumber)
+		prog.increment(blockNumber)
+	}
+	log.Infof("ethereum backfill worker %d finished section from %d to %d", id, heights[0], heights[len(heights)-1])
+}
+
+// fetchAt fetches the payloads at the provided heights, backing off exponentially and retrying while the node is
+// rate limiting us, up to maxRateLimitRetries times
+func (bfs *Service) fetchAt(id int, fetcher eth.Fetcher, heights []uint64) ([]statediff.Payload, error) {
+	backoff := minRateLimitBackoff
+	for retry := 0; ; retry++ {
+		payloads, err := fetcher.FetchAt(heights)
+		if err == nil || !eth.IsRateLimitError(err) || retry == maxRateLimitRetries {
+			return payloads, err
+		}
+		log.Warnf("ethereum backfill worker %d is being rate limited, retrying in %s: %v", id, backoff, err)
+		time.Sleep(jitter(backoff, bfs.Jitter))
+		if backoff *= 2; backoff > maxRateLimitBackoff {
+			backoff = maxRateLimitBackoff
+		}
+	}
+}
+
+// tailGaps returns the range from the block after the highest indexed block up to TailDistance blocks behind the head
+func (bfs *Service) tailGaps() ([]eth.DBGap, error) {
+	last, err := bfs.Retriever.RetrieveLastBlockNumber()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), bfs.Timeout)
+	defer cancel()
+	head, err := bfs.HeadClient.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	headHeight := head.Number.Uint64()
+	if headHeight < bfs.TailDistance {
+		return nil, nil
+	}
+	start, stop := uint64(last)+1, headHeight-bfs.TailDistance
+	if stop < start {
+		return nil, nil
+	}
+	return []eth.DBGap{{Start: start, Stop: stop}}, nil
+}
+
+// progressFrequency returns how often to report backfill progress, defaulting to 30 seconds
+func (bfs *Service) progressFrequency() time.Duration {
+	if bfs.ProgressFrequency <= 0 {
+		return time.Second * 30
+	}
+	return bfs.ProgressFrequency
+}
+
 func (bfs *Service) Stop() error {
 	log.Info("stopping ethereum backfill service")
 	close(bfs.QuitChan)
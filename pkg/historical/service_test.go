@@ -17,9 +17,13 @@
 package historical_test
 
 import (
+	"io/ioutil"
+	"math/big"
+	"os"
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/statediff"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -156,4 +160,230 @@ var _ = Describe("BackFiller", func() {
 			Expect(mockFetcher.CalledAtBlockHeights[0]).To(Equal([]uint64{0, 1, 2}))
 		})
 	})
+
+	Describe("Batching", func() {
+		It("Fills each batch from as many gaps as it takes and splits gaps larger than a batch", func() {
+			mockTransformer := &mocks.IterativeTransformer{
+				ReturnErr:     nil,
+				ReturnHeights: []uint64{100, 102, 103, 104, 105},
+			}
+			mockRetriever := &mocks.Retriever{
+				FirstBlockNumberToReturn: 0,
+				GapsToRetrieve: []eth.DBGap{
+					{Start: 100, Stop: 100},
+					{Start: 102, Stop: 105},
+				},
+			}
+			mockFetcher := &mocks.PayloadFetcher{
+				PayloadsToReturn: map[uint64]statediff.Payload{
+					100: mocks.MockStateDiffPayload,
+					102: mocks.MockStateDiffPayload,
+					103: mocks.MockStateDiffPayload,
+					104: mocks.MockStateDiffPayload,
+					105: mocks.MockStateDiffPayload,
+				},
+			}
+			quitChan := make(chan bool, 1)
+			backfiller := &historical.Service{
+				Transformer:       mockTransformer,
+				Fetcher:           mockFetcher,
+				Retriever:         mockRetriever,
+				GapCheckFrequency: time.Second * 2,
+				BatchSize:         3,
+				Workers:           1,
+				QuitChan:          quitChan,
+			}
+			wg := &sync.WaitGroup{}
+			backfiller.Sync(wg)
+			time.Sleep(time.Second * 3)
+			quitChan <- true
+			Expect(len(mockTransformer.PassedStateDiffs)).To(Equal(5))
+			Expect(mockFetcher.CalledAtBlockHeights).To(Equal([][]uint64{{100, 102, 103}, {104, 105}}))
+		})
+	})
+
+	Describe("Partitions", func() {
+		It("Backfills contiguous partitions of the gaps with a fetcher and transformer each", func() {
+			payloads := map[uint64]statediff.Payload{
+				100: mocks.MockStateDiffPayload,
+				101: mocks.MockStateDiffPayload,
+				102: mocks.MockStateDiffPayload,
+				103: mocks.MockStateDiffPayload,
+			}
+			fetchers := make([]*mocks.PayloadFetcher, 0)
+			transformers := make([]*mocks.IterativeTransformer, 0)
+			lock := new(sync.Mutex)
+			quitChan := make(chan bool, 1)
+			backfiller := &historical.Service{
+				Retriever: &mocks.Retriever{
+					GapsToRetrieve: []eth.DBGap{{Start: 100, Stop: 103}},
+				},
+				GapCheckFrequency: time.Second * 2,
+				BatchSize:         shared.DefaultMaxBatchSize,
+				Partitions:        2,
+				NewPartition: func() (eth.Fetcher, eth.Transformer, error) {
+					lock.Lock()
+					defer lock.Unlock()
+					fetcher := &mocks.PayloadFetcher{PayloadsToReturn: payloads}
+					transformer := &mocks.IterativeTransformer{ReturnHeights: []uint64{0, 0}}
+					fetchers = append(fetchers, fetcher)
+					transformers = append(transformers, transformer)
+					return fetcher, transformer, nil
+				},
+				QuitChan: quitChan,
+			}
+			wg := &sync.WaitGroup{}
+			backfiller.Sync(wg)
+			time.Sleep(time.Second * 3)
+			quitChan <- true
+			lock.Lock()
+			defer lock.Unlock()
+			Expect(len(fetchers)).To(Equal(2))
+			fetched := make([][]uint64, 0)
+			for i, fetcher := range fetchers {
+				Expect(len(fetcher.CalledAtBlockHeights)).To(Equal(1))
+				fetched = append(fetched, fetcher.CalledAtBlockHeights[0])
+				Expect(len(transformers[i].PassedStateDiffs)).To(Equal(2))
+			}
+			Expect(fetched).To(ConsistOf([]uint64{100, 101}, []uint64{102, 103}))
+		})
+	})
+
+	Describe("TailFollowing", func() {
+		It("Follows the chain behind head once there are no gaps", func() {
+			mockTransformer := &mocks.IterativeTransformer{
+				ReturnErr:     nil,
+				ReturnHeights: []uint64{101, 102, 103},
+			}
+			mockRetriever := &mocks.Retriever{
+				LastBlockNumberToReturn: 100,
+				GapsToRetrieve:          []eth.DBGap{},
+			}
+			mockFetcher := &mocks.PayloadFetcher{
+				PayloadsToReturn: map[uint64]statediff.Payload{
+					101: mocks.MockStateDiffPayload,
+					102: mocks.MockStateDiffPayload,
+					103: mocks.MockStateDiffPayload,
+				},
+			}
+			mockHeadClient := &mocks.HeaderClient{
+				HeadToReturn: &types.Header{Number: big.NewInt(105)},
+			}
+			quitChan := make(chan bool, 1)
+			backfiller := &historical.Service{
+				Transformer:         mockTransformer,
+				Fetcher:             mockFetcher,
+				Retriever:           mockRetriever,
+				HeadClient:          mockHeadClient,
+				TailDistance:        2,
+				ModeSwitchThreshold: 1,
+				Timeout:             time.Second,
+				GapCheckFrequency:   time.Second * 2,
+				BatchSize:           shared.DefaultMaxBatchSize,
+				Workers:             shared.DefaultMaxBatchNumber,
+				QuitChan:            quitChan,
+			}
+			wg := &sync.WaitGroup{}
+			backfiller.Sync(wg)
+			time.Sleep(time.Second * 3)
+			quitChan <- true
+			Expect(len(mockTransformer.PassedStateDiffs)).To(Equal(3))
+			Expect(mockRetriever.CalledTimes).To(Equal(1))
+			Expect(len(mockFetcher.CalledAtBlockHeights)).To(Equal(1))
+			Expect(mockFetcher.CalledAtBlockHeights[0]).To(Equal([]uint64{101, 102, 103}))
+		})
+
+		It("Doesn't switch to tail-following until the gap search has come up empty enough times", func() {
+			mockRetriever := &mocks.Retriever{
+				LastBlockNumberToReturn: 100,
+				GapsToRetrieve:          []eth.DBGap{},
+			}
+			mockFetcher := &mocks.PayloadFetcher{
+				PayloadsToReturn: map[uint64]statediff.Payload{},
+			}
+			quitChan := make(chan bool, 1)
+			backfiller := &historical.Service{
+				Transformer:         &mocks.IterativeTransformer{},
+				Fetcher:             mockFetcher,
+				Retriever:           mockRetriever,
+				HeadClient:          &mocks.HeaderClient{HeadToReturn: &types.Header{Number: big.NewInt(105)}},
+				TailDistance:        2,
+				ModeSwitchThreshold: 3,
+				Timeout:             time.Second,
+				GapCheckFrequency:   time.Second * 2,
+				BatchSize:           shared.DefaultMaxBatchSize,
+				Workers:             shared.DefaultMaxBatchNumber,
+				QuitChan:            quitChan,
+			}
+			wg := &sync.WaitGroup{}
+			backfiller.Sync(wg)
+			time.Sleep(time.Second * 3)
+			quitChan <- true
+			Expect(mockRetriever.CalledTimes).To(Equal(1))
+			Expect(mockFetcher.CalledAtBlockHeights).To(BeEmpty())
+		})
+
+		It("Only backfills the listed blocks that are in the chain, without checking for gaps", func() {
+			mockTransformer := &mocks.IterativeTransformer{
+				ReturnHeights: []uint64{100, 101, 103},
+			}
+			mockRetriever := &mocks.Retriever{
+				GapsToRetrieve: []eth.DBGap{{Start: 1, Stop: 10}},
+			}
+			mockFetcher := &mocks.PayloadFetcher{
+				PayloadsToReturn: map[uint64]statediff.Payload{
+					100: mocks.MockStateDiffPayload,
+					101: mocks.MockStateDiffPayload,
+					103: mocks.MockStateDiffPayload,
+				},
+			}
+			quitChan := make(chan bool, 1)
+			backfiller := &historical.Service{
+				Transformer:       mockTransformer,
+				Fetcher:           mockFetcher,
+				Retriever:         mockRetriever,
+				HeadClient:        &mocks.HeaderClient{HeadToReturn: &types.Header{Number: big.NewInt(150)}},
+				Timeout:           time.Second,
+				GapCheckFrequency: time.Second * 2,
+				BatchSize:         shared.DefaultMaxBatchSize,
+				Workers:           shared.DefaultMaxBatchNumber,
+				QuitChan:          quitChan,
+				Blocks:            []uint64{100, 101, 103, 200},
+			}
+			wg := &sync.WaitGroup{}
+			backfiller.Sync(wg)
+			time.Sleep(time.Second)
+			Expect(len(mockTransformer.PassedStateDiffs)).To(Equal(3))
+			Expect(mockRetriever.CalledTimes).To(Equal(0))
+			Expect(len(mockFetcher.CalledAtBlockHeights)).To(Equal(1))
+			Expect(mockFetcher.CalledAtBlockHeights[0]).To(Equal([]uint64{100, 101, 103}))
+		})
+	})
+
+	Describe("ParseBlockList", func() {
+		It("Parses, sorts and deduplicates the listed and file block numbers", func() {
+			file, err := ioutil.TempFile("", "blocks")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.Remove(file.Name())
+			_, err = file.WriteString("# blocks affected by the bug\n12, 7\n\n3 # and this one\n")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(file.Close()).To(Succeed())
+
+			blocks, err := historical.ParseBlockList([]string{"5,3", "9"}, file.Name())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blocks).To(Equal([]uint64{3, 5, 7, 9, 12}))
+		})
+
+		It("Rejects invalid block numbers", func() {
+			_, err := historical.ParseBlockList([]string{"5", "-1"}, "")
+			Expect(err).To(HaveOccurred())
+			_, err = historical.ParseBlockList([]string{"0x10"}, "")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Returns an error if the file can't be read", func() {
+			_, err := historical.ParseBlockList(nil, "/does/not/exist")
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
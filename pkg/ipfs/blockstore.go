@@ -0,0 +1,152 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ipfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	"github.com/sirupsen/logrus"
+)
+
+// BlockPutPath is the path of the IPFS HTTP API endpoint blocks are written to
+const BlockPutPath = "/api/v0/block/put"
+
+// Defaults for the IPFSBlockstore
+const (
+	DefaultMaxRetries     = 5
+	DefaultInitialBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff     = 30 * time.Second
+	DefaultTimeout        = 30 * time.Second
+)
+
+// ErrCIDMismatch is returned when the CID the IPFS daemon derived for a block is not the CID it was expected to have
+// this is not retried, it means the daemon hashed the block under a different codec or hash function
+var ErrCIDMismatch = errors.New("ipfs daemon returned an unexpected cid")
+
+// IPFSBlockstore writes IPLD blocks to a running IPFS daemon through its HTTP API
+type IPFSBlockstore struct {
+	client *http.Client
+	putURL string
+	// How many times a block is retried while the daemon is unavailable, defaults to DefaultMaxRetries
+	MaxRetries int
+	// The backoff before the first retry, which doubles with each retry up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// blockPutResponse is the response of the block/put endpoint
+type blockPutResponse struct {
+	Key  string
+	Size int
+}
+
+// NewIPFSBlockstore returns a new IPFSBlockstore that writes to the IPFS HTTP API at the provided address, e.g. http://127.0.0.1:5001
+func NewIPFSBlockstore(apiAddr string, timeout time.Duration) *IPFSBlockstore {
+	return &IPFSBlockstore{
+		client:         &http.Client{Timeout: timeout},
+		putURL:         apiAddr + BlockPutPath,
+		MaxRetries:     DefaultMaxRetries,
+		InitialBackoff: DefaultInitialBackoff,
+		MaxBackoff:     DefaultMaxBackoff,
+	}
+}
+
+// Put writes the raw block to the daemon under the codec and hash function of the provided CID
+// it retries with an exponential backoff while the daemon is unavailable, and returns ErrCIDMismatch if the daemon
+// derives a different CID for the block
+func (bs *IPFSBlockstore) Put(c cid.Cid, raw []byte) error {
+	format, ok := cid.CodecToStr[c.Type()]
+	if !ok {
+		return fmt.Errorf("ipfs blockstore: unsupported codec 0x%x", c.Type())
+	}
+	prefix := c.Prefix()
+	mhType, ok := multihash.Codes[prefix.MhType]
+	if !ok {
+		return fmt.Errorf("ipfs blockstore: unsupported multihash 0x%x", prefix.MhType)
+	}
+	params := url.Values{}
+	params.Set("format", format)
+	params.Set("mhtype", mhType)
+	params.Set("mhlen", "-1")
+	reqURL := bs.putURL + "?" + params.Encode()
+
+	backoff := bs.InitialBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		var returned cid.Cid
+		returned, err = bs.put(reqURL, raw)
+		if err == nil {
+			if !returned.Equals(c) {
+				return fmt.Errorf("%w: expected %s, got %s", ErrCIDMismatch, c.String(), returned.String())
+			}
+			return nil
+		}
+		if attempt >= bs.MaxRetries {
+			break
+		}
+		logrus.Warnf("ipfs blockstore: error putting block %s, retrying in %s: %v", c.String(), backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > bs.MaxBackoff {
+			backoff = bs.MaxBackoff
+		}
+	}
+	return fmt.Errorf("ipfs blockstore: unable to put block %s after %d retries: %v", c.String(), bs.MaxRetries, err)
+}
+
+// put makes a single block/put request and returns the CID the daemon derived for the block
+func (bs *IPFSBlockstore) put(reqURL string, raw []byte) (cid.Cid, error) {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("data", "data")
+	if err != nil {
+		return cid.Cid{}, err
+	}
+	if _, err := part.Write(raw); err != nil {
+		return cid.Cid{}, err
+	}
+	if err := writer.Close(); err != nil {
+		return cid.Cid{}, err
+	}
+	res, err := bs.client.Post(reqURL, writer.FormDataContentType(), body)
+	if err != nil {
+		return cid.Cid{}, err
+	}
+	defer res.Body.Close()
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return cid.Cid{}, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return cid.Cid{}, fmt.Errorf("ipfs daemon responded with status %d: %s", res.StatusCode, string(resBody))
+	}
+	var putRes blockPutResponse
+	if err := json.Unmarshal(resBody, &putRes); err != nil {
+		return cid.Cid{}, err
+	}
+	return cid.Decode(putRes.Key)
+}
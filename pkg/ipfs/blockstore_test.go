@@ -0,0 +1,116 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ipfs_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/ipfs"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/ipfs/ipld"
+)
+
+var _ = Describe("IPFSBlockstore", func() {
+	var (
+		raw      = []byte("mockRawBlock")
+		blockCID cid.Cid
+		// the number of requests the mock daemon fails before it succeeds
+		failures int
+		requests int
+		received []byte
+		path     string
+		query    map[string]string
+		returned func() cid.Cid
+		server   *httptest.Server
+		bs       *ipfs.IPFSBlockstore
+	)
+	BeforeEach(func() {
+		var err error
+		blockCID, err = ipld.RawdataToCid(ipld.MEthStateTrie, raw, multihash.KECCAK_256)
+		Expect(err).ToNot(HaveOccurred())
+		failures, requests, received = 0, 0, nil
+		returned = func() cid.Cid { return blockCID }
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests <= failures {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			path = r.URL.Path
+			query = map[string]string{
+				"format": r.URL.Query().Get("format"),
+				"mhtype": r.URL.Query().Get("mhtype"),
+			}
+			file, _, err := r.FormFile("data")
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			received, _ = ioutil.ReadAll(file)
+			json.NewEncoder(w).Encode(map[string]interface{}{"Key": returned().String(), "Size": len(received)})
+		}))
+		bs = ipfs.NewIPFSBlockstore(server.URL, time.Second)
+		bs.InitialBackoff = time.Millisecond
+		bs.MaxBackoff = time.Millisecond
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("Puts the block under the codec and hash function of its CID", func() {
+		err := bs.Put(blockCID, raw)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(path).To(Equal(ipfs.BlockPutPath))
+		Expect(received).To(Equal(raw))
+		Expect(query["format"]).To(Equal("eth-state-trie"))
+		Expect(query["mhtype"]).To(Equal("keccak-256"))
+	})
+
+	It("Retries while the daemon is unavailable", func() {
+		failures = 2
+		err := bs.Put(blockCID, raw)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requests).To(Equal(3))
+	})
+
+	It("Gives up once it runs out of retries", func() {
+		failures = 10
+		bs.MaxRetries = 2
+		err := bs.Put(blockCID, raw)
+		Expect(err).To(HaveOccurred())
+		Expect(requests).To(Equal(3))
+	})
+
+	It("Returns ErrCIDMismatch if the daemon derives a different CID", func() {
+		returned = func() cid.Cid {
+			c, _ := ipld.RawdataToCid(ipld.MEthStateTrie, raw, multihash.SHA2_256)
+			return c
+		}
+		err := bs.Put(blockCID, raw)
+		Expect(errors.Is(err, ipfs.ErrCIDMismatch)).To(BeTrue())
+		Expect(requests).To(Equal(1))
+	})
+})
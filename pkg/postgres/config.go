@@ -32,17 +32,28 @@ const (
 	DATABASE_MAX_IDLE_CONNECTIONS = "DATABASE_MAX_IDLE_CONNECTIONS"
 	DATABASE_MAX_OPEN_CONNECTIONS = "DATABASE_MAX_OPEN_CONNECTIONS"
 	DATABASE_MAX_CONN_LIFETIME    = "DATABASE_MAX_CONN_LIFETIME"
+	DATABASE_SCHEMA               = "DATABASE_SCHEMA"
+	DATABASE_VERIFY_INDEXES       = "DATABASE_VERIFY_INDEXES"
+)
+
+// Default connection pool settings, used when none are configured
+const (
+	DefaultMaxIdle     = 10
+	DefaultMaxOpen     = 50
+	DefaultMaxLifetime = 60 * 30 // seconds
 )
 
 type Config struct {
-	Hostname    string
-	Name        string
-	User        string
-	Password    string
-	Port        int
-	MaxIdle     int
-	MaxOpen     int
-	MaxLifetime int
+	Hostname      string
+	Name          string
+	User          string
+	Password      string
+	Port          int
+	MaxIdle       int
+	MaxOpen       int
+	MaxLifetime   int
+	Schema        string
+	VerifyIndexes bool
 }
 
 func DbConnectionString(config Config) string {
@@ -66,6 +77,8 @@ func (d *Config) Init() {
 	viper.BindEnv("database.maxIdle", DATABASE_MAX_IDLE_CONNECTIONS)
 	viper.BindEnv("database.maxOpen", DATABASE_MAX_OPEN_CONNECTIONS)
 	viper.BindEnv("database.maxLifetime", DATABASE_MAX_CONN_LIFETIME)
+	viper.BindEnv("database.schema", DATABASE_SCHEMA)
+	viper.BindEnv("database.verifyIndexes", DATABASE_VERIFY_INDEXES)
 
 	d.Name = viper.GetString("database.name")
 	d.Hostname = viper.GetString("database.hostname")
@@ -75,4 +88,6 @@ func (d *Config) Init() {
 	d.MaxIdle = viper.GetInt("database.maxIdle")
 	d.MaxOpen = viper.GetInt("database.maxOpen")
 	d.MaxLifetime = viper.GetInt("database.maxLifetime")
+	d.Schema = viper.GetString("database.schema")
+	d.VerifyIndexes = viper.GetBool("database.verifyIndexes")
 }
@@ -26,6 +26,8 @@ const (
 	DeleteQueryFailedMsg      = "delete query failed"
 	InsertQueryFailedMsg      = "insert query failed"
 	SettingNodeFailedMsg      = "unable to set db node"
+	CreatingSchemaFailedMsg   = "unable to create db schema"
+	SchemaVersionMismatchMsg  = "db schema was created at a different migration version"
 )
 
 func ErrBeginTransactionFailed(beginErr error) error {
@@ -48,6 +50,15 @@ func ErrUnableToSetNode(setErr error) error {
 	return formatError(SettingNodeFailedMsg, setErr.Error())
 }
 
+func ErrUnableToCreateSchema(createErr error) error {
+	return formatError(CreatingSchemaFailedMsg, createErr.Error())
+}
+
+func ErrSchemaVersionMismatch(schema, created string, current int64) error {
+	return formatError(SchemaVersionMismatchMsg, fmt.Sprintf("schema %s was created at version %s but the %s schema is at version %d, "+
+		"migrations are not applied to it so it must be dropped to be recreated", schema, created, DefaultSchema, current))
+}
+
 func formatError(msg, err string) error {
 	return fmt.Errorf("%s: %s", msg, err)
 }
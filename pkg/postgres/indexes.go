@@ -0,0 +1,88 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ExpectedIndex is a column of a cid table that is expected to lead an index
+type ExpectedIndex struct {
+	Table  string
+	Column string
+}
+
+func (i ExpectedIndex) String() string {
+	return fmt.Sprintf("%s(%s)", i.Table, i.Column)
+}
+
+// ExpectedIndexes are the indexes the indexer relies on, these are created by the migrations
+var ExpectedIndexes = []ExpectedIndex{
+	// gap searches, resync/clean ranges and revalidation all select headers by block number
+	{Table: "header_cids", Column: "block_number"},
+	// the strict parent check looks headers up by hash
+	{Table: "header_cids", Column: "block_hash"},
+	// the cid columns serve the reverse lookup from a cid to the block it was indexed at
+	{Table: "header_cids", Column: "cid"},
+	{Table: "uncle_cids", Column: "cid"},
+	{Table: "transaction_cids", Column: "cid"},
+	{Table: "receipt_cids", Column: "cid"},
+	{Table: "state_cids", Column: "cid"},
+	{Table: "storage_cids", Column: "cid"},
+	// the FK columns are joined on by nearly every query and scanned by the cascading deletes of the cleaner
+	{Table: "transaction_cids", Column: "header_id"},
+	{Table: "receipt_cids", Column: "tx_id"},
+	{Table: "state_cids", Column: "header_id"},
+	{Table: "storage_cids", Column: "state_id"},
+	{Table: "state_accounts", Column: "state_id"},
+}
+
+// MissingIndexes returns the expected indexes that do not exist in the db's schema
+// an index is matched by its leading column rather than by name, since tables created for a non-default schema
+// are given generated index names
+func (db *DB) MissingIndexes() ([]ExpectedIndex, error) {
+	pgStr := `SELECT EXISTS(SELECT 1 FROM pg_index
+				INNER JOIN pg_class ON (pg_index.indrelid = pg_class.oid)
+				INNER JOIN pg_namespace ON (pg_class.relnamespace = pg_namespace.oid)
+				INNER JOIN pg_attribute ON (pg_attribute.attrelid = pg_class.oid AND pg_attribute.attnum = pg_index.indkey[0])
+				WHERE pg_namespace.nspname = $1 AND pg_class.relname = $2 AND pg_attribute.attname = $3)`
+	missing := make([]ExpectedIndex, 0)
+	for _, index := range ExpectedIndexes {
+		var exists bool
+		if err := db.Get(&exists, pgStr, db.Schema, index.Table, index.Column); err != nil {
+			return nil, err
+		}
+		if !exists {
+			missing = append(missing, index)
+		}
+	}
+	return missing, nil
+}
+
+// VerifyIndexes logs a warning for each expected index that is missing
+func (db *DB) VerifyIndexes() error {
+	missing, err := db.MissingIndexes()
+	if err != nil {
+		return err
+	}
+	for _, index := range missing {
+		logrus.Warnf("expected index on %s.%s is missing, queries that rely on it will be slow", db.Schema, index.String())
+	}
+	return nil
+}
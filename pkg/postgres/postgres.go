@@ -28,25 +28,47 @@ type DB struct {
 	*sqlx.DB
 	Node   node.Info
 	NodeID int64
+	Schema string
 }
 
 func NewDB(databaseConfig Config, node node.Info) (*DB, error) {
+	schema := databaseConfig.Schema
+	if schema == "" {
+		schema = DefaultSchema
+	}
+	if err := ValidateSchemaName(schema); err != nil {
+		return &DB{}, err
+	}
 	connectString := DbConnectionString(databaseConfig)
 	db, connectErr := sqlx.Connect("postgres", connectString)
 	if connectErr != nil {
 		return &DB{}, ErrDBConnectionFailed(connectErr)
 	}
-	if databaseConfig.MaxOpen > 0 {
-		db.SetMaxOpenConns(databaseConfig.MaxOpen)
+	// an unbounded pool can exhaust the connections available on the Postgres server, so always apply limits
+	maxOpen := databaseConfig.MaxOpen
+	if maxOpen <= 0 {
+		maxOpen = DefaultMaxOpen
+	}
+	db.SetMaxOpenConns(maxOpen)
+	maxIdle := databaseConfig.MaxIdle
+	if maxIdle <= 0 {
+		maxIdle = DefaultMaxIdle
+	}
+	db.SetMaxIdleConns(maxIdle)
+	maxLifetime := databaseConfig.MaxLifetime
+	if maxLifetime <= 0 {
+		maxLifetime = DefaultMaxLifetime
 	}
-	if databaseConfig.MaxIdle > 0 {
-		db.SetMaxIdleConns(databaseConfig.MaxIdle)
+	db.SetConnMaxLifetime(time.Duration(maxLifetime) * time.Second)
+	pg := DB{DB: db, Node: node, Schema: schema}
+	if err := pg.CreateSchema(); err != nil {
+		return &DB{}, ErrUnableToCreateSchema(err)
 	}
-	if databaseConfig.MaxLifetime > 0 {
-		lifetime := time.Duration(databaseConfig.MaxLifetime) * time.Second
-		db.SetConnMaxLifetime(lifetime)
+	if databaseConfig.VerifyIndexes {
+		if err := pg.VerifyIndexes(); err != nil {
+			return &DB{}, err
+		}
 	}
-	pg := DB{DB: db, Node: node}
 	nodeErr := pg.CreateNode(&node)
 	if nodeErr != nil {
 		return &DB{}, ErrUnableToSetNode(nodeErr)
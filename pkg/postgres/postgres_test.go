@@ -100,4 +100,59 @@ var _ = Describe("Postgres DB", func() {
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring(postgres.SettingNodeFailedMsg))
 	})
+
+	It("rejects schema names that are not plain identifiers", func() {
+		config := test_config.DBConfig
+		config.Schema = "eth; DROP TABLE eth.header_cids"
+		node := node.Info{GenesisBlock: "GENESIS", NetworkID: "1", ID: "x123", ClientName: "geth"}
+
+		_, err := postgres.NewDB(config, node)
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("invalid schema name"))
+	})
+
+	It("creates the cid tables in a schema that does not exist yet", func() {
+		config := test_config.DBConfig
+		config.Schema = "eth_testing"
+		node := node.Info{GenesisBlock: "GENESIS", NetworkID: "1", ID: "x123", ClientName: "geth"}
+
+		db, err := postgres.NewDB(config, node)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Exec(`DROP SCHEMA eth_testing CASCADE`)
+		Expect(db.Schema).To(Equal("eth_testing"))
+
+		var count int
+		err = db.Get(&count, `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = 'eth_testing' AND table_type = 'BASE TABLE'`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(count).To(Equal(12))
+	})
+
+	It("rejects an existing schema created at a different migration version", func() {
+		config := test_config.DBConfig
+		config.Schema = "eth_testing"
+		node := node.Info{GenesisBlock: "GENESIS", NetworkID: "1", ID: "x123", ClientName: "geth"}
+
+		db, err := postgres.NewDB(config, node)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Exec(`DROP SCHEMA eth_testing CASCADE`)
+		_, err = postgres.NewDB(config, node)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = db.Exec(`UPDATE eth_testing.schema_version SET version_id = version_id - 1`)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = postgres.NewDB(config, node)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(postgres.SchemaVersionMismatchMsg))
+	})
+
+	It("finds all of the expected indexes in the migrated schema", func() {
+		node := node.Info{GenesisBlock: "GENESIS", NetworkID: "1", ID: "x123", ClientName: "geth"}
+		db, err := postgres.NewDB(test_config.DBConfig, node)
+		Expect(err).ToNot(HaveOccurred())
+
+		missing, err := db.MissingIndexes()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(missing).To(BeEmpty())
+	})
 })
@@ -0,0 +1,172 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package postgres
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// DefaultSchema is the schema created by the migrations, used when no schema is configured
+const DefaultSchema = "eth"
+
+// schemaNameRegex restricts schema names to unquoted lowercase Postgres identifiers
+// since the schema name is interpolated directly into queries it must never contain anything else
+var schemaNameRegex = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// cidTables are the tables, in foreign key dependency order, that are created in a non-default schema
+var cidTables = []string{
+	"addresses",
+	"header_cids",
+	"ipld_sizes",
+	"uncle_cids",
+	"transaction_cids",
+	"receipt_cids",
+	"state_cids",
+	"storage_cids",
+	"state_accounts",
+	"gaps",
+	"payloads",
+}
+
+// cidForeignKeys are the foreign key constraints of the cid tables, these are not copied by CREATE TABLE ... LIKE
+// the %[1]s verb is replaced by the schema name
+var cidForeignKeys = []string{
+	`ALTER TABLE %[1]s.header_cids ADD CONSTRAINT header_cids_mh_key_fkey FOREIGN KEY (mh_key) REFERENCES public.blocks(key) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED`,
+	`ALTER TABLE %[1]s.header_cids ADD CONSTRAINT header_cids_node_id_fkey FOREIGN KEY (node_id) REFERENCES public.nodes(id) ON DELETE CASCADE`,
+	`ALTER TABLE %[1]s.ipld_sizes ADD CONSTRAINT ipld_sizes_header_id_fkey FOREIGN KEY (header_id) REFERENCES %[1]s.header_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED`,
+	`ALTER TABLE %[1]s.uncle_cids ADD CONSTRAINT uncle_cids_header_id_fkey FOREIGN KEY (header_id) REFERENCES %[1]s.header_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED`,
+	`ALTER TABLE %[1]s.uncle_cids ADD CONSTRAINT uncle_cids_mh_key_fkey FOREIGN KEY (mh_key) REFERENCES public.blocks(key) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED`,
+	`ALTER TABLE %[1]s.transaction_cids ADD CONSTRAINT transaction_cids_header_id_fkey FOREIGN KEY (header_id) REFERENCES %[1]s.header_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED`,
+	`ALTER TABLE %[1]s.transaction_cids ADD CONSTRAINT transaction_cids_mh_key_fkey FOREIGN KEY (mh_key) REFERENCES public.blocks(key) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED`,
+	`ALTER TABLE %[1]s.transaction_cids ADD CONSTRAINT transaction_cids_src_id_fkey FOREIGN KEY (src_id) REFERENCES %[1]s.addresses(id)`,
+	`ALTER TABLE %[1]s.transaction_cids ADD CONSTRAINT transaction_cids_dst_id_fkey FOREIGN KEY (dst_id) REFERENCES %[1]s.addresses(id)`,
+	`ALTER TABLE %[1]s.receipt_cids ADD CONSTRAINT receipt_cids_tx_id_fkey FOREIGN KEY (tx_id) REFERENCES %[1]s.transaction_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED`,
+	`ALTER TABLE %[1]s.receipt_cids ADD CONSTRAINT receipt_cids_mh_key_fkey FOREIGN KEY (mh_key) REFERENCES public.blocks(key) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED`,
+	`ALTER TABLE %[1]s.receipt_cids ADD CONSTRAINT receipt_cids_contract_id_fkey FOREIGN KEY (contract_id) REFERENCES %[1]s.addresses(id)`,
+	`ALTER TABLE %[1]s.state_cids ADD CONSTRAINT state_cids_header_id_fkey FOREIGN KEY (header_id) REFERENCES %[1]s.header_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED`,
+	`ALTER TABLE %[1]s.state_cids ADD CONSTRAINT state_cids_mh_key_fkey FOREIGN KEY (mh_key) REFERENCES public.blocks(key) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED`,
+	`ALTER TABLE %[1]s.storage_cids ADD CONSTRAINT storage_cids_state_id_fkey FOREIGN KEY (state_id) REFERENCES %[1]s.state_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED`,
+	`ALTER TABLE %[1]s.storage_cids ADD CONSTRAINT storage_cids_mh_key_fkey FOREIGN KEY (mh_key) REFERENCES public.blocks(key) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED`,
+	`ALTER TABLE %[1]s.state_accounts ADD CONSTRAINT state_accounts_state_id_fkey FOREIGN KEY (state_id) REFERENCES %[1]s.state_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED`,
+	`ALTER TABLE %[1]s.gaps ADD CONSTRAINT gaps_header_id_fkey FOREIGN KEY (header_id) REFERENCES %[1]s.header_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED`,
+}
+
+// cidViews are the views over the cid tables that join their address ids back to hex, these are not copied either
+// the %[1]s verb is replaced by the schema name
+var cidViews = []string{
+	`CREATE VIEW %[1]s.transaction_cids_with_addresses AS
+		SELECT transaction_cids.*, src.address AS src, COALESCE(dst.address, '') AS dst
+		FROM %[1]s.transaction_cids
+		INNER JOIN %[1]s.addresses src ON (transaction_cids.src_id = src.id)
+		LEFT JOIN %[1]s.addresses dst ON (transaction_cids.dst_id = dst.id)`,
+	`CREATE VIEW %[1]s.receipt_cids_with_addresses AS
+		SELECT receipt_cids.*, COALESCE(contract.address, '') AS contract,
+			ARRAY(SELECT addresses.address FROM unnest(receipt_cids.log_contract_ids) WITH ORDINALITY AS c (id, ord)
+				INNER JOIN %[1]s.addresses ON (addresses.id = c.id) ORDER BY c.ord) AS log_contracts
+		FROM %[1]s.receipt_cids
+		LEFT JOIN %[1]s.addresses contract ON (receipt_cids.contract_id = contract.id)`,
+}
+
+// ValidateSchemaName returns an error if the provided name is not safe to use as a schema name
+func ValidateSchemaName(name string) error {
+	if !schemaNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid schema name %q: must be a lowercase identifier of at most 63 characters", name)
+	}
+	return nil
+}
+
+// CreateSchema creates the db's schema and its cid tables if the schema does not yet exist
+// The tables are modeled on those in the default schema, which must already have been migrated
+// Note that the id columns of the new tables share their sequences with the default schema
+// The migrations are only applied to the default schema, so the migration version the tables were copied at is recorded in
+// the schema, and an existing schema created at a different version than the default schema is now at is rejected
+func (db *DB) CreateSchema() error {
+	if db.Schema == DefaultSchema {
+		return nil
+	}
+	version, err := db.migrationVersion()
+	if err != nil {
+		return err
+	}
+	var exists bool
+	if err := db.Get(&exists, `SELECT EXISTS(SELECT 1 FROM information_schema.schemata WHERE schema_name = $1)`, db.Schema); err != nil {
+		return err
+	}
+	if exists {
+		return db.checkSchemaVersion(version)
+	}
+	tx, err := db.Beginx()
+	if err != nil {
+		return ErrBeginTransactionFailed(err)
+	}
+	pgStrs := []string{
+		fmt.Sprintf(`CREATE SCHEMA %s`, db.Schema),
+		fmt.Sprintf(`CREATE TABLE %s.schema_version (version_id BIGINT NOT NULL)`, db.Schema),
+		fmt.Sprintf(`INSERT INTO %s.schema_version (version_id) VALUES (%d)`, db.Schema, version),
+	}
+	for _, table := range cidTables {
+		pgStrs = append(pgStrs, fmt.Sprintf(`CREATE TABLE %[1]s.%[2]s (LIKE %[3]s.%[2]s INCLUDING ALL)`, db.Schema, table, DefaultSchema))
+	}
+	for _, fk := range cidForeignKeys {
+		pgStrs = append(pgStrs, fmt.Sprintf(fk, db.Schema))
+	}
+	for _, view := range cidViews {
+		pgStrs = append(pgStrs, fmt.Sprintf(view, db.Schema))
+	}
+	for _, pgStr := range pgStrs {
+		if _, err := tx.Exec(pgStr); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// migrationVersion returns the latest migration applied to the database, or 0 if it was not migrated with goose
+func (db *DB) migrationVersion() (int64, error) {
+	var migrated bool
+	if err := db.Get(&migrated, `SELECT to_regclass('public.goose_db_version') IS NOT NULL`); err != nil {
+		return 0, err
+	}
+	if !migrated {
+		return 0, nil
+	}
+	var version int64
+	err := db.Get(&version, `SELECT COALESCE(MAX(version_id), 0) FROM public.goose_db_version WHERE is_applied`)
+	return version, err
+}
+
+// checkSchemaVersion returns an error if the db's existing schema was not created at the provided migration version
+// schemas created before their version was recorded have no schema_version table, and are rejected too
+func (db *DB) checkSchemaVersion(version int64) error {
+	var recorded bool
+	if err := db.Get(&recorded, `SELECT to_regclass($1) IS NOT NULL`, db.Schema+".schema_version"); err != nil {
+		return err
+	}
+	if !recorded {
+		return ErrSchemaVersionMismatch(db.Schema, "unknown", version)
+	}
+	var created int64
+	if err := db.Get(&created, fmt.Sprintf(`SELECT version_id FROM %s.schema_version`, db.Schema)); err != nil {
+		return err
+	}
+	if created != version {
+		return ErrSchemaVersionMismatch(db.Schema, fmt.Sprint(created), version)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prom
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/metrics/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const namespace = "ipld_eth_indexer"
+
+var (
+	enabled  bool
+	registry = metrics.NewRegistry()
+
+	backfillBlocksProcessed metrics.Gauge
+	backfillBlocksFailed    metrics.Gauge
+	backfillCurrentBlock    metrics.Gauge
+	backfillRemainingBlocks metrics.Gauge
+	backfillBlocksPerSecond metrics.GaugeFloat64
+	backfillETASeconds      metrics.GaugeFloat64
+	gapRanges               metrics.Gauge
+	gapBlocks               metrics.Gauge
+
+	chainHead      metrics.Gauge
+	highestIndexed metrics.Gauge
+	indexingLag    metrics.Gauge
+
+	oldestValidation metrics.Gauge
+
+	transformDuration metrics.Histogram
+	blockCommit       metrics.Histogram
+	chunkCommit       metrics.Histogram
+
+	publishCacheHits   metrics.Counter
+	publishCacheMisses metrics.Counter
+	publishCacheBytes  metrics.Counter
+
+	droppedBlockEvents metrics.Counter
+
+	syncQueueDepth      metrics.Gauge
+	syncBusyWorkers     metrics.Gauge
+	backfillBusyWorkers metrics.Gauge
+)
+
+// size and bias of the samples the latency histograms are computed over
+const (
+	sampleSize  = 1028
+	sampleAlpha = 0.015
+)
+
+// Init enables metrics collection and registers the indexer's metrics
+// Until this is called all of the metric setters in this package are no-ops
+func Init() {
+	metrics.Enabled = true
+	enabled = true
+
+	backfillBlocksProcessed = metrics.NewRegisteredGauge(namespace+"/backfill/blocks_processed", registry)
+	backfillBlocksFailed = metrics.NewRegisteredGauge(namespace+"/backfill/blocks_failed", registry)
+	backfillCurrentBlock = metrics.NewRegisteredGauge(namespace+"/backfill/current_block", registry)
+	backfillRemainingBlocks = metrics.NewRegisteredGauge(namespace+"/backfill/remaining_blocks", registry)
+	backfillBlocksPerSecond = metrics.NewRegisteredGaugeFloat64(namespace+"/backfill/blocks_per_second", registry)
+	backfillETASeconds = metrics.NewRegisteredGaugeFloat64(namespace+"/backfill/eta_seconds", registry)
+	gapRanges = metrics.NewRegisteredGauge(namespace+"/backfill/gap_ranges", registry)
+	gapBlocks = metrics.NewRegisteredGauge(namespace+"/backfill/gap_blocks", registry)
+
+	chainHead = metrics.NewRegisteredGauge(namespace+"/chain_head", registry)
+	highestIndexed = metrics.NewRegisteredGauge(namespace+"/highest_indexed_block", registry)
+	indexingLag = metrics.NewRegisteredGauge(namespace+"/indexing_lag_blocks", registry)
+
+	oldestValidation = metrics.NewRegisteredGauge(namespace+"/oldest_validation_timestamp_seconds", registry)
+
+	transformDuration = metrics.NewRegisteredHistogram(namespace+"/transform/duration_microseconds", registry,
+		metrics.NewExpDecaySample(sampleSize, sampleAlpha))
+	blockCommit = metrics.NewRegisteredHistogram(namespace+"/db/commit/block_microseconds", registry,
+		metrics.NewExpDecaySample(sampleSize, sampleAlpha))
+	chunkCommit = metrics.NewRegisteredHistogram(namespace+"/db/commit/chunk_microseconds", registry,
+		metrics.NewExpDecaySample(sampleSize, sampleAlpha))
+
+	publishCacheHits = metrics.NewRegisteredCounter(namespace+"/publish_cache/hits", registry)
+	publishCacheMisses = metrics.NewRegisteredCounter(namespace+"/publish_cache/misses", registry)
+	publishCacheBytes = metrics.NewRegisteredCounter(namespace+"/publish_cache/skipped_bytes", registry)
+
+	droppedBlockEvents = metrics.NewRegisteredCounter(namespace+"/block_events/dropped", registry)
+
+	syncQueueDepth = metrics.NewRegisteredGauge(namespace+"/sync/payload_queue_depth", registry)
+	syncBusyWorkers = metrics.NewRegisteredGauge(namespace+"/sync/busy_workers", registry)
+	backfillBusyWorkers = metrics.NewRegisteredGauge(namespace+"/backfill/busy_workers", registry)
+}
+
+// Serve exposes the registered metrics in the prometheus format at addr/metrics
+func Serve(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", prometheus.Handler(registry))
+	logrus.Infof("serving metrics at http://%s/metrics", addr)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			logrus.Errorf("metrics server error: %v", err)
+		}
+	}()
+}
+
+// SetBackfillProgress updates the backfill progress gauges
+// failed are the blocks of the pass whose payload could not be transformed, they are not included in processed
+func SetBackfillProgress(processed, failed, current, remaining uint64, blocksPerSecond float64, eta time.Duration) {
+	if !enabled {
+		return
+	}
+	backfillBlocksProcessed.Update(int64(processed))
+	backfillBlocksFailed.Update(int64(failed))
+	backfillCurrentBlock.Update(int64(current))
+	backfillRemainingBlocks.Update(int64(remaining))
+	backfillBlocksPerSecond.Update(blocksPerSecond)
+	backfillETASeconds.Update(eta.Seconds())
+}
+
+// SetGaps updates the gauges of the number of gaps in the indexed data and the total number of blocks missing from them
+func SetGaps(ranges, blocks uint64) {
+	if !enabled {
+		return
+	}
+	gapRanges.Update(int64(ranges))
+	gapBlocks.Update(int64(blocks))
+}
+
+// SetIndexingLag updates the chain head, highest indexed block and indexing lag gauges
+func SetIndexingLag(head, indexed, lag uint64) {
+	if !enabled {
+		return
+	}
+	chainHead.Update(int64(head))
+	highestIndexed.Update(int64(indexed))
+	indexingLag.Update(int64(lag))
+}
+
+// SetOldestValidation updates the gauge of the unix time at which the least recently validated header was last validated
+// a validator that has stalled shows up as this falling further and further behind the current time
+func SetOldestValidation(oldest time.Time) {
+	if !enabled {
+		return
+	}
+	oldestValidation.Update(oldest.Unix())
+}
+
+// ObserveTransform records how long it took to transform and index a payload
+func ObserveTransform(d time.Duration) {
+	if !enabled {
+		return
+	}
+	transformDuration.Update(d.Microseconds())
+}
+
+// ObserveCommit records how long it took to commit a Postgres tx written by the transformer
+// the commits of payloads whose state and storage nodes were split across several txs are recorded per chunk, apart
+// from those of payloads committed in a single tx, so that their smaller commits don't skew the per-block latency
+func ObserveCommit(chunked bool, d time.Duration) {
+	if !enabled {
+		return
+	}
+	if chunked {
+		chunkCommit.Update(d.Microseconds())
+		return
+	}
+	blockCommit.Update(d.Microseconds())
+}
+
+// ObservePublishCacheLookup counts a lookup of the cache of recently published IPLD keys as a hit or a miss
+// a hit is an insert into public.blocks that was skipped
+func ObservePublishCacheLookup(hit bool) {
+	if !enabled {
+		return
+	}
+	if hit {
+		publishCacheHits.Inc(1)
+		return
+	}
+	publishCacheMisses.Inc(1)
+}
+
+// AddPublishCacheSkippedBytes counts the bytes of the state and storage IPLDs whose insert was skipped as they had
+// already been committed by a recent block; over a backfill this is the data the publish cache saved rewriting
+func AddPublishCacheSkippedBytes(n int) {
+	if !enabled {
+		return
+	}
+	publishCacheBytes.Inc(int64(n))
+}
+
+// IncDroppedBlockEvents counts a block event that was dropped because its consumer had fallen too far behind
+func IncDroppedBlockEvents() {
+	if !enabled {
+		return
+	}
+	droppedBlockEvents.Inc(1)
+}
+
+// SetSyncWorkerPool updates the gauges of the number of payloads queued for the sync workers and of the workers busy
+// transforming one; a queue that stays full means the workers, i.e. the database writes, are the bottleneck, while
+// idle workers mean the payloads aren't arriving fast enough
+func SetSyncWorkerPool(queued, busy int64) {
+	if !enabled {
+		return
+	}
+	syncQueueDepth.Update(queued)
+	syncBusyWorkers.Update(busy)
+}
+
+// SetBackfillBusyWorkers updates the gauge of the number of backfill workers busy fetching and transforming a batch
+func SetBackfillBusyWorkers(busy int64) {
+	if !enabled {
+		return
+	}
+	backfillBusyWorkers.Update(busy)
+}
@@ -17,13 +17,16 @@
 package resync
 
 import (
+	"database/sql"
 	"fmt"
 	"time"
 
 	"github.com/ethereum/go-ethereum/rpc"
 
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/node"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
@@ -39,6 +42,7 @@ const (
 	RESYNC_CLEAR_OLD_CACHE  = "RESYNC_CLEAR_OLD_CACHE"
 	RESYNC_TYPE             = "RESYNC_TYPE"
 	RESYNC_RESET_VALIDATION = "RESYNC_RESET_VALIDATION"
+	RESYNC_RESUME           = "RESYNC_RESUME"
 
 	RESYNC_MAX_IDLE_CONNECTIONS = "RESYNC_MAX_IDLE_CONNECTIONS"
 	RESYNC_MAX_OPEN_CONNECTIONS = "RESYNC_MAX_OPEN_CONNECTIONS"
@@ -50,6 +54,7 @@ type Config struct {
 	ResyncType      shared.DataType // The type of data to resync
 	ClearOldCache   bool            // Resync will first clear all the data within the range
 	ResetValidation bool            // If true, resync will reset the validation level to 0 for the given range
+	Resume          bool            // If true, the start height is ignored and resync continues from the highest indexed block
 
 	// DB info
 	DB       *postgres.DB
@@ -61,6 +66,8 @@ type Config struct {
 	BatchSize  uint64        // BatchSize for the resync http calls (client has to support batch sizing)
 	Timeout    time.Duration // HTTP connection timeout in seconds
 	Workers    uint64
+	// The hash function each IPLD node type is keyed under, keyed by its multicodec, node types left out keep the default
+	Multihashes map[uint64]uint64
 }
 
 // NewConfig fills and returns a resync config from toml parameters
@@ -76,6 +83,7 @@ func NewConfig() (*Config, error) {
 	viper.BindEnv("resync.batchSize", RESYNC_BATCH_SIZE)
 	viper.BindEnv("resync.workers", RESYNC_WORKERS)
 	viper.BindEnv("resync.resetValidation", RESYNC_RESET_VALIDATION)
+	viper.BindEnv("resync.resume", RESYNC_RESUME)
 	viper.BindEnv("resync.timeout", shared.HTTP_TIMEOUT)
 
 	timeout := viper.GetInt("resync.timeout")
@@ -89,8 +97,12 @@ func NewConfig() (*Config, error) {
 	c.Ranges = [][2]uint64{{start, stop}}
 	c.ClearOldCache = viper.GetBool("resync.clearOldCache")
 	c.ResetValidation = viper.GetBool("resync.resetValidation")
+	c.Resume = viper.GetBool("resync.resume")
 	c.BatchSize = uint64(viper.GetInt64("resync.batchSize"))
 	c.Workers = uint64(viper.GetInt64("resync.workers"))
+	if c.Multihashes, err = eth.ParseMultihashes(shared.MultihashEntries()); err != nil {
+		return nil, err
+	}
 
 	resyncType := viper.GetString("resync.type")
 	c.ResyncType, err = shared.GenerateDataTypeFromString(resyncType)
@@ -105,7 +117,7 @@ func NewConfig() (*Config, error) {
 	}
 
 	ethHTTP := viper.GetString("ethereum.httpPath")
-	c.NodeInfo, c.HTTPClient, err = shared.GetEthNodeAndClient(fmt.Sprintf("http://%s", ethHTTP))
+	c.NodeInfo, c.HTTPClient, err = shared.GetEthNodeAndClient(shared.EthEndpoint(ethHTTP, "http"))
 	if err != nil {
 		return nil, err
 	}
@@ -114,14 +126,41 @@ func NewConfig() (*Config, error) {
 	overrideDBConnConfig(&c.DBConfig)
 	db := utils.LoadPostgres(c.DBConfig, c.NodeInfo)
 	c.DB = &db
+
+	if c.Resume {
+		if c.Ranges[0][0], err = resumeHeight(c.DB); err != nil {
+			return nil, err
+		}
+		logrus.Infof("resuming resync from block %d", c.Ranges[0][0])
+	}
 	return c, nil
 }
 
+// resumeHeight returns the height after the highest indexed block, or 0 if nothing has been indexed yet
+// gaps below this height are left to the backfill process
+func resumeHeight(db *postgres.DB) (uint64, error) {
+	last, err := eth.NewGapRetriever(db).RetrieveLastBlockNumber()
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("unable to retrieve the highest indexed block to resume from: %v", err)
+	}
+	return uint64(last) + 1, nil
+}
+
 func overrideDBConnConfig(con *postgres.Config) {
 	viper.BindEnv("database.resync.maxIdle", RESYNC_MAX_IDLE_CONNECTIONS)
 	viper.BindEnv("database.resync.maxOpen", RESYNC_MAX_OPEN_CONNECTIONS)
 	viper.BindEnv("database.resync.maxLifetime", RESYNC_MAX_CONN_LIFETIME)
-	con.MaxIdle = viper.GetInt("database.resync.maxIdle")
-	con.MaxOpen = viper.GetInt("database.resync.maxOpen")
-	con.MaxLifetime = viper.GetInt("database.resync.maxLifetime")
+	// only override the general pool settings with command specific ones if they have been set
+	if maxIdle := viper.GetInt("database.resync.maxIdle"); maxIdle > 0 {
+		con.MaxIdle = maxIdle
+	}
+	if maxOpen := viper.GetInt("database.resync.maxOpen"); maxOpen > 0 {
+		con.MaxOpen = maxOpen
+	}
+	if maxLifetime := viper.GetInt("database.resync.maxLifetime"); maxLifetime > 0 {
+		con.MaxLifetime = maxLifetime
+	}
 }
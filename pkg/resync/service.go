@@ -60,12 +60,16 @@ type Service struct {
 func NewResyncService(settings *Config) (Resync, error) {
 	rs := new(Service)
 	var err error
-	rs.Fetcher = eth.NewPayloadFetcher(settings.HTTPClient, settings.Timeout)
-	rs.ChainConfig, err = eth.ChainConfig(settings.NodeInfo.ChainID)
+	rs.Fetcher = eth.NewPayloadFetcher(eth.NewRateLimitedBatchClient(settings.HTTPClient, shared.RPCRateLimiter()), settings.Timeout)
+	rs.ChainConfig, err = eth.ResolveChainConfig(settings.HTTPClient, settings.NodeInfo.ChainID, shared.ChainConfigFromNode())
 	if err != nil {
 		return nil, err
 	}
-	rs.Transformer = eth.NewStateDiffTransformer(rs.ChainConfig, settings.DB)
+	transformer := eth.NewStateDiffTransformer(rs.ChainConfig, settings.DB)
+	if err := transformer.SetMultihashes(settings.Multihashes); err != nil {
+		return nil, err
+	}
+	rs.Transformer = transformer
 	rs.Cleaner = eth.NewDBCleaner(settings.DB)
 	rs.BatchSize = settings.BatchSize
 	if rs.BatchSize == 0 {
@@ -98,6 +102,7 @@ func (rs *Service) Sync() error {
 		}
 	}
 	// spin up worker goroutines
+	logrus.Infof("ethereum resync spinning up %d workers", rs.Workers)
 	heightsChan := make(chan []uint64)
 	for i := 1; i <= int(rs.Workers); i++ {
 		go rs.resync(i, heightsChan)
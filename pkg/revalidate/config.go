@@ -0,0 +1,78 @@
+// VulcanizeDB
+// Copyright © 2020 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package revalidate
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/node"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+	"github.com/vulcanize/ipld-eth-indexer/utils"
+)
+
+// Env variables
+const (
+	REVALIDATE_START = "REVALIDATE_START"
+	REVALIDATE_STOP  = "REVALIDATE_STOP"
+)
+
+// Config holds the parameters needed to perform a revalidation
+type Config struct {
+	// DB info
+	DB       *postgres.DB
+	DBConfig postgres.Config
+
+	HTTPClient *rpc.Client   // Ethereum rpc client
+	NodeInfo   node.Info     // Info for the associated node
+	Start      uint64        // The block height to start revalidating at
+	Stop       uint64        // The block height to stop revalidating at
+	Timeout    time.Duration // HTTP connection timeout in seconds
+}
+
+// NewConfig fills and returns a revalidate config from toml parameters
+func NewConfig() (*Config, error) {
+	c := new(Config)
+	var err error
+
+	viper.BindEnv("ethereum.httpPath", shared.ETH_HTTP_PATH)
+	viper.BindEnv("revalidate.start", REVALIDATE_START)
+	viper.BindEnv("revalidate.stop", REVALIDATE_STOP)
+	viper.BindEnv("revalidate.timeout", shared.HTTP_TIMEOUT)
+
+	timeout := viper.GetInt("revalidate.timeout")
+	if timeout < 5 {
+		timeout = 5
+	}
+	c.Timeout = time.Second * time.Duration(timeout)
+	c.Start = uint64(viper.GetInt64("revalidate.start"))
+	c.Stop = uint64(viper.GetInt64("revalidate.stop"))
+
+	ethHTTP := viper.GetString("ethereum.httpPath")
+	c.NodeInfo, c.HTTPClient, err = shared.GetEthNodeAndClient(shared.EthEndpoint(ethHTTP, "http"))
+	if err != nil {
+		return nil, err
+	}
+
+	c.DBConfig.Init()
+	db := utils.LoadPostgres(c.DBConfig, c.NodeInfo)
+	c.DB = &db
+	return c, nil
+}
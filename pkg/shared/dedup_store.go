@@ -0,0 +1,176 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package shared
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/prom"
+)
+
+// DefaultPublishCacheSize is the number of recently published IPLD keys the process remembers if no size has been configured
+const DefaultPublishCacheSize = 1 << 16
+
+// DedupStore remembers the multihash keys of IPLDs that have been committed to public.blocks, so that the publishing
+// functions in this package can skip the redundant inserts of IPLDs that recur across blocks
+// implementations must be safe for concurrent use, as a database's store is shared by all of the process' workers
+type DedupStore interface {
+	// Contains returns whether the key has been recently committed
+	Contains(key string) bool
+	// Add records the keys as committed
+	Add(keys ...string)
+	// Purge forgets all of the keys
+	Purge()
+}
+
+// LRUDedupStore is a DedupStore that holds a bounded number of keys, once full the least recently used are evicted first
+type LRUDedupStore struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	keys  map[string]*list.Element
+}
+
+// NewLRUDedupStore returns an LRUDedupStore that holds up to size keys
+func NewLRUDedupStore(size int) *LRUDedupStore {
+	return &LRUDedupStore{
+		size:  size,
+		order: list.New(),
+		keys:  make(map[string]*list.Element, size),
+	}
+}
+
+// Contains satisfies the DedupStore interface, a key that is found becomes the most recently used
+func (s *LRUDedupStore) Contains(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.keys[key]
+	if ok {
+		s.order.MoveToFront(e)
+	}
+	return ok
+}
+
+// Add satisfies the DedupStore interface
+func (s *LRUDedupStore) Add(keys ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		if e, ok := s.keys[key]; ok {
+			s.order.MoveToFront(e)
+			continue
+		}
+		if s.order.Len() >= s.size {
+			evicted := s.order.Back()
+			s.order.Remove(evicted)
+			delete(s.keys, evicted.Value.(string))
+		}
+		s.keys[key] = s.order.PushFront(key)
+	}
+}
+
+// Purge satisfies the DedupStore interface
+func (s *LRUDedupStore) Purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.order.Init()
+	s.keys = make(map[string]*list.Element, s.size)
+}
+
+var (
+	dedupStores    = make(map[*sqlx.DB]DedupStore)
+	dedupStoresMu  sync.RWMutex
+	dedupStoreSize int
+	dedupSizeOnce  sync.Once
+)
+
+// publishCacheSize returns the configured database.publishCacheSize, or DefaultPublishCacheSize if none has been set
+func publishCacheSize() int {
+	dedupSizeOnce.Do(func() {
+		viper.BindEnv("database.publishCacheSize", DATABASE_PUBLISH_CACHE_SIZE)
+		dedupStoreSize = DefaultPublishCacheSize
+		if viper.IsSet("database.publishCacheSize") {
+			dedupStoreSize = viper.GetInt("database.publishCacheSize")
+		}
+	})
+	return dedupStoreSize
+}
+
+// DedupStoreFor returns the DedupStore of the database the pool connects to, by default an LRUDedupStore of
+// database.publishCacheSize keys; each database has a store of its own, as a key committed to the public.blocks of one
+// database is not in that of another
+// it is nil, and no inserts are skipped, if the configured size is not positive
+func DedupStoreFor(db *sqlx.DB) DedupStore {
+	dedupStoresMu.RLock()
+	store, ok := dedupStores[db]
+	dedupStoresMu.RUnlock()
+	if ok {
+		return store
+	}
+	dedupStoresMu.Lock()
+	defer dedupStoresMu.Unlock()
+	if store, ok := dedupStores[db]; ok {
+		return store
+	}
+	if size := publishCacheSize(); size > 0 {
+		store = NewLRUDedupStore(size)
+	}
+	dedupStores[db] = store
+	return store
+}
+
+// SetDedupStore replaces the DedupStore of the database the pool connects to, a nil store disables the deduplication
+func SetDedupStore(db *sqlx.DB, store DedupStore) {
+	dedupStoresMu.Lock()
+	defer dedupStoresMu.Unlock()
+	dedupStores[db] = store
+}
+
+// RecentlyPublished returns whether the IPLD with the key has been committed by a recent tx that recorded it in the store
+// each lookup is recorded as a hit or a miss of the publish cache, a nil store holds no keys
+func RecentlyPublished(store DedupStore, key string) bool {
+	if store == nil {
+		return false
+	}
+	hit := store.Contains(key)
+	prom.ObservePublishCacheLookup(hit)
+	return hit
+}
+
+// MarkPublished records the keys as committed in the store, it must only be called once the tx they were published in
+// has been committed; were they added earlier, a rolled back tx would leave the store holding keys that are not in
+// public.blocks
+func MarkPublished(store DedupStore, keys ...string) {
+	if store != nil {
+		store.Add(keys...)
+	}
+}
+
+// ForgetPublished purges the DedupStores of every database, it must be called by anything that deletes from public.blocks
+func ForgetPublished() {
+	dedupStoresMu.RLock()
+	defer dedupStoresMu.RUnlock()
+	for _, store := range dedupStores {
+		if store != nil {
+			store.Purge()
+		}
+	}
+}
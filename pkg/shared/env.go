@@ -17,7 +17,14 @@
 package shared
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/sirupsen/logrus"
 
 	"github.com/spf13/viper"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/node"
@@ -34,25 +41,98 @@ const (
 	ETH_GENESIS_BLOCK = "ETH_GENESIS_BLOCK"
 	ETH_NETWORK_ID    = "ETH_NETWORK_ID"
 	ETH_CHAIN_ID      = "ETH_CHAIN_ID"
+
+	ETH_STRICT_CHAIN_ID = "ETH_STRICT_CHAIN_ID"
+	ETH_RPC_RATE_LIMIT  = "ETH_RPC_RATE_LIMIT"
+
+	ETH_CHAIN_CONFIG_FROM_NODE = "ETH_CHAIN_CONFIG_FROM_NODE"
+	ETH_MULTIHASHES            = "ETH_MULTIHASHES"
+
+	DATABASE_PUBLISH_CACHE_SIZE = "DATABASE_PUBLISH_CACHE_SIZE"
 )
 
-// GetEthNodeAndClient returns eth node info and client from path url
-func GetEthNodeAndClient(path string) (node.Info, *rpc.Client, error) {
+// chainIDTimeout is the timeout of the eth_chainId request made when connecting to a node
+const chainIDTimeout = 15 * time.Second
+
+// EthEndpoint returns the url to dial an ethereum node at from a configured path
+// paths with an explicit scheme (e.g. http://, https://, ws://, wss://) are used as is and filesystem paths are dialed over IPC,
+// bare host:port addresses are given the default scheme so that existing configurations keep working
+func EthEndpoint(path, defaultScheme string) string {
+	if strings.Contains(path, "://") {
+		return path
+	}
+	if strings.HasPrefix(path, "/") || strings.HasPrefix(path, ".") || strings.HasSuffix(path, ".ipc") {
+		return path
+	}
+	return defaultScheme + "://" + path
+}
+
+// GetEthNodeInfo returns the configured eth node info, for commands that work without connecting to a node
+func GetEthNodeInfo() node.Info {
 	viper.BindEnv("ethereum.nodeID", ETH_NODE_ID)
 	viper.BindEnv("ethereum.clientName", ETH_CLIENT_NAME)
 	viper.BindEnv("ethereum.genesisBlock", ETH_GENESIS_BLOCK)
 	viper.BindEnv("ethereum.networkID", ETH_NETWORK_ID)
 	viper.BindEnv("ethereum.chainID", ETH_CHAIN_ID)
 
-	rpcClient, err := rpc.Dial(path)
-	if err != nil {
-		return node.Info{}, nil, err
-	}
 	return node.Info{
 		ID:           viper.GetString("ethereum.nodeID"),
 		ClientName:   viper.GetString("ethereum.clientName"),
 		GenesisBlock: viper.GetString("ethereum.genesisBlock"),
 		NetworkID:    viper.GetString("ethereum.networkID"),
 		ChainID:      viper.GetUint64("ethereum.chainID"),
-	}, rpcClient, nil
+	}
+}
+
+// ChainConfigFromNode returns whether the chain config should be read from the connected node, rather than selected by
+// the configured chain id
+func ChainConfigFromNode() bool {
+	viper.BindEnv("ethereum.chainConfigFromNode", ETH_CHAIN_CONFIG_FROM_NODE)
+	return viper.GetBool("ethereum.chainConfigFromNode")
+}
+
+// MultihashEntries returns the configured nodeType=hashName entries that select the hash function each IPLD node type is
+// keyed under, which are parsed by eth.ParseMultihashes
+func MultihashEntries() []string {
+	viper.BindEnv("ethereum.multihashes", ETH_MULTIHASHES)
+	return viper.GetStringSlice("ethereum.multihashes")
+}
+
+// GetEthNodeAndClient returns eth node info and client from path url
+func GetEthNodeAndClient(path string) (node.Info, *rpc.Client, error) {
+	viper.BindEnv("ethereum.strictChainID", ETH_STRICT_CHAIN_ID)
+
+	rpcClient, err := rpc.Dial(path)
+	if err != nil {
+		return node.Info{}, nil, err
+	}
+	info := GetEthNodeInfo()
+	if err := checkChainID(rpcClient, info.ChainID, viper.GetBool("ethereum.strictChainID")); err != nil {
+		return node.Info{}, nil, err
+	}
+	return info, rpcClient, nil
+}
+
+// checkChainID compares the configured chain id, which selects the chain config used for signer and reward calculations,
+// against the chain id reported by the node, a mismatch means those calculations will be silently wrong
+// in strict mode a mismatch is returned as an error, otherwise it is only logged; a node that can't report its chain id
+// is logged and let through in either mode
+func checkChainID(client *rpc.Client, configured uint64, strict bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), chainIDTimeout)
+	defer cancel()
+	var reported hexutil.Big
+	if err := client.CallContext(ctx, &reported, "eth_chainId"); err != nil {
+		logrus.Warnf("unable to retrieve the chain id of the ethereum node to check it against the configured chain id %d: %v", configured, err)
+		return nil
+	}
+	nodeChainID := reported.ToInt()
+	if nodeChainID.IsUint64() && nodeChainID.Uint64() == configured {
+		return nil
+	}
+	if strict {
+		return fmt.Errorf("configured chain id %d does not match the chain id %s of the ethereum node", configured, nodeChainID.String())
+	}
+	logrus.Warnf("CHAIN ID MISMATCH: configured chain id %d does not match the chain id %s of the ethereum node, "+
+		"transaction senders and rewards will be derived with the wrong chain config", configured, nodeChainID.String())
+	return nil
 }
@@ -23,6 +23,8 @@ import (
 	"github.com/ipfs/go-ipfs-ds-help"
 	node "github.com/ipfs/go-ipld-format"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/multiformats/go-multihash"
 	"github.com/sirupsen/logrus"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/ipfs/ipld"
 )
@@ -52,10 +54,31 @@ func Rollback(tx *sqlx.Tx) {
 
 // PublishIPLD is used to insert an ipld into Postgres blockstore with the provided tx
 func PublishIPLD(tx *sqlx.Tx, i node.Node) error {
-	dbKey := dshelp.MultihashToDsKey(i.Cid().Hash())
-	prefixedKey := blockstore.BlockPrefix.String() + dbKey.String()
-	raw := i.RawData()
-	_, err := tx.Exec(`INSERT INTO public.blocks (key, data) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING`, prefixedKey, raw)
+	return PublishDirect(tx, MultihashKeyFromCID(i.Cid()), i.RawData())
+}
+
+// PublishIPLDs is used to insert a batch of iplds into Postgres blockstore with the provided tx, in a single statement
+func PublishIPLDs(tx *sqlx.Tx, is []node.Node) error {
+	if len(is) == 0 {
+		return nil
+	}
+	keys := make([]string, len(is))
+	data := make([][]byte, len(is))
+	for j, i := range is {
+		keys[j] = MultihashKeyFromCID(i.Cid())
+		data[j] = i.RawData()
+	}
+	return PublishDirectBatch(tx, keys, data)
+}
+
+// PublishDirectBatch is used to insert a batch of raw data into Postgres blockstore under the provided (blockstore-prefixed)
+// multihash keys, in a single statement
+func PublishDirectBatch(tx *sqlx.Tx, keys []string, data [][]byte) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(`INSERT INTO public.blocks (key, data) SELECT * FROM unnest($1::TEXT[], $2::BYTEA[]) ON CONFLICT (key) DO NOTHING`,
+		pq.Array(keys), pq.Array(data))
 	return err
 }
 
@@ -93,14 +116,38 @@ func MultihashKeyFromCIDString(c string) (string, error) {
 	return blockstore.BlockPrefix.String() + dbKey.String(), nil
 }
 
+// MultihashKeyFromKeccak256 hashes the data with keccak256 into the blockstore-prefixed multihash db key string that it is
+// published under by the indexer, which keys the eth IPLDs by their keccak256 multihash regardless of their codec
+func MultihashKeyFromKeccak256(data []byte) (string, error) {
+	mh, err := multihash.Sum(data, multihash.KECCAK_256, -1)
+	if err != nil {
+		return "", err
+	}
+	dbKey := dshelp.MultihashToDsKey(mh)
+	return blockstore.BlockPrefix.String() + dbKey.String(), nil
+}
+
 // PublishRaw derives a cid from raw bytes and provided codec and multihash type, and writes it to the db tx
 func PublishRaw(tx *sqlx.Tx, codec, mh uint64, raw []byte) (string, error) {
 	c, err := ipld.RawdataToCid(codec, raw, mh)
 	if err != nil {
 		return "", err
 	}
-	dbKey := dshelp.MultihashToDsKey(c.Hash())
-	prefixedKey := blockstore.BlockPrefix.String() + dbKey.String()
-	_, err = tx.Exec(`INSERT INTO public.blocks (key, data) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING`, prefixedKey, raw)
-	return c.String(), err
+	return c.String(), PublishDirect(tx, MultihashKeyFromCID(c), raw)
+}
+
+// PublishDirect is used to insert raw data into Postgres blockstore under the provided (blockstore-prefixed) multihash key
+func PublishDirect(tx *sqlx.Tx, key string, value []byte) error {
+	_, err := PublishDirectIfNew(tx, nil, key, value)
+	return err
+}
+
+// PublishDirectIfNew is PublishDirect, but also returns whether the insert was issued
+// it is not if the key is held by the store, which must be the DedupStore of the tx's database
+func PublishDirectIfNew(tx *sqlx.Tx, store DedupStore, key string, value []byte) (bool, error) {
+	if RecentlyPublished(store, key) {
+		return false, nil
+	}
+	_, err := tx.Exec(`INSERT INTO public.blocks (key, data) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING`, key, value)
+	return err == nil, err
 }
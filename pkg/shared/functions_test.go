@@ -0,0 +1,49 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package shared_test
+
+import (
+	"github.com/ethereum/go-ethereum/rlp"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth/mocks"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
+)
+
+var _ = Describe("MultihashKeyFromKeccak256", func() {
+	It("Keys the empty trie node under the keccak256 multihash of the empty trie root", func() {
+		emptyNode, err := rlp.EncodeToBytes([]byte{})
+		Expect(err).ToNot(HaveOccurred())
+		mhKey, err := shared.MultihashKeyFromKeccak256(emptyNode)
+		Expect(err).ToNot(HaveOccurred())
+		// base32 of the 0x1b20 keccak256 multihash prefix and the empty trie root 0x56e81f...b421
+		Expect(mhKey).To(Equal("/blocks/DMQFN2A7C4N4YVNG76BULZUSYD4G4W2I4ANZS3FNYAAWEL5V4NR3III"))
+	})
+
+	It("Returns the key the state and storage nodes are published under", func() {
+		mhKey, err := shared.MultihashKeyFromKeccak256(mocks.ContractLeafNode)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mhKey).To(Equal(mocks.State1MhKey))
+		mhKey, err = shared.MultihashKeyFromKeccak256(mocks.AccountLeafNode)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mhKey).To(Equal(mocks.State2MhKey))
+		mhKey, err = shared.MultihashKeyFromKeccak256(mocks.StorageLeafNode)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mhKey).To(Equal(mocks.StorageMhKey))
+	})
+})
@@ -0,0 +1,88 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package shared
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// RateLimiter is a token bucket that spaces out requests to keep them under a rate, it holds a single token so
+// requests are never sent in bursts; a nil RateLimiter does not limit anything
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewRateLimiter returns a RateLimiter that lets through perSecond requests per second,
+// or nil if perSecond is not positive
+func NewRateLimiter(perSecond float64) *RateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &RateLimiter{
+		interval: time.Duration(float64(time.Second) / perSecond),
+	}
+}
+
+// Wait blocks until n requests can be sent, or until the context is done
+// the requests are reserved when Wait is called, so concurrent callers are let through in the order they called it
+func (rl *RateLimiter) Wait(ctx context.Context, n int) error {
+	if rl == nil || n <= 0 {
+		return nil
+	}
+	rl.mu.Lock()
+	now := time.Now()
+	if rl.next.Before(now) {
+		rl.next = now
+	}
+	// the first request goes out at the reserved time, each of the others an interval later
+	at := rl.next.Add(time.Duration(n-1) * rl.interval)
+	rl.next = at.Add(rl.interval)
+	rl.mu.Unlock()
+	delay := at.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+var (
+	rpcRateLimiter     *RateLimiter
+	rpcRateLimiterOnce sync.Once
+)
+
+// RPCRateLimiter returns the limiter shared by all of the process' ethereum rpc requests, configured by the
+// ethereum.rpcRateLimit requests per second; it is nil, and so does not limit anything, if no rate has been configured
+func RPCRateLimiter() *RateLimiter {
+	rpcRateLimiterOnce.Do(func() {
+		viper.BindEnv("ethereum.rpcRateLimit", ETH_RPC_RATE_LIMIT)
+		rpcRateLimiter = NewRateLimiter(viper.GetFloat64("ethereum.rpcRateLimit"))
+	})
+	return rpcRateLimiter
+}
@@ -33,6 +33,15 @@ func SetupDB() (*postgres.DB, error) {
 	}, node.Info{})
 }
 
+// SetupReplicaDB is used to setup a second db for tests that write to more than one database
+func SetupReplicaDB() (*postgres.DB, error) {
+	return postgres.NewDB(postgres.Config{
+		Hostname: "localhost",
+		Name:     "vulcanize_testing_replica",
+		Port:     5432,
+	}, node.Info{})
+}
+
 // ListContainsString used to check if a list of strings contains a particular string
 func ListContainsString(sss []string, s string) bool {
 	for _, str := range sss {
@@ -18,10 +18,12 @@ package sync
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/spf13/viper"
 
+	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/node"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/postgres"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
@@ -30,7 +32,10 @@ import (
 
 // Env variables
 const (
-	SYNC_WORKERS = "SYNC_WORKERS"
+	SYNC_WORKERS          = "SYNC_WORKERS"
+	SYNC_PERSIST_PAYLOADS = "SYNC_PERSIST_PAYLOADS"
+	SYNC_EVENT_SOCKET     = "SYNC_EVENT_SOCKET"
+	SYNC_CONFIRMATIONS    = "SYNC_CONFIRMATIONS"
 
 	SYNC_MAX_IDLE_CONNECTIONS = "SYNC_MAX_IDLE_CONNECTIONS"
 	SYNC_MAX_OPEN_CONNECTIONS = "SYNC_MAX_OPEN_CONNECTIONS"
@@ -44,6 +49,14 @@ type Config struct {
 	Workers  int64
 	WSClient *rpc.Client
 	NodeInfo node.Info
+	// If true, the raw payload of each block is also stored in eth.payloads, so it can be reprocessed without a node
+	PersistPayloads bool
+	// If set, a JSON event is written to the consumers of this Unix socket for each committed block
+	EventSocket string
+	// If greater than zero, each block is only indexed once it is this many blocks behind the highest block received
+	Confirmations uint64
+	// The hash function each IPLD node type is keyed under, keyed by its multicodec, node types left out keep the default
+	Multihashes map[uint64]uint64
 }
 
 // NewConfig is used to initialize a sync config from a .toml file
@@ -51,6 +64,9 @@ func NewConfig() (*Config, error) {
 	c := new(Config)
 	var err error
 	viper.BindEnv("sync.workers", SYNC_WORKERS)
+	viper.BindEnv("sync.persistPayloads", SYNC_PERSIST_PAYLOADS)
+	viper.BindEnv("sync.eventSocket", SYNC_EVENT_SOCKET)
+	viper.BindEnv("sync.confirmations", SYNC_CONFIRMATIONS)
 	viper.BindEnv("ethereum.wsPath", shared.ETH_WS_PATH)
 
 	workers := viper.GetInt64("sync.workers")
@@ -58,9 +74,19 @@ func NewConfig() (*Config, error) {
 		workers = 1
 	}
 	c.Workers = workers
+	c.PersistPayloads = viper.GetBool("sync.persistPayloads")
+	c.EventSocket = viper.GetString("sync.eventSocket")
+	c.Confirmations = uint64(viper.GetInt64("sync.confirmations"))
+	if c.Multihashes, err = eth.ParseMultihashes(shared.MultihashEntries()); err != nil {
+		return nil, err
+	}
 
-	ethWS := viper.GetString("ethereum.wsPath")
-	c.NodeInfo, c.WSClient, err = shared.GetEthNodeAndClient(fmt.Sprintf("ws://%s", ethWS))
+	// sync subscribes to the statediff service, which needs a transport that supports subscriptions
+	ethWS := shared.EthEndpoint(viper.GetString("ethereum.wsPath"), "ws")
+	if strings.HasPrefix(ethWS, "http://") || strings.HasPrefix(ethWS, "https://") {
+		return nil, fmt.Errorf("sync requires a ws or ipc ethereum endpoint, got %s", ethWS)
+	}
+	c.NodeInfo, c.WSClient, err = shared.GetEthNodeAndClient(ethWS)
 	if err != nil {
 		return nil, err
 	}
@@ -76,7 +102,14 @@ func overrideDBConnConfig(con *postgres.Config) {
 	viper.BindEnv("database.sync.maxIdle", SYNC_MAX_IDLE_CONNECTIONS)
 	viper.BindEnv("database.sync.maxOpen", SYNC_MAX_OPEN_CONNECTIONS)
 	viper.BindEnv("database.sync.maxLifetime", SYNC_MAX_CONN_LIFETIME)
-	con.MaxIdle = viper.GetInt("database.sync.maxIdle")
-	con.MaxOpen = viper.GetInt("database.sync.maxOpen")
-	con.MaxLifetime = viper.GetInt("database.sync.maxLifetime")
+	// only override the general pool settings with command specific ones if they have been set
+	if maxIdle := viper.GetInt("database.sync.maxIdle"); maxIdle > 0 {
+		con.MaxIdle = maxIdle
+	}
+	if maxOpen := viper.GetInt("database.sync.maxOpen"); maxOpen > 0 {
+		con.MaxOpen = maxOpen
+	}
+	if maxLifetime := viper.GetInt("database.sync.maxLifetime"); maxLifetime > 0 {
+		con.MaxLifetime = maxLifetime
+	}
 }
@@ -18,7 +18,10 @@ package sync
 
 import (
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/ethereum/go-ethereum/ethclient"
 	ethnode "github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
@@ -27,11 +30,17 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/vulcanize/ipld-eth-indexer/pkg/eth"
+	"github.com/vulcanize/ipld-eth-indexer/pkg/prom"
 	"github.com/vulcanize/ipld-eth-indexer/pkg/shared"
 )
 
 const (
 	PayloadChanBufferSize = 2000
+	lagTimeout            = 5 * time.Second
+
+	// bounds of the exponential backoff between attempts to re-establish a dropped subscription
+	minReconnectBackoff = time.Second
+	maxReconnectBackoff = time.Minute
 )
 
 // Indexer is the top level interface for streaming, converting to IPLDs, publishing, and indexing all chain data at head
@@ -59,6 +68,10 @@ type Service struct {
 	Workers int64
 	// chain type for this service
 	ChainConfig *params.ChainConfig
+	// Tracks how far the indexed data trails the head of the chain, updated after each block is transformed
+	LagTracker *eth.LagTracker
+	// Number of workers currently transforming a payload
+	busyWorkers int64
 }
 
 // NewIndexer creates a new Indexer using an underlying Service struct
@@ -67,11 +80,27 @@ func NewIndexerService(settings *Config) (Indexer, error) {
 	var err error
 	sn.PayloadChan = make(chan statediff.Payload, eth.PayloadChanBufferSize)
 	sn.Streamer = eth.NewPayloadStreamer(settings.WSClient)
-	sn.ChainConfig, err = eth.ChainConfig(settings.NodeInfo.ChainID)
+	sn.ChainConfig, err = eth.ResolveChainConfig(settings.WSClient, settings.NodeInfo.ChainID, shared.ChainConfigFromNode())
 	if err != nil {
 		return nil, err
 	}
-	sn.Transformer = eth.NewStateDiffTransformer(sn.ChainConfig, settings.DB)
+	transformer := eth.NewStateDiffTransformer(sn.ChainConfig, settings.DB)
+	if err := transformer.SetMultihashes(settings.Multihashes); err != nil {
+		return nil, err
+	}
+	transformer.PersistPayloads = settings.PersistPayloads
+	if settings.EventSocket != "" {
+		emitter, err := eth.NewUnixSocketEmitter(settings.EventSocket)
+		if err != nil {
+			return nil, err
+		}
+		transformer.EventSink = emitter
+	}
+	sn.Transformer = transformer
+	if settings.Confirmations > 0 {
+		sn.Transformer = eth.NewFinalizingTransformer(transformer, settings.Confirmations)
+	}
+	sn.LagTracker = eth.NewLagTracker(eth.NewRateLimitedHeaderClient(ethclient.NewClient(settings.WSClient), shared.RPCRateLimiter()), eth.NewGapRetriever(settings.DB), lagTimeout)
 	sn.QuitChan = make(chan bool)
 	sn.Workers = settings.Workers
 	return sn, nil
@@ -113,18 +142,47 @@ func (sap *Service) Sync(wg *sync.WaitGroup) error {
 					<-publishPayload
 					publishPayload <- diffPayload
 				}
+				prom.SetSyncWorkerPool(int64(len(publishPayload)), atomic.LoadInt64(&sap.busyWorkers))
 			case err := <-sub.Err():
-				log.Errorf("ethereumm sync subscription error: %v", err)
+				// the subscription is dead once it has errored, a dropped connection is re-established by resubscribing
+				log.Errorf("ethereum sync subscription error: %v", err)
+				if sub = sap.resubscribe(); sub == nil {
+					log.Info("quiting ethereum sync process")
+					return
+				}
 			case <-sap.QuitChan:
 				log.Info("quiting ethereum sync process")
 				return
 			}
 		}
 	}()
-	log.Info("ethereum sync process successfully spun up")
+	log.Infof("ethereum sync process successfully spun up with %d workers", sap.Workers)
 	return nil
 }
 
+// resubscribe re-establishes the statediff subscription after it has been dropped, backing off exponentially between attempts
+// it returns nil if the service is told to quit before the subscription is re-established
+func (sap *Service) resubscribe() *rpc.ClientSubscription {
+	backoff := minReconnectBackoff
+	for attempt := 1; ; attempt++ {
+		log.Infof("ethereum sync reconnecting to the statediff subscription in %s (attempt %d)", backoff, attempt)
+		select {
+		case <-sap.QuitChan:
+			return nil
+		case <-time.After(backoff):
+		}
+		sub, err := sap.Streamer.Stream(sap.PayloadChan)
+		if err == nil {
+			log.Infof("ethereum sync reconnected to the statediff subscription after %d attempt(s)", attempt)
+			return sub
+		}
+		log.Errorf("ethereum sync reconnection attempt %d failed: %v", attempt, err)
+		if backoff *= 2; backoff > maxReconnectBackoff {
+			backoff = maxReconnectBackoff
+		}
+	}
+}
+
 // transform is spun up by Sync and receives statediff payloads from it
 // it transforms this data into IPLD models and indexes their CIDs with useful metadata in Postgres
 func (sap *Service) transform(wg *sync.WaitGroup, id int, statediffChan <-chan statediff.Payload) {
@@ -133,11 +191,18 @@ func (sap *Service) transform(wg *sync.WaitGroup, id int, statediffChan <-chan s
 	for {
 		select {
 		case diff := <-statediffChan:
+			prom.SetSyncWorkerPool(int64(len(statediffChan)), atomic.AddInt64(&sap.busyWorkers, 1))
 			blockNumber, err := sap.Transformer.Transform(id, diff)
+			prom.SetSyncWorkerPool(int64(len(statediffChan)), atomic.AddInt64(&sap.busyWorkers, -1))
 			if err != nil {
 				log.Errorf("ethereum sync worker %d transformer error: %v", id, err)
 			}
 			log.Infof("ethereum sync worker %d transformed data at height %d", id, blockNumber)
+			if err == nil && sap.LagTracker != nil {
+				if _, err := sap.LagTracker.Update(); err != nil {
+					log.Errorf("ethereum sync worker %d error updating the indexing lag: %v", id, err)
+				}
+			}
 		case <-sap.QuitChan:
 			log.Infof("ethereum sync worker %d shutting down", id)
 			return